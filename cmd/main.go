@@ -2,18 +2,23 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"sync"
 	"syscall"
 	"time"
 
 	app "aviasales-bot/search-service/internal/application"
 	api "aviasales-bot/search-service/internal/infrastructure/aviasales"
+	"aviasales-bot/search-service/internal/infrastructure/redisstreams"
 	httpiface "aviasales-bot/search-service/internal/interfaces/http"
 	"aviasales-bot/search-service/internal/monitor"
 	obslogger "aviasales-bot/search-service/internal/observability/logger"
+	"aviasales-bot/search-service/internal/streams"
 
 	shared "github.com/KamnevVladimir/aviabot-shared-logging"
 )
@@ -46,9 +51,10 @@ func main() {
 
 	client := api.NewClient(baseURL, token, marker, api.WithLogger(lg))
 
-	adapter := &clientAdapter{c: client}
+	// фильтры запроса (авиакомпании, цена, длительность, часы вылета) и порядок sort применяются поверх ответа API
+	searcher := app.WithSorting(app.WithFilters(&clientAdapter{c: client}))
 
-	h := httpiface.NewHandlerWithLogger(adapter, convertLogger(lg))
+	h := httpiface.NewHandlerWithLogger(searcher, convertLogger(lg))
 
 	// Routing
 	http.Handle("/", h)
@@ -79,13 +85,55 @@ func main() {
 		}
 	}()
 
+	// stream worker: search.requests -> search.results
+	var workers sync.WaitGroup
+	if redisURL := os.Getenv("REDIS_URL"); redisURL != "" {
+		rc, err := redisstreams.NewClient(redisURL)
+		if err != nil {
+			log.Fatalf("redis: %v", err)
+		}
+		defer rc.Close()
+
+		group := os.Getenv("SEARCH_CONSUMER_GROUP")
+		if group == "" {
+			group = "search-service"
+		}
+		dlq := streams.NewDeadLetterQueue(rc, group)
+
+		consumerMonitor := startSearchWorker(ctx, &workers, rc, group, dlq, searcher, lg)
+		http.HandleFunc("/health/worker", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			_ = json.NewEncoder(w).Encode(consumerMonitor.GetHealthStatus())
+		})
+		// без ADMIN_TOKEN админские endpoints не публикуются: DLQ содержит исходные запросы пользователей
+		if adminToken := os.Getenv("ADMIN_TOKEN"); adminToken != "" {
+			http.Handle("/admin/", httpiface.NewAdminHandler(dlq, adminToken, convertLogger(lg)))
+		} else {
+			lg.Error("admin_disabled", map[string]interface{}{"reason": "ADMIN_TOKEN is not set"})
+		}
+
+		startDealsDigest(ctx, &workers, rc, searcher, lg)
+	}
+
 	// graceful shutdown
 	go func() {
 		sig := make(chan os.Signal, 1)
 		signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
 		<-sig
-		hm.ServiceStop()
 		cancel()
+		// дожидаемся завершения запросов, взятых воркером в работу, но не дольше SHUTDOWN_TIMEOUT
+		drained := make(chan struct{})
+		go func() {
+			workers.Wait()
+			close(drained)
+		}()
+		select {
+		case <-drained:
+		case <-time.After(envDuration("SHUTDOWN_TIMEOUT", 45*time.Second)):
+			lg.Error("search_worker_drain_timeout", map[string]interface{}{"reason": "in-flight searches did not finish"})
+		}
+		hm.ServiceStop()
 		os.Exit(0)
 	}()
 
@@ -93,52 +141,374 @@ func main() {
 	log.Fatal(http.ListenAndServe(addr, nil))
 }
 
+// streamsRedis объединяет возможности Redis, необходимые stream воркеру
+type streamsRedis interface {
+	streams.RedisClient
+	streams.RedisProducer
+	streams.RedisIdempotency
+	streams.RedisPendingClient
+	streams.RedisDeadLetter
+}
+
+// startSearchWorker запускает воркер search.requests в фоне до отмены ctx
+func startSearchWorker(ctx context.Context, wg *sync.WaitGroup, rc streamsRedis, group string, dlq *streams.DeadLetterQueue, searcher streams.Searcher, lg obslogger.Logger) *streams.ConsumerHealthMonitor {
+	// у каждого экземпляра свое имя консьюмера, иначе реплики делят pending list и счетчики доставок
+	consumer := os.Getenv("SEARCH_CONSUMER_NAME")
+	if consumer == "" {
+		consumer = "search-service"
+		if host, err := os.Hostname(); err == nil && host != "" {
+			consumer = host
+		}
+	}
+
+	consumerMonitor := streams.NewConsumerHealthMonitor()
+	worker := streams.NewSearchWorker(
+		streams.NewSearchRequestConsumer(rc, group, streams.WithDeadLetterQueue(dlq), streams.WithConsumerName(consumer)),
+		streams.NewSearchResultProducer(rc, streams.WithResultSchemaVersion(envInt("SEARCH_RESULT_SCHEMA_VERSION", streams.SchemaV1))),
+		streams.NewIdempotencyTracker(rc),
+		consumerMonitor,
+		searcher,
+		streams.WithWorkerLogger(lg),
+		// один поиск не дольше SEARCH_TIMEOUT, иначе зависший API держит воркер и остановку
+		streams.WithSearchTimeout(envDuration("SEARCH_TIMEOUT", 30*time.Second)),
+	)
+
+	// запросы, брошенные упавшим воркером без ack, перехватываются после SEARCH_PENDING_IDLE
+	minIdle := envDuration("SEARCH_PENDING_IDLE", 2*time.Minute)
+	// после SEARCH_MAX_DELIVERIES доставок запрос считается ядовитым и уходит в DLQ
+	maxDeliveries := int64(5)
+	if v := os.Getenv("SEARCH_MAX_DELIVERIES"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			maxDeliveries = n
+		}
+	}
+	reclaimer := streams.NewPendingReclaimer(rc, group, minIdle,
+		streams.WithReclaimConsumerName(consumer),
+		streams.WithReclaimDeadLetter(dlq, maxDeliveries),
+		streams.WithReclaimerLogger(lg),
+	)
+
+	// SEARCH_WORKERS воркеров обрабатывают запросы параллельно, чаты обслуживаются по кругу,
+	// и одному чату достается не больше SEARCH_CHAT_CONCURRENCY воркеров
+	// ожидающие воркера запросы держатся в pending своими, пока не дойдет их очередь
+	pool := streams.NewWorkerPool(worker,
+		streams.WithPoolSize(envInt("SEARCH_WORKERS", 4)),
+		streams.WithBatchSize(int64(envInt("SEARCH_BATCH_SIZE", 10))),
+		streams.WithMaxInFlight(envInt("SEARCH_MAX_IN_FLIGHT", 0)),
+		// SEARCH_READ_AHEAD=0 отключает чтение наперед
+		streams.WithReadAhead(envNonNegativeInt("SEARCH_READ_AHEAD", 4*envInt("SEARCH_WORKERS", 4))),
+		streams.WithChatConcurrency(envInt("SEARCH_CHAT_CONCURRENCY", 0)),
+		streams.WithPendingIdle(minIdle),
+	)
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		lg.Info("search_worker_start", map[string]interface{}{"group": group, "consumer": consumer})
+		_ = pool.Run(ctx)
+		lg.Info("search_worker_stop", consumerMonitor.GetHealthStatus())
+	}()
+	go func() {
+		defer wg.Done()
+		_ = reclaimer.Run(ctx, worker.Handle)
+	}()
+
+	return consumerMonitor
+}
+
+// startDealsDigest публикует спецпредложения в deals.digest раз в DEALS_DIGEST_INTERVAL до отмены ctx;
+// из нескольких реплик период публикует одна
+func startDealsDigest(ctx context.Context, wg *sync.WaitGroup, rc streams.RedisDigest, source streams.DealsSource, lg obslogger.Logger) {
+	publisher := streams.NewDealsDigestPublisher(rc, source,
+		streams.WithDigestInterval(envDuration("DEALS_DIGEST_INTERVAL", 24*time.Hour)),
+		streams.WithDigestLimit(envInt("DEALS_DIGEST_LIMIT", 10)),
+		streams.WithDigestLogger(lg),
+	)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = publisher.Run(ctx)
+	}()
+}
+
+// envInt читает положительное целое из переменной окружения или возвращает def
+func envInt(key string, def int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return def
+}
+
+// envNonNegativeInt читает неотрицательное целое (0 допустим) из переменной окружения или возвращает def
+func envNonNegativeInt(key string, def int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return def
+}
+
+// envDuration читает положительную длительность (time.ParseDuration) из переменной окружения или возвращает def
+func envDuration(key string, def time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return def
+}
+
 // clientAdapter адаптер который реализует FlightSearcher интерфейс
 type clientAdapter struct{ c *api.Client }
 
 // Реализация нового FlightSearcher интерфейса
 func (a *clientAdapter) SearchCheap(ctx context.Context, p app.SearchParams) ([]app.Flight, error) {
-	// Конвертируем app.SearchParams в api.SearchParams
-	apiParams := api.SearchParams{
+	if p.API == app.PricesAPIV3 {
+		return a.searchPricesForDates(ctx, p, false)
+	}
+
+	// Вызываем API и получаем результат
+	flights, err := a.c.SearchCheap(ctx, toAPIParams(p))
+	if err != nil {
+		return nil, err
+	}
+
+	return toAppFlights(flights), nil
+}
+
+func (a *clientAdapter) SearchDirect(ctx context.Context, p app.SearchParams) ([]app.Flight, error) {
+	if p.API == app.PricesAPIV3 {
+		return a.searchPricesForDates(ctx, p, true)
+	}
+
+	flights, err := a.c.SearchDirect(ctx, toAPIParams(p))
+	if err != nil {
+		return nil, err
+	}
+
+	return toAppFlights(flights), nil
+}
+
+// searchPricesForDates выполняет поиск через v3 prices_for_dates
+func (a *clientAdapter) searchPricesForDates(ctx context.Context, p app.SearchParams, direct bool) ([]app.Flight, error) {
+	flights, err := a.c.SearchPricesForDates(ctx, api.PricesForDatesParams{
 		Origin:      p.Origin,
 		Destination: p.Destination,
-		DepartDate:  p.DepartDate,
-		ReturnDate:  p.ReturnDate,
+		DepartureAt: p.DepartDate,
+		ReturnAt:    p.ReturnDate,
+		OneWay:      p.OneWay,
+		Direct:      direct,
+		Unique:      p.Unique,
+		Sorting:     p.Sorting,
 		Currency:    p.Currency,
 		Limit:       p.Limit,
+		Page:        p.Page,
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// Вызываем API и получаем результат
-	flights, err := a.c.SearchCheap(ctx, apiParams)
+	return toAppFlights(flights), nil
+}
+
+func (a *clientAdapter) GeneratePartnerLink(flight app.Flight, passengers int) string {
+	return a.c.GeneratePartnerLink(toAPIFlight(flight), passengers)
+}
+
+func (a *clientAdapter) FormatFlightMessage(originCity, destCity string, flights []app.Flight, passengers int, currency string) string {
+	var apiFlights []api.Flight
+	for _, flight := range flights {
+		apiFlights = append(apiFlights, toAPIFlight(flight))
+	}
+
+	return a.c.FormatFlightMessage(originCity, destCity, apiFlights, passengers, currency)
+}
+
+func (a *clientAdapter) SearchCalendar(ctx context.Context, p app.CalendarParams) (*app.PriceCalendar, error) {
+	cal, err := a.c.SearchCalendar(ctx, api.CalendarParams{
+		Origin:       p.Origin,
+		Destination:  p.Destination,
+		Month:        p.Month,
+		ReturnMonth:  p.ReturnMonth,
+		CalendarType: api.CalendarType(p.CalendarType),
+		Length:       p.Length,
+		Currency:     p.Currency,
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Конвертируем api.Flight в app.Flight
+	days := make(map[string]app.Flight, len(cal.Days))
+	for date, flight := range cal.Days {
+		days[date] = toAppFlight(flight)
+	}
+	return &app.PriceCalendar{
+		Origin:       cal.Origin,
+		Destination:  cal.Destination,
+		Month:        cal.Month,
+		CalendarType: string(cal.CalendarType),
+		Currency:     cal.Currency,
+		Days:         days,
+	}, nil
+}
+
+func (a *clientAdapter) FormatCalendarMessage(originCity, destCity string, cal *app.PriceCalendar) string {
+	days := make(map[string]api.Flight, len(cal.Days))
+	for date, flight := range cal.Days {
+		days[date] = toAPIFlight(flight)
+	}
+	return a.c.FormatCalendarMessage(originCity, destCity, &api.PriceCalendar{
+		Origin:       cal.Origin,
+		Destination:  cal.Destination,
+		Month:        cal.Month,
+		CalendarType: api.CalendarType(cal.CalendarType),
+		Currency:     cal.Currency,
+		Days:         days,
+	})
+}
+
+func (a *clientAdapter) SearchWeekMatrix(ctx context.Context, p app.MatrixParams) (*app.PriceMatrix, error) {
+	return toAppMatrix(a.c.SearchWeekMatrix(ctx, api.MatrixParams(p)))
+}
+
+func (a *clientAdapter) SearchMonthMatrix(ctx context.Context, p app.MatrixParams) (*app.PriceMatrix, error) {
+	return toAppMatrix(a.c.SearchMonthMatrix(ctx, api.MatrixParams(p)))
+}
+
+func (a *clientAdapter) SearchLatest(ctx context.Context, p app.LatestParams) ([]app.Flight, error) {
+	flights, err := a.c.SearchLatest(ctx, api.LatestParams(p))
+	if err != nil {
+		return nil, err
+	}
+
+	return toAppFlights(flights), nil
+}
+
+func (a *clientAdapter) CityAirports(ctx context.Context, code string) ([]string, error) {
+	return a.c.CityAirports(ctx, code)
+}
+
+func (a *clientAdapter) PopularDirections(ctx context.Context, origin, currency string) ([]app.Flight, error) {
+	flights, err := a.c.PopularDirections(ctx, origin, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	return toAppFlights(flights), nil
+}
+
+func (a *clientAdapter) SearchAirlineRoutes(ctx context.Context, p app.AirlineParams) ([]app.Flight, error) {
+	flights, err := a.c.SearchAirlineRoutes(ctx, api.AirlineParams(p))
+	if err != nil {
+		return nil, err
+	}
+
+	return toAppFlights(flights), nil
+}
+
+func (a *clientAdapter) SpecialOffers(ctx context.Context) ([]app.SpecialOffer, error) {
+	offers, err := a.c.SpecialOffers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	appOffers := make([]app.SpecialOffer, 0, len(offers))
+	for _, offer := range offers {
+		appOffers = append(appOffers, app.SpecialOffer(offer))
+	}
+	return appOffers, nil
+}
+
+func (a *clientAdapter) FormatOffersMessage(offers []app.SpecialOffer) string {
+	apiOffers := make([]api.SpecialOffer, 0, len(offers))
+	for _, offer := range offers {
+		apiOffers = append(apiOffers, api.SpecialOffer(offer))
+	}
+	return a.c.FormatOffersMessage(apiOffers)
+}
+
+func (a *clientAdapter) SearchGroupedPrices(ctx context.Context, p app.GroupedParams) (*app.GroupedPrices, error) {
+	g, err := a.c.SearchGroupedPrices(ctx, api.GroupedParams{
+		Origin:      p.Origin,
+		Destination: p.Destination,
+		GroupBy:     api.GroupBy(p.GroupBy),
+		DepartureAt: p.DepartureAt,
+		ReturnAt:    p.ReturnAt,
+		Direct:      p.Direct,
+		Currency:    p.Currency,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	periods := make(map[string]app.Flight, len(g.Periods))
+	for period, flight := range g.Periods {
+		periods[period] = toAppFlight(flight)
+	}
+	return &app.GroupedPrices{
+		Origin:      g.Origin,
+		Destination: g.Destination,
+		GroupBy:     string(g.GroupBy),
+		Currency:    g.Currency,
+		Periods:     periods,
+	}, nil
+}
+
+func (a *clientAdapter) FormatGroupedMessage(originCity, destCity string, g *app.GroupedPrices) string {
+	periods := make(map[string]api.Flight, len(g.Periods))
+	for period, flight := range g.Periods {
+		periods[period] = toAPIFlight(flight)
+	}
+	return a.c.FormatGroupedMessage(originCity, destCity, &api.GroupedPrices{
+		Origin:      g.Origin,
+		Destination: g.Destination,
+		GroupBy:     api.GroupBy(g.GroupBy),
+		Currency:    g.Currency,
+		Periods:     periods,
+	})
+}
+
+// toAppMatrix конвертирует результат api матрицы цен в app.PriceMatrix
+func toAppMatrix(m *api.PriceMatrix, err error) (*app.PriceMatrix, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &app.PriceMatrix{
+		Origin:      m.Origin,
+		Destination: m.Destination,
+		Currency:    m.Currency,
+		Flights:     toAppFlights(m.Flights),
+	}, nil
+}
+
+// toAPIParams конвертирует app.SearchParams в api.SearchParams
+func toAPIParams(p app.SearchParams) api.SearchParams {
+	return api.SearchParams{
+		Origin:      p.Origin,
+		Destination: p.Destination,
+		DepartDate:  p.DepartDate,
+		ReturnDate:  p.ReturnDate,
+		Currency:    p.Currency,
+		Limit:       p.Limit,
+	}
+}
+
+// toAppFlights конвертирует api.Flight в app.Flight
+func toAppFlights(flights []api.Flight) []app.Flight {
 	var appFlights []app.Flight
 	for _, flight := range flights {
-		appFlights = append(appFlights, app.Flight{
-			Origin:       flight.Origin,
-			Destination:  flight.Destination,
-			DepartDate:   flight.DepartDate,
-			ReturnDate:   flight.ReturnDate,
-			Price:        flight.Price,
-			Airline:      flight.Airline,
-			FlightNumber: flight.FlightNumber,
-			Duration:     flight.Duration,
-			Distance:     flight.Distance,
-			Gate:         flight.Gate,
-			ExpiresAt:    flight.ExpiresAt,
-			Actual:       flight.Actual,
-		})
+		appFlights = append(appFlights, toAppFlight(flight))
 	}
-
-	return appFlights, nil
+	return appFlights
 }
 
-func (a *clientAdapter) GeneratePartnerLink(flight app.Flight, passengers int) string {
-	// Конвертируем app.Flight в api.Flight
-	apiFlight := api.Flight{
+// toAppFlight конвертирует api.Flight в app.Flight
+func toAppFlight(flight api.Flight) app.Flight {
+	return app.Flight{
 		Origin:       flight.Origin,
 		Destination:  flight.Destination,
 		DepartDate:   flight.DepartDate,
@@ -147,36 +517,47 @@ func (a *clientAdapter) GeneratePartnerLink(flight app.Flight, passengers int) s
 		Airline:      flight.Airline,
 		FlightNumber: flight.FlightNumber,
 		Duration:     flight.Duration,
+		Transfers:    flight.Transfers,
 		Distance:     flight.Distance,
 		Gate:         flight.Gate,
 		ExpiresAt:    flight.ExpiresAt,
 		Actual:       flight.Actual,
-	}
 
-	return a.c.GeneratePartnerLink(apiFlight, passengers)
-}
+		ReturnTransfers: flight.ReturnTransfers,
+		DurationTo:      flight.DurationTo,
+		DurationBack:    flight.DurationBack,
+		Link:            flight.Link,
 
-func (a *clientAdapter) FormatFlightMessage(originCity, destCity string, flights []app.Flight, passengers int) string {
-	// Конвертируем app.Flight в api.Flight
-	var apiFlights []api.Flight
-	for _, flight := range flights {
-		apiFlights = append(apiFlights, api.Flight{
-			Origin:       flight.Origin,
-			Destination:  flight.Destination,
-			DepartDate:   flight.DepartDate,
-			ReturnDate:   flight.ReturnDate,
-			Price:        flight.Price,
-			Airline:      flight.Airline,
-			FlightNumber: flight.FlightNumber,
-			Duration:     flight.Duration,
-			Distance:     flight.Distance,
-			Gate:         flight.Gate,
-			ExpiresAt:    flight.ExpiresAt,
-			Actual:       flight.Actual,
-		})
+		OriginName:      flight.OriginName,
+		DestinationName: flight.DestinationName,
 	}
+}
 
-	return a.c.FormatFlightMessage(originCity, destCity, apiFlights, passengers)
+// toAPIFlight конвертирует app.Flight в api.Flight
+func toAPIFlight(flight app.Flight) api.Flight {
+	return api.Flight{
+		Origin:       flight.Origin,
+		Destination:  flight.Destination,
+		DepartDate:   flight.DepartDate,
+		ReturnDate:   flight.ReturnDate,
+		Price:        flight.Price,
+		Airline:      flight.Airline,
+		FlightNumber: flight.FlightNumber,
+		Duration:     flight.Duration,
+		Transfers:    flight.Transfers,
+		Distance:     flight.Distance,
+		Gate:         flight.Gate,
+		ExpiresAt:    flight.ExpiresAt,
+		Actual:       flight.Actual,
+
+		ReturnTransfers: flight.ReturnTransfers,
+		DurationTo:      flight.DurationTo,
+		DurationBack:    flight.DurationBack,
+		Link:            flight.Link,
+
+		OriginName:      flight.OriginName,
+		DestinationName: flight.DestinationName,
+	}
 }
 
 // convertLogger adapts observability logger to handler's minimal interface
@@ -0,0 +1,178 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// HourWindow интервал часов [From, To) по местному времени аэропорта.
+// From > To означает интервал через полночь: 22-6 — с 22:00 до 06:00.
+type HourWindow struct {
+	From int `json:"from"` // 0..23
+	To   int `json:"to"`   // 1..24
+}
+
+// ParseHourWindow разбирает интервал вида "18-24"
+func ParseHourWindow(s string) (*HourWindow, error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return nil, fmt.Errorf("hour window %q must look like 18-24", s)
+	}
+	w := &HourWindow{}
+	var err error
+	if w.From, err = strconv.Atoi(strings.TrimSpace(from)); err != nil {
+		return nil, fmt.Errorf("hour window %q: invalid start hour", s)
+	}
+	if w.To, err = strconv.Atoi(strings.TrimSpace(to)); err != nil {
+		return nil, fmt.Errorf("hour window %q: invalid end hour", s)
+	}
+	return w, w.validate()
+}
+
+func (w HourWindow) validate() error {
+	if w.From < 0 || w.From > 23 || w.To < 1 || w.To > 24 || w.From == w.To {
+		return fmt.Errorf("hour window %d-%d is out of range 0-24", w.From, w.To)
+	}
+	return nil
+}
+
+// Contains сообщает, попадает ли час в интервал
+func (w HourWindow) Contains(hour int) bool {
+	if w.From < w.To {
+		return hour >= w.From && hour < w.To
+	}
+	return hour >= w.From || hour < w.To
+}
+
+// Filter ограничения на найденные билеты; нулевые поля не ограничивают
+type Filter struct {
+	Airlines        []string    `json:"airlines,omitempty"`         // только эти авиакомпании (IATA)
+	ExcludeAirlines []string    `json:"exclude_airlines,omitempty"` // кроме этих авиакомпаний (IATA)
+	MinPrice        int         `json:"min_price,omitempty"`
+	MaxPrice        int         `json:"max_price,omitempty"`
+	MaxDuration     int         `json:"max_duration,omitempty"`  // минуты; билеты без длительности отбрасываются
+	MaxTransfers    *int        `json:"max_transfers,omitempty"` // 0 — только прямые
+	DepartHours     *HourWindow `json:"depart_hours,omitempty"`  // часы вылета
+	ReturnHours     *HourWindow `json:"return_hours,omitempty"`  // часы обратного вылета; билеты в одну сторону не проверяются
+}
+
+// Empty сообщает, что фильтр ничего не ограничивает
+func (f Filter) Empty() bool {
+	return len(f.Airlines) == 0 && len(f.ExcludeAirlines) == 0 &&
+		f.MinPrice == 0 && f.MaxPrice == 0 && f.MaxDuration == 0 &&
+		f.MaxTransfers == nil && f.DepartHours == nil && f.ReturnHours == nil
+}
+
+// Validate проверяет согласованность ограничений
+func (f Filter) Validate() error {
+	switch {
+	case f.MinPrice < 0 || f.MaxPrice < 0:
+		return fmt.Errorf("price bounds must be positive")
+	case f.MaxPrice > 0 && f.MinPrice > f.MaxPrice:
+		return fmt.Errorf("min_price %d exceeds max_price %d", f.MinPrice, f.MaxPrice)
+	case f.MaxDuration < 0:
+		return fmt.Errorf("max_duration must be positive")
+	case f.MaxTransfers != nil && *f.MaxTransfers < 0:
+		return fmt.Errorf("max_transfers must be positive")
+	}
+	for _, w := range []*HourWindow{f.DepartHours, f.ReturnHours} {
+		if w != nil {
+			if err := w.validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Match сообщает, проходит ли билет все ограничения фильтра
+func (f Filter) Match(flight Flight) bool {
+	switch {
+	case len(f.Airlines) > 0 && !containsAirline(f.Airlines, flight.Airline):
+		return false
+	case containsAirline(f.ExcludeAirlines, flight.Airline):
+		return false
+	case flight.Price < f.MinPrice:
+		return false
+	case f.MaxPrice > 0 && flight.Price > f.MaxPrice:
+		return false
+	case f.MaxDuration > 0 && (flight.Duration == 0 || flight.Duration > f.MaxDuration):
+		return false
+	case f.MaxTransfers != nil && (flight.Transfers > *f.MaxTransfers || flight.ReturnTransfers > *f.MaxTransfers):
+		return false
+	case f.DepartHours != nil && !f.DepartHours.Contains(flight.DepartDate.Hour()):
+		return false
+	case f.ReturnHours != nil && !flight.ReturnDate.IsZero() && !f.ReturnHours.Contains(flight.ReturnDate.Hour()):
+		return false
+	}
+	return true
+}
+
+// Apply возвращает билеты, прошедшие фильтр, в исходном порядке
+func (f Filter) Apply(flights []Flight) []Flight {
+	if f.Empty() {
+		return flights
+	}
+	matched := make([]Flight, 0, len(flights))
+	for _, flight := range flights {
+		if f.Match(flight) {
+			matched = append(matched, flight)
+		}
+	}
+	return matched
+}
+
+func containsAirline(airlines []string, airline string) bool {
+	for _, a := range airlines {
+		if strings.EqualFold(strings.TrimSpace(a), airline) {
+			return true
+		}
+	}
+	return false
+}
+
+// filteringSearcher применяет SearchParams.Filter к результатам SearchCheap и SearchDirect
+type filteringSearcher struct {
+	FlightSearcher
+}
+
+// WithFilters оборачивает FlightSearcher так, что поиск билетов учитывает SearchParams.Filter.
+// Для v1 билеты запрашиваются без лимита и обрезаются до Limit уже после фильтра,
+// чтобы отброшенные билеты не уменьшали выдачу; в v3 Limit — размер страницы API.
+func WithFilters(fs FlightSearcher) FlightSearcher {
+	return &filteringSearcher{FlightSearcher: fs}
+}
+
+func (s *filteringSearcher) SearchCheap(ctx context.Context, p SearchParams) ([]Flight, error) {
+	return s.search(ctx, p, s.FlightSearcher.SearchCheap)
+}
+
+func (s *filteringSearcher) SearchDirect(ctx context.Context, p SearchParams) ([]Flight, error) {
+	return s.search(ctx, p, s.FlightSearcher.SearchDirect)
+}
+
+func (s *filteringSearcher) search(ctx context.Context, p SearchParams, find func(context.Context, SearchParams) ([]Flight, error)) ([]Flight, error) {
+	if p.Filter.Empty() {
+		return find(ctx, p)
+	}
+	if err := p.Filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	limit := p.Limit
+	if p.API != PricesAPIV3 {
+		p.Limit = 0
+	}
+	flights, err := find(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	flights = p.Filter.Apply(flights)
+	if limit > 0 && len(flights) > limit {
+		flights = flights[:limit]
+	}
+	return flights, nil
+}
@@ -0,0 +1,135 @@
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func testFlights() []Flight {
+	at := func(day, hour int) time.Time { return time.Date(2024, 12, day, hour, 0, 0, 0, time.UTC) }
+	return []Flight{
+		{Destination: "AER", Airline: "DP", Price: 4500, Duration: 240, Transfers: 0, DepartDate: at(10, 6), ReturnDate: at(17, 20)},
+		{Destination: "AER", Airline: "SU", Price: 12000, Duration: 235, Transfers: 0, DepartDate: at(10, 19), ReturnDate: at(17, 9)},
+		{Destination: "AER", Airline: "S7", Price: 9800, Duration: 410, Transfers: 1, DepartDate: at(11, 23)},
+		{Destination: "AER", Airline: "UT", Price: 25000, Duration: 0, Transfers: 2, DepartDate: at(12, 1), ReturnDate: at(19, 2)},
+	}
+}
+
+func airlinesOf(flights []Flight) []string {
+	var airlines []string
+	for _, f := range flights {
+		airlines = append(airlines, f.Airline)
+	}
+	return airlines
+}
+
+// Тест фильтрации по каждому ограничению
+func TestFilter_Apply(t *testing.T) {
+	zero := 0
+	cases := map[string]struct {
+		filter Filter
+		want   []string
+	}{
+		"empty":             {Filter{}, []string{"DP", "SU", "S7", "UT"}},
+		"include airlines":  {Filter{Airlines: []string{"su", "S7"}}, []string{"SU", "S7"}},
+		"exclude airlines":  {Filter{ExcludeAirlines: []string{"DP"}}, []string{"SU", "S7", "UT"}},
+		"price range":       {Filter{MinPrice: 5000, MaxPrice: 20000}, []string{"SU", "S7"}},
+		"max duration":      {Filter{MaxDuration: 360}, []string{"DP", "SU"}},
+		"direct only":       {Filter{MaxTransfers: &zero}, []string{"DP", "SU"}},
+		"evening departure": {Filter{DepartHours: &HourWindow{From: 18, To: 24}}, []string{"SU", "S7"}},
+		"night departure":   {Filter{DepartHours: &HourWindow{From: 22, To: 6}}, []string{"S7", "UT"}},
+		"return hours":      {Filter{ReturnHours: &HourWindow{From: 0, To: 12}}, []string{"SU", "S7", "UT"}},
+	}
+	for name, tc := range cases {
+		got := airlinesOf(tc.filter.Apply(testFlights()))
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: got %v, want %v", name, got, tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("%s: got %v, want %v", name, got, tc.want)
+				break
+			}
+		}
+	}
+}
+
+func TestParseHourWindow(t *testing.T) {
+	w, err := ParseHourWindow("18-24")
+	if err != nil || w.From != 18 || w.To != 24 {
+		t.Fatalf("unexpected window %+v, err %v", w, err)
+	}
+	for _, s := range []string{"18", "a-5", "5-5", "0-25", "-1-4"} {
+		if _, err := ParseHourWindow(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
+
+func TestFilter_Validate(t *testing.T) {
+	negative := -1
+	for name, f := range map[string]Filter{
+		"min above max":     {MinPrice: 30000, MaxPrice: 20000},
+		"negative price":    {MaxPrice: -5},
+		"negative transfer": {MaxTransfers: &negative},
+		"bad window":        {DepartHours: &HourWindow{From: 7, To: 7}},
+	} {
+		if err := f.Validate(); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	if err := (Filter{MinPrice: 1000, MaxPrice: 1000}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// stubSearcher отдает заготовленные билеты и запоминает параметры поиска
+type stubSearcher struct {
+	FlightSearcher
+	flights    []Flight
+	calledWith SearchParams
+}
+
+func (s *stubSearcher) SearchCheap(_ context.Context, p SearchParams) ([]Flight, error) {
+	s.calledWith = p
+	if p.Limit > 0 && len(s.flights) > p.Limit {
+		return s.flights[:p.Limit], nil
+	}
+	return s.flights, nil
+}
+
+// Тест: фильтр применяется до лимита, поэтому отброшенные билеты не уменьшают выдачу
+func TestWithFilters_FiltersBeforeLimit(t *testing.T) {
+	stub := &stubSearcher{flights: testFlights()}
+	fs := WithFilters(stub)
+
+	flights, err := fs.SearchCheap(context.Background(), SearchParams{
+		Limit:  2,
+		Filter: Filter{ExcludeAirlines: []string{"DP"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stub.calledWith.Limit != 0 {
+		t.Errorf("expected unlimited upstream search, got limit %d", stub.calledWith.Limit)
+	}
+	if got := airlinesOf(flights); len(got) != 2 || got[0] != "SU" || got[1] != "S7" {
+		t.Errorf("unexpected flights: %v", got)
+	}
+
+	// Без фильтра параметры передаются как есть
+	if _, err := fs.SearchCheap(context.Background(), SearchParams{Limit: 2}); err != nil || stub.calledWith.Limit != 2 {
+		t.Errorf("expected limit 2 without filter, got %d (err %v)", stub.calledWith.Limit, err)
+	}
+
+	// В v3 Limit — размер страницы API и не снимается
+	if _, err := fs.SearchCheap(context.Background(), SearchParams{API: PricesAPIV3, Limit: 2, Filter: Filter{MaxPrice: 10000}}); err != nil || stub.calledWith.Limit != 2 {
+		t.Errorf("expected v3 page size 2, got %d (err %v)", stub.calledWith.Limit, err)
+	}
+
+	if _, err := fs.SearchCheap(context.Background(), SearchParams{Filter: Filter{MinPrice: -1}}); err == nil {
+		t.Error("expected error for invalid filter")
+	}
+}
@@ -0,0 +1,196 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"aviasales-bot/search-service/internal/fanout"
+)
+
+// MaxFlexDays наибольший разброс дат вылета в SearchParams.FlexDays: не больше 15 запросов к API
+const MaxFlexDays = 7
+
+// defaultFlexConcurrency сколько дат поиска с гибкими датами выполняются одновременно
+const defaultFlexConcurrency = 4
+
+const dateLayout = "2006-01-02"
+
+// CheapSearcher часть FlightSearcher, которой достаточно для поиска с гибкими датами
+type CheapSearcher interface {
+	SearchCheap(ctx context.Context, p SearchParams) ([]Flight, error)
+	SearchDirect(ctx context.Context, p SearchParams) ([]Flight, error)
+}
+
+// FlexResult результат поиска на ±FlexDays дней от даты вылета
+type FlexResult struct {
+	Days    map[string]Flight `json:"days"`             // самый дешевый билет на каждый день вылета, ключ — YYYY-MM-DD
+	Best    *Flight           `json:"best,omitempty"`   // самый дешевый билет во всем окне дат
+	Flights []Flight          `json:"flights"`          // найденные билеты без дублей в порядке Sort, не больше Limit
+	Errors  map[string]string `json:"errors,omitempty"` // даты, поиск на которые не удался, и причина
+}
+
+// FlexSearcher ищет билеты на окно дат вокруг SearchParams.DepartDate параллельными запросами
+type FlexSearcher struct {
+	fs          CheapSearcher
+	concurrency int
+	now         func() time.Time
+}
+
+// FlexOption настраивает FlexSearcher
+type FlexOption func(*FlexSearcher)
+
+// WithFlexConcurrency ограничивает число одновременных запросов к API
+func WithFlexConcurrency(n int) FlexOption {
+	return func(s *FlexSearcher) {
+		if n > 0 {
+			s.concurrency = n
+		}
+	}
+}
+
+// NewFlexSearcher создает поиск с гибкими датами поверх fs
+func NewFlexSearcher(fs CheapSearcher, opts ...FlexOption) *FlexSearcher {
+	s := &FlexSearcher{
+		fs:          fs,
+		concurrency: defaultFlexConcurrency,
+		now:         time.Now,
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
+// ValidateFlex проверяет параметры поиска с гибкими датами: точная дата вылета и разброс до MaxFlexDays
+func ValidateFlex(p SearchParams) error {
+	if p.FlexDays < 0 || p.FlexDays > MaxFlexDays {
+		return fmt.Errorf("flex_days must be between 0 and %d", MaxFlexDays)
+	}
+	if _, err := time.Parse(dateLayout, p.DepartDate); err != nil {
+		return fmt.Errorf("flex_days requires depart_date as YYYY-MM-DD")
+	}
+	return nil
+}
+
+// Search ищет самые дешевые билеты на каждый день окна DepartDate ± FlexDays; direct — только прямые рейсы.
+// Точная дата возвращения сдвигается вместе с датой вылета, сохраняя длительность поездки.
+// Прошедшие даты пропускаются. Сбой на одну дату не прерывает поиск: дата попадает
+// в FlexResult.Errors, а Days и Best считаются по остальным дням окна.
+func (s *FlexSearcher) Search(ctx context.Context, p SearchParams, direct bool) (*FlexResult, error) {
+	if err := ValidateFlex(p); err != nil {
+		return nil, err
+	}
+	queries := s.queries(p)
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("all dates around %s are in the past", p.DepartDate)
+	}
+
+	search := s.fs.SearchCheap
+	if direct {
+		search = s.fs.SearchDirect
+	}
+
+	found, errs := fanout.Map(queries, s.concurrency, func(q SearchParams) ([]Flight, error) {
+		return search(ctx, q)
+	})
+
+	result := &FlexResult{Days: make(map[string]Flight)}
+	for i, q := range queries {
+		if errs[i] != nil {
+			if result.Errors == nil {
+				result.Errors = make(map[string]string)
+			}
+			result.Errors[q.DepartDate] = errs[i].Error()
+		}
+	}
+	if len(result.Errors) == len(queries) {
+		return nil, fmt.Errorf("flexible search failed for all dates: %w", errors.Join(errs...))
+	}
+
+	seen := make(map[flightKey]bool)
+	for i, q := range queries {
+		for _, flight := range found[i] {
+			key := keyOf(flight)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			result.Flights = append(result.Flights, flight)
+
+			day := q.DepartDate
+			if !flight.DepartDate.IsZero() {
+				day = flight.DepartDate.Format(dateLayout)
+			}
+			if best, ok := result.Days[day]; !ok || cheaper(flight, best) {
+				result.Days[day] = flight
+			}
+		}
+	}
+
+	for _, flight := range result.Flights {
+		if result.Best == nil || cheaper(flight, *result.Best) {
+			best := flight
+			result.Best = &best
+		}
+	}
+	SortFlights(result.Flights, p.Sort)
+	if p.Limit > 0 && len(result.Flights) > p.Limit {
+		result.Flights = result.Flights[:p.Limit]
+	}
+	return result, nil
+}
+
+// queries строит запросы на каждую непрошедшую дату окна в порядке возрастания
+func (s *FlexSearcher) queries(p SearchParams) []SearchParams {
+	depart, _ := time.Parse(dateLayout, p.DepartDate)
+	ret, exactReturn := time.Time{}, false
+	if t, err := time.Parse(dateLayout, p.ReturnDate); err == nil {
+		ret, exactReturn = t, true
+	}
+	today := s.now().Format(dateLayout)
+
+	var queries []SearchParams
+	for offset := -p.FlexDays; offset <= p.FlexDays; offset++ {
+		q := p
+		q.FlexDays = 0
+		q.DepartDate = depart.AddDate(0, 0, offset).Format(dateLayout)
+		if q.DepartDate < today {
+			continue
+		}
+		if exactReturn {
+			q.ReturnDate = ret.AddDate(0, 0, offset).Format(dateLayout)
+		}
+		queries = append(queries, q)
+	}
+	return queries
+}
+
+// flightKey определяет один и тот же билет в ответах на разные даты
+type flightKey struct {
+	origin, destination string
+	depart, ret         time.Time
+	airline             string
+	flightNumber, price int
+}
+
+func keyOf(f Flight) flightKey {
+	return flightKey{
+		origin:       f.Origin,
+		destination:  f.Destination,
+		depart:       f.DepartDate.UTC(),
+		ret:          f.ReturnDate.UTC(),
+		airline:      f.Airline,
+		flightNumber: f.FlightNumber,
+		price:        f.Price,
+	}
+}
+
+// cheaper сравнивает билеты по цене, при равной цене — по дате вылета
+func cheaper(a, b Flight) bool {
+	if a.Price != b.Price {
+		return a.Price < b.Price
+	}
+	return a.DepartDate.Before(b.DepartDate)
+}
@@ -0,0 +1,140 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// datedSearcher отдает билеты по дате вылета и считает одновременные запросы
+type datedSearcher struct {
+	mu        sync.Mutex
+	byDate    map[string][]Flight
+	failing   map[string]bool
+	calls     []SearchParams
+	active    int
+	maxActive int
+	direct    bool
+}
+
+func (s *datedSearcher) SearchCheap(_ context.Context, p SearchParams) ([]Flight, error) {
+	s.mu.Lock()
+	s.calls = append(s.calls, p)
+	s.active++
+	if s.active > s.maxActive {
+		s.maxActive = s.active
+	}
+	s.mu.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active--
+	if s.failing[p.DepartDate] {
+		return nil, errors.New("upstream timeout")
+	}
+	return s.byDate[p.DepartDate], nil
+}
+
+func (s *datedSearcher) SearchDirect(ctx context.Context, p SearchParams) ([]Flight, error) {
+	s.mu.Lock()
+	s.direct = true
+	s.mu.Unlock()
+	return s.SearchCheap(ctx, p)
+}
+
+func fare(day, price int, airline string) Flight {
+	return Flight{
+		Origin:      "MOW",
+		Destination: "AER",
+		DepartDate:  time.Date(2031, 12, day, 10, 0, 0, 0, time.UTC),
+		Price:       price,
+		Airline:     airline,
+	}
+}
+
+func TestFlexSearcher_Search(t *testing.T) {
+	shared := fare(15, 7000, "SU") // API отдает один и тот же билет в ответах на соседние даты
+	searcher := &datedSearcher{
+		byDate: map[string][]Flight{
+			"2031-12-13": {fare(13, 9000, "DP"), fare(13, 11000, "SU")},
+			"2031-12-14": {shared},
+			"2031-12-15": {shared, fare(15, 8000, "S7")},
+			"2031-12-17": {fare(17, 6500, "UT")},
+		},
+		failing: map[string]bool{"2031-12-16": true},
+	}
+	fs := NewFlexSearcher(searcher, WithFlexConcurrency(2))
+
+	result, err := fs.Search(context.Background(), SearchParams{
+		Origin:      "MOW",
+		Destination: "AER",
+		DepartDate:  "2031-12-15",
+		ReturnDate:  "2031-12-22",
+		Limit:       3,
+		FlexDays:    2,
+	}, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(searcher.calls) != 5 || !searcher.direct {
+		t.Fatalf("expected 5 direct searches, got %d (direct=%v)", len(searcher.calls), searcher.direct)
+	}
+	if searcher.maxActive > 2 {
+		t.Errorf("expected at most 2 concurrent searches, got %d", searcher.maxActive)
+	}
+	for _, call := range searcher.calls {
+		if call.FlexDays != 0 {
+			t.Errorf("flex days leaked into upstream search: %+v", call)
+		}
+		if call.DepartDate == "2031-12-13" && call.ReturnDate != "2031-12-20" {
+			t.Errorf("expected return date shifted to 2031-12-20, got %s", call.ReturnDate)
+		}
+	}
+
+	if result.Best == nil || result.Best.Airline != "UT" {
+		t.Errorf("expected overall best UT, got %+v", result.Best)
+	}
+	if len(result.Days) != 3 || result.Days["2031-12-13"].Price != 9000 || result.Days["2031-12-15"].Price != 7000 {
+		t.Errorf("unexpected cheapest per day: %+v", result.Days)
+	}
+	if len(result.Flights) != 3 || result.Flights[0].Price != 6500 || result.Flights[1].Price != 7000 || result.Flights[2].Price != 8000 {
+		t.Errorf("expected deduplicated flights limited to 3, got %+v", result.Flights)
+	}
+	if result.Errors["2031-12-16"] == "" || len(result.Errors) != 1 {
+		t.Errorf("expected partial failure for 2031-12-16, got %v", result.Errors)
+	}
+}
+
+func TestFlexSearcher_SkipsPastDatesAndFailsWhenAllFail(t *testing.T) {
+	searcher := &datedSearcher{failing: map[string]bool{"2031-12-15": true, "2031-12-16": true}}
+	fs := NewFlexSearcher(searcher)
+	fs.now = func() time.Time { return time.Date(2031, 12, 15, 12, 0, 0, 0, time.UTC) }
+
+	_, err := fs.Search(context.Background(), SearchParams{Origin: "MOW", DepartDate: "2031-12-15", FlexDays: 1}, false)
+	if err == nil {
+		t.Fatal("expected error when all dates fail")
+	}
+	if len(searcher.calls) != 2 {
+		t.Errorf("expected past date to be skipped, got %d searches", len(searcher.calls))
+	}
+}
+
+func TestValidateFlex(t *testing.T) {
+	for name, p := range map[string]SearchParams{
+		"month date": {DepartDate: "2031-12", FlexDays: 3},
+		"too wide":   {DepartDate: "2031-12-15", FlexDays: MaxFlexDays + 1},
+		"negative":   {DepartDate: "2031-12-15", FlexDays: -1},
+	} {
+		if err := ValidateFlex(p); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	if err := ValidateFlex(SearchParams{DepartDate: "2031-12-15", FlexDays: 3}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,221 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"aviasales-bot/search-service/internal/fanout"
+)
+
+// MaxRoutes наибольшее число маршрутов (пар город или аэропорт вылета → назначения) в одном поиске
+const MaxRoutes = 16
+
+// defaultMultiConcurrency сколько маршрутов ищутся одновременно
+const defaultMultiConcurrency = 4
+
+// ParseCodes разбирает список IATA кодов через запятую: верхний регистр, без пустых и повторов
+func ParseCodes(s string) []string {
+	var codes []string
+	seen := make(map[string]bool)
+	for _, code := range strings.Split(s, ",") {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+		codes = append(codes, code)
+	}
+	return codes
+}
+
+// IsMultiRoute сообщает, что поиск идет по нескольким маршрутам: в origin или destination
+// перечислено несколько кодов через запятую или города нужно раскрыть в аэропорты
+func IsMultiRoute(p SearchParams) bool {
+	return p.ExpandAirports || len(ParseCodes(p.Origin)) > 1 || len(ParseCodes(p.Destination)) > 1
+}
+
+// ValidateRoutes проверяет число маршрутов поиска до раскрытия городов в аэропорты
+func ValidateRoutes(p SearchParams) error {
+	origins := len(ParseCodes(p.Origin))
+	destinations := 1
+	if !IsAnywhere(p.Destination) {
+		destinations = len(ParseCodes(p.Destination))
+	}
+	if origins*destinations > MaxRoutes {
+		return fmt.Errorf("too many routes: %d origins × %d destinations, at most %d", origins, destinations, MaxRoutes)
+	}
+	return nil
+}
+
+// AirportResolver раскрывает IATA код города в коды его аэропортов
+type AirportResolver interface {
+	CityAirports(ctx context.Context, code string) ([]string, error)
+}
+
+// MultiResult объединенный результат поиска по нескольким маршрутам
+type MultiResult struct {
+	Flights []Flight          `json:"flights"`          // билеты всех маршрутов в порядке Sort, не больше Limit
+	Routes  []string          `json:"routes"`           // маршруты поиска вида SVO-IST
+	Errors  map[string]string `json:"errors,omitempty"` // маршруты, поиск по которым не удался, и причина
+}
+
+// MultiSearcher ищет билеты сразу из нескольких городов или аэропортов в несколько направлений
+type MultiSearcher struct {
+	fs          CheapSearcher
+	airports    AirportResolver
+	concurrency int
+}
+
+// MultiOption настраивает MultiSearcher
+type MultiOption func(*MultiSearcher)
+
+// WithAirportResolver включает раскрытие городов в аэропорты для SearchParams.ExpandAirports
+func WithAirportResolver(r AirportResolver) MultiOption {
+	return func(s *MultiSearcher) { s.airports = r }
+}
+
+// WithMultiConcurrency ограничивает число одновременных запросов к API
+func WithMultiConcurrency(n int) MultiOption {
+	return func(s *MultiSearcher) {
+		if n > 0 {
+			s.concurrency = n
+		}
+	}
+}
+
+// NewMultiSearcher создает поиск по нескольким маршрутам поверх fs
+func NewMultiSearcher(fs CheapSearcher, opts ...MultiOption) *MultiSearcher {
+	s := &MultiSearcher{
+		fs:          fs,
+		concurrency: defaultMultiConcurrency,
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
+// SearchCheap ищет самые дешевые билеты по всем маршрутам и объединяет их
+func (s *MultiSearcher) SearchCheap(ctx context.Context, p SearchParams) ([]Flight, error) {
+	result, err := s.Search(ctx, p, false)
+	if err != nil {
+		return nil, err
+	}
+	return result.Flights, nil
+}
+
+// SearchDirect ищет самые дешевые прямые рейсы по всем маршрутам и объединяет их
+func (s *MultiSearcher) SearchDirect(ctx context.Context, p SearchParams) ([]Flight, error) {
+	result, err := s.Search(ctx, p, true)
+	if err != nil {
+		return nil, err
+	}
+	return result.Flights, nil
+}
+
+// Search ищет билеты по каждой паре вылет → назначение из списков Origin и Destination;
+// с ExpandAirports коды городов заменяются их аэропортами. У каждого билета заполнен его
+// фактический маршрут. Маршрут, поиск по которому не удался, остается в MultiResult.Routes
+// с причиной в MultiResult.Errors, а его билеты просто не попадают в ответ.
+func (s *MultiSearcher) Search(ctx context.Context, p SearchParams, direct bool) (*MultiResult, error) {
+	if err := ValidateRoutes(p); err != nil {
+		return nil, err
+	}
+	origins := s.expand(ctx, p, ParseCodes(p.Origin))
+	destinations := []string{AnyDestination}
+	if !IsAnywhere(p.Destination) {
+		destinations = s.expand(ctx, p, ParseCodes(p.Destination))
+	}
+
+	var queries []SearchParams
+	for _, origin := range origins {
+		for _, destination := range destinations {
+			if origin == destination {
+				continue
+			}
+			q := p
+			q.Origin, q.Destination, q.ExpandAirports = origin, destination, false
+			queries = append(queries, q)
+		}
+	}
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("no routes to search")
+	}
+	if len(queries) > MaxRoutes {
+		return nil, fmt.Errorf("too many routes after expanding airports: %d, at most %d", len(queries), MaxRoutes)
+	}
+
+	search := s.fs.SearchCheap
+	if direct {
+		search = s.fs.SearchDirect
+	}
+
+	found, errs := fanout.Map(queries, s.concurrency, func(q SearchParams) ([]Flight, error) {
+		return search(ctx, q)
+	})
+
+	result := &MultiResult{Routes: make([]string, 0, len(queries))}
+	for i, q := range queries {
+		route := q.Origin + "-" + q.Destination
+		result.Routes = append(result.Routes, route)
+		if errs[i] != nil {
+			if result.Errors == nil {
+				result.Errors = make(map[string]string)
+			}
+			result.Errors[route] = errs[i].Error()
+		}
+	}
+	if len(result.Errors) == len(queries) {
+		return nil, fmt.Errorf("search failed for all routes: %w", errors.Join(errs...))
+	}
+
+	seen := make(map[flightKey]bool)
+	for i, q := range queries {
+		for _, flight := range found[i] {
+			if flight.Origin == "" {
+				flight.Origin = q.Origin
+			}
+			if flight.Destination == "" && q.Destination != AnyDestination {
+				flight.Destination = q.Destination
+			}
+			key := keyOf(flight)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			result.Flights = append(result.Flights, flight)
+		}
+	}
+
+	SortFlights(result.Flights, p.Sort)
+	if p.Limit > 0 && len(result.Flights) > p.Limit {
+		result.Flights = result.Flights[:p.Limit]
+	}
+	return result, nil
+}
+
+// expand раскрывает коды городов в аэропорты, если это запрошено; при ошибке справочника
+// город остается как есть — Data API принимает и коды городов
+func (s *MultiSearcher) expand(ctx context.Context, p SearchParams, codes []string) []string {
+	if !p.ExpandAirports || s.airports == nil {
+		return codes
+	}
+
+	var expanded []string
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		airports, err := s.airports.CityAirports(ctx, code)
+		if err != nil || len(airports) == 0 {
+			airports = []string{code}
+		}
+		for _, airport := range airports {
+			if !seen[airport] {
+				seen[airport] = true
+				expanded = append(expanded, airport)
+			}
+		}
+	}
+	return expanded
+}
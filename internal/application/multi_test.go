@@ -0,0 +1,111 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// routeSearcher отдает билеты по маршруту запроса и запоминает маршруты
+type routeSearcher struct {
+	mu      sync.Mutex
+	prices  map[string]int
+	failing map[string]bool
+	routes  []string
+}
+
+func (s *routeSearcher) SearchCheap(_ context.Context, p SearchParams) ([]Flight, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	route := p.Origin + "-" + p.Destination
+	s.routes = append(s.routes, route)
+	if s.failing[route] {
+		return nil, errors.New("upstream timeout")
+	}
+	// Data API не всегда возвращает origin: маршрут должен заполнить MultiSearcher
+	return []Flight{{Destination: p.Destination, Price: s.prices[route], Airline: "SU"}}, nil
+}
+
+func (s *routeSearcher) SearchDirect(ctx context.Context, p SearchParams) ([]Flight, error) {
+	return s.SearchCheap(ctx, p)
+}
+
+type airportsMap map[string][]string
+
+func (m airportsMap) CityAirports(_ context.Context, code string) ([]string, error) {
+	if airports, ok := m[code]; ok {
+		return airports, nil
+	}
+	return []string{code}, nil
+}
+
+func TestParseCodes(t *testing.T) {
+	if got := strings.Join(ParseCodes(" ist, ayt,,IST "), ","); got != "IST,AYT" {
+		t.Errorf("unexpected codes: %s", got)
+	}
+}
+
+func TestMultiSearcher_Search(t *testing.T) {
+	searcher := &routeSearcher{
+		prices: map[string]int{
+			"SVO-IST": 14000, "SVO-AYT": 12000,
+			"DME-IST": 9500, "DME-AYT": 16000,
+		},
+		failing: map[string]bool{"VKO-IST": true, "VKO-AYT": true},
+	}
+	fs := NewMultiSearcher(searcher,
+		WithAirportResolver(airportsMap{"MOW": {"DME", "SVO", "VKO"}}),
+		WithMultiConcurrency(2),
+	)
+
+	result, err := fs.Search(context.Background(), SearchParams{
+		Origin:         "MOW",
+		Destination:    "IST,AYT",
+		ExpandAirports: true,
+		Limit:          3,
+	}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sort.Strings(searcher.routes)
+	if strings.Join(searcher.routes, " ") != "DME-AYT DME-IST SVO-AYT SVO-IST VKO-AYT VKO-IST" {
+		t.Errorf("unexpected routes: %v", searcher.routes)
+	}
+	var got []string
+	for _, f := range result.Flights {
+		got = append(got, f.Origin+"-"+f.Destination)
+	}
+	if strings.Join(got, " ") != "DME-IST SVO-AYT SVO-IST" {
+		t.Errorf("expected merged flights by price with actual routes, got %v", got)
+	}
+	if len(result.Routes) != 6 || len(result.Errors) != 2 {
+		t.Errorf("unexpected routes %v or errors %v", result.Routes, result.Errors)
+	}
+
+	// Без раскрытия аэропортов ищем по перечисленным кодам, сортировка по запросу
+	searcher.routes = nil
+	result, err = fs.Search(context.Background(), SearchParams{Origin: "SVO,DME", Destination: "AYT", Sort: SortByDeparture}, false)
+	if err != nil || len(searcher.routes) != 2 || len(result.Flights) != 2 || result.Flights[0].Origin != "SVO" {
+		t.Errorf("unexpected result %+v (routes %v, err %v)", result, searcher.routes, err)
+	}
+}
+
+func TestMultiSearcher_FailsWhenAllRoutesFail(t *testing.T) {
+	searcher := &routeSearcher{failing: map[string]bool{"SVO-IST": true, "DME-IST": true}}
+	if _, err := NewMultiSearcher(searcher).Search(context.Background(), SearchParams{Origin: "SVO,DME", Destination: "IST"}, false); err == nil {
+		t.Error("expected error when all routes fail")
+	}
+}
+
+func TestValidateRoutes(t *testing.T) {
+	if err := ValidateRoutes(SearchParams{Origin: "A1,A2,A3,A4,A5", Destination: "B1,B2,B3,B4"}); err == nil {
+		t.Error("expected error for 20 routes")
+	}
+	if err := ValidateRoutes(SearchParams{Origin: "SVO,DME,VKO", Destination: AnyDestination}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -2,17 +2,66 @@ package application
 
 import (
 	"context"
+	"strings"
 	"time"
 )
 
+// AnyDestination направление поиска «куда угодно»: самые дешевые билеты из города во все направления
+const AnyDestination = "-"
+
+// IsAnywhere сообщает, что направление не задано: пустая строка, "-" или "anywhere"
+func IsAnywhere(destination string) bool {
+	switch strings.ToLower(strings.TrimSpace(destination)) {
+	case "", AnyDestination, "anywhere":
+		return true
+	default:
+		return false
+	}
+}
+
+// Версии API цен, между которыми выбирает SearchParams.API
+const (
+	PricesAPIV1 = "v1" // /v1/prices/cheap и /v1/prices/direct (по умолчанию)
+	PricesAPIV3 = "v3" // /aviasales/v3/prices_for_dates: one_way, sorting, unique, page
+)
+
+// Порядок результатов поиска в SearchParams.Sort
+const (
+	SortByPrice     = "price"     // сначала самые дешевые (по умолчанию)
+	SortByDeparture = "departure" // по времени вылета
+	SortByDuration  = "duration"  // сначала самые короткие
+	SortByTransfers = "transfers" // сначала с меньшим числом пересадок
+)
+
+// ValidSort проверяет порядок сортировки; пустая строка — по цене
+func ValidSort(sort string) bool {
+	switch sort {
+	case "", SortByPrice, SortByDeparture, SortByDuration, SortByTransfers:
+		return true
+	default:
+		return false
+	}
+}
+
 // SearchParams параметры поиска авиабилетов
 type SearchParams struct {
-	Origin      string // IATA код города отправления
-	Destination string // IATA код города назначения
+	Origin      string // IATA код города или аэропорта отправления; несколько через запятую — см. MultiSearcher
+	Destination string // IATA код назначения или список через запятую; AnyDestination — все направления
 	DepartDate  string // Дата вылета (YYYY-MM-DD или YYYY-MM)
 	ReturnDate  string // Дата возвращения (YYYY-MM-DD или YYYY-MM)
 	Currency    string // Валюта (rub, usd, eur)
-	Limit       int    // Максимальное количество результатов
+	Limit       int    // Максимальное количество результатов (направлений при поиске «куда угодно»)
+	Sort        string // Порядок результатов (SortBy*), применяется до Limit (см. WithSorting)
+	Filter      Filter // Ограничения на найденные билеты (см. WithFilters)
+	FlexDays    int    // Разброс даты вылета ±N дней (см. FlexSearcher), 0 — точная дата
+
+	ExpandAirports bool // Раскрыть города в аэропорты и искать из каждого по отдельности (см. MultiSearcher)
+
+	API     string // Версия API цен: PricesAPIV1 (по умолчанию) или PricesAPIV3
+	OneWay  bool   // Билеты в одну сторону (v3)
+	Sorting string // price или route (v3)
+	Unique  bool   // Один билет на направление (v3)
+	Page    int    // Номер страницы, начиная с 1 (v3)
 }
 
 // Flight представляет информацию о рейсе
@@ -25,20 +74,174 @@ type Flight struct {
 	Airline      string    `json:"airline"`
 	FlightNumber int       `json:"flight_number"`
 	Duration     int       `json:"duration"`
+	Transfers    int       `json:"transfers"`
 	Distance     int       `json:"distance"`
 	Gate         string    `json:"gate"`
 	ExpiresAt    time.Time `json:"expires_at"`
 	Actual       bool      `json:"actual"`
+
+	ReturnTransfers int    `json:"return_transfers"`        // пересадки на обратном пути (v3)
+	DurationTo      int    `json:"duration_to,omitempty"`   // длительность перелета туда в минутах (v3)
+	DurationBack    int    `json:"duration_back,omitempty"` // длительность перелета обратно в минутах (v3)
+	Link            string `json:"link,omitempty"`          // ссылка на поиск с этим билетом (v3)
+
+	OriginName      string `json:"origin_name,omitempty"`      // название города отправления
+	DestinationName string `json:"destination_name,omitempty"` // название города назначения
+}
+
+// CalendarParams параметры календаря цен
+type CalendarParams struct {
+	Origin       string // IATA код города отправления
+	Destination  string // IATA код города назначения
+	Month        string // Месяц вылета (YYYY-MM)
+	ReturnMonth  string // Месяц возвращения (YYYY-MM), необязательно
+	CalendarType string // departure_date (по умолчанию) или return_date
+	Length       int    // Длительность поездки в днях, необязательно
+	Currency     string // Валюта (rub, usd, eur)
+}
+
+// PriceCalendar самые дешевые билеты на каждый день месяца
+type PriceCalendar struct {
+	Origin       string            `json:"origin"`
+	Destination  string            `json:"destination"`
+	Month        string            `json:"month"`
+	CalendarType string            `json:"calendar_type"`
+	Currency     string            `json:"currency"`
+	Days         map[string]Flight `json:"days"` // ключ — дата YYYY-MM-DD
+}
+
+// MatrixParams параметры матрицы цен
+type MatrixParams struct {
+	Origin      string // IATA код города отправления
+	Destination string // IATA код города назначения
+	DepartDate  string // Дата вылета (YYYY-MM-DD); для месячной матрицы достаточно YYYY-MM
+	ReturnDate  string // Дата возвращения (YYYY-MM-DD), для недельной матрицы
+	Currency    string // Валюта (rub, usd, eur)
+}
+
+// PriceMatrix цены по сетке дат вылета и возвращения
+type PriceMatrix struct {
+	Origin      string   `json:"origin"`
+	Destination string   `json:"destination"`
+	Currency    string   `json:"currency"`
+	Flights     []Flight `json:"flights"` // по возрастанию даты вылета, затем даты возвращения
+}
+
+// GroupedParams параметры поиска самых дешевых билетов по периодам
+type GroupedParams struct {
+	Origin      string // IATA код города отправления
+	Destination string // IATA код города назначения
+	GroupBy     string // month (по умолчанию) или departure_at
+	DepartureAt string // Дата или месяц вылета (YYYY-MM-DD или YYYY-MM), необязательно
+	ReturnAt    string // Дата или месяц возвращения, необязательно
+	Direct      bool   // Только прямые рейсы
+	Currency    string // Валюта (rub, usd, eur)
+}
+
+// GroupedPrices самый дешевый билет на каждый период
+type GroupedPrices struct {
+	Origin      string            `json:"origin"`
+	Destination string            `json:"destination"`
+	GroupBy     string            `json:"group_by"`
+	Currency    string            `json:"currency"`
+	Periods     map[string]Flight `json:"periods"` // ключ — YYYY-MM для месяцев, YYYY-MM-DD для дней
+}
+
+// LatestParams параметры ленты последних найденных цен
+type LatestParams struct {
+	Origin            string // IATA код города отправления, необязательно
+	Destination       string // IATA код города назначения, необязательно (пусто — куда угодно)
+	Currency          string // Валюта (rub, usd, eur)
+	PeriodType        string // Период дат вылета: year или month
+	BeginningOfPeriod string // Начало периода (YYYY-MM-DD), обязательно для period_type=month
+	OneWay            bool   // Только билеты в одну сторону
+	Page              int    // Номер страницы, начиная с 1
+	Limit             int    // Размер страницы (до 1000)
+	ShowToAffiliates  bool   // Только цены, найденные с партнерским marker
+	Sorting           string // price, route или distance_unit_price
+	TripClass         int    // 0 — эконом, 1 — бизнес, 2 — первый класс
+}
+
+// MaxAirlineRoutes наибольшее число маршрутов авиакомпании в AirlineParams.Limit:
+// цена каждого маршрута — отдельный запрос к API
+const MaxAirlineRoutes = 30
+
+// AirlineParams параметры поиска популярных маршрутов авиакомпании
+type AirlineParams struct {
+	Airline    string // IATA код авиакомпании
+	Origin     string // IATA код города отправления, необязательно
+	DepartDate string // Дата вылета (YYYY-MM-DD или YYYY-MM), необязательно
+	Currency   string // Валюта (rub, usd, eur)
+	Limit      int    // Сколько популярных маршрутов проверять, 1..MaxAirlineRoutes
+}
+
+// SpecialOffer спецпредложение авиакомпании на одном маршруте
+type SpecialOffer struct {
+	Airline         string    `json:"airline"`      // название авиакомпании
+	AirlineCode     string    `json:"airline_code"` // IATA код авиакомпании
+	Title           string    `json:"title"`
+	Description     string    `json:"description,omitempty"`
+	Origin          string    `json:"origin"`
+	Destination     string    `json:"destination"`
+	OriginName      string    `json:"origin_name,omitempty"`
+	DestinationName string    `json:"destination_name,omitempty"`
+	Price           int       `json:"price"`
+	Currency        string    `json:"currency"`
+	OneWay          bool      `json:"one_way"`
+	SaleStart       time.Time `json:"sale_start"`             // с какого момента действует предложение
+	SaleEnd         time.Time `json:"sale_end"`               // до какого момента можно купить билет
+	FlightStart     time.Time `json:"flight_start,omitempty"` // начало периода вылета
+	FlightEnd       time.Time `json:"flight_end,omitempty"`   // конец периода вылета
+	Link            string    `json:"link"`
 }
 
 // FlightSearcher интерфейс для поиска авиабилетов
 type FlightSearcher interface {
-	// SearchCheap ищет самые дешевые билеты
+	// SearchCheap ищет самые дешевые билеты; с AnyDestination — самый дешевый билет в каждый город
 	SearchCheap(ctx context.Context, p SearchParams) ([]Flight, error)
 
+	// SearchDirect ищет самые дешевые билеты только на прямые рейсы
+	SearchDirect(ctx context.Context, p SearchParams) ([]Flight, error)
+
 	// GeneratePartnerLink генерирует партнерскую ссылку для покупки
 	GeneratePartnerLink(flight Flight, passengers int) string
 
-	// FormatFlightMessage форматирует сообщение с билетами для пользователя
-	FormatFlightMessage(originCity, destCity string, flights []Flight, passengers int) string
+	// FormatFlightMessage форматирует сообщение с билетами для пользователя; цены — в валюте currency
+	FormatFlightMessage(originCity, destCity string, flights []Flight, passengers int, currency string) string
+
+	// CityAirports возвращает коды аэропортов города; код аэропорта возвращается как есть
+	CityAirports(ctx context.Context, code string) ([]string, error)
+
+	// SearchCalendar возвращает самые дешевые билеты на каждый день месяца
+	SearchCalendar(ctx context.Context, p CalendarParams) (*PriceCalendar, error)
+
+	// FormatCalendarMessage форматирует календарь цен в сетку месяца для пользователя
+	FormatCalendarMessage(originCity, destCity string, cal *PriceCalendar) string
+
+	// SearchWeekMatrix возвращает цены на ±3 дня от дат вылета и возвращения
+	SearchWeekMatrix(ctx context.Context, p MatrixParams) (*PriceMatrix, error)
+
+	// SearchMonthMatrix возвращает цены на каждый день месяца вылета
+	SearchMonthMatrix(ctx context.Context, p MatrixParams) (*PriceMatrix, error)
+
+	// SearchGroupedPrices возвращает самый дешевый билет на каждый месяц или день вылета
+	SearchGroupedPrices(ctx context.Context, p GroupedParams) (*GroupedPrices, error)
+
+	// FormatGroupedMessage форматирует лучшие цены по периодам для пользователя
+	FormatGroupedMessage(originCity, destCity string, g *GroupedPrices) string
+
+	// SearchLatest возвращает цены, найденные за последние 48 часов, постранично
+	SearchLatest(ctx context.Context, p LatestParams) ([]Flight, error)
+
+	// PopularDirections возвращает популярные направления из города с актуальными ценами
+	PopularDirections(ctx context.Context, origin, currency string) ([]Flight, error)
+
+	// SearchAirlineRoutes возвращает популярные маршруты авиакомпании с ее самыми дешевыми билетами
+	SearchAirlineRoutes(ctx context.Context, p AirlineParams) ([]Flight, error)
+
+	// SpecialOffers возвращает действующие спецпредложения авиакомпаний по возрастанию цены
+	SpecialOffers(ctx context.Context) ([]SpecialOffer, error)
+
+	// FormatOffersMessage форматирует спецпредложения для пользователя
+	FormatOffersMessage(offers []SpecialOffer) string
 }
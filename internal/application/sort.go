@@ -0,0 +1,118 @@
+package application
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// SortFlights упорядочивает билеты по SearchParams.Sort (SortBy*, пусто — по цене).
+// Равные по ключу билеты сравниваются по цене, вылету, маршруту, авиакомпании, номеру рейса,
+// дате возвращения и пересадкам, поэтому порядок не зависит от обхода map в ответе API.
+func SortFlights(flights []Flight, by string) {
+	sort.SliceStable(flights, func(i, j int) bool {
+		return compareFlights(flights[i], flights[j], by) < 0
+	})
+}
+
+// compareFlights сравнивает билеты по ключу by, затем по стабильной цепочке полей
+func compareFlights(a, b Flight, by string) int {
+	var c int
+	switch by {
+	case SortByDeparture:
+		c = a.DepartDate.Compare(b.DepartDate)
+	case SortByDuration:
+		c = compareDuration(a.Duration, b.Duration)
+	case SortByTransfers:
+		c = compareInt(a.Transfers, b.Transfers)
+	}
+	if c != 0 {
+		return c
+	}
+
+	for _, c := range []int{
+		compareInt(a.Price, b.Price),
+		a.DepartDate.Compare(b.DepartDate),
+		strings.Compare(a.Origin, b.Origin),
+		strings.Compare(a.Destination, b.Destination),
+		strings.Compare(a.Airline, b.Airline),
+		compareInt(a.FlightNumber, b.FlightNumber),
+		a.ReturnDate.Compare(b.ReturnDate),
+		compareInt(a.Transfers, b.Transfers),
+	} {
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareDuration сравнивает длительности; неизвестная (0) длительность больше любой известной
+func compareDuration(a, b int) int {
+	switch {
+	case a == b:
+		return 0
+	case a == 0:
+		return 1
+	case b == 0:
+		return -1
+	}
+	return compareInt(a, b)
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// sortingSearcher упорядочивает результаты SearchCheap и SearchDirect по SearchParams.Sort
+type sortingSearcher struct {
+	FlightSearcher
+}
+
+// WithSorting оборачивает FlightSearcher так, что билеты упорядочиваются по SearchParams.Sort.
+// Для v1 билеты запрашиваются без лимита и обрезаются до Limit уже после сортировки,
+// чтобы лимит не отрезал лучшие билеты; в v3 Limit — размер страницы API, и порядок
+// меняется внутри страницы, только если Sort задан явно.
+func WithSorting(fs FlightSearcher) FlightSearcher {
+	return &sortingSearcher{FlightSearcher: fs}
+}
+
+func (s *sortingSearcher) SearchCheap(ctx context.Context, p SearchParams) ([]Flight, error) {
+	return s.search(ctx, p, s.FlightSearcher.SearchCheap)
+}
+
+func (s *sortingSearcher) SearchDirect(ctx context.Context, p SearchParams) ([]Flight, error) {
+	return s.search(ctx, p, s.FlightSearcher.SearchDirect)
+}
+
+func (s *sortingSearcher) search(ctx context.Context, p SearchParams, find func(context.Context, SearchParams) ([]Flight, error)) ([]Flight, error) {
+	if p.API == PricesAPIV3 {
+		flights, err := find(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		if p.Sort != "" {
+			SortFlights(flights, p.Sort)
+		}
+		return flights, nil
+	}
+
+	limit := p.Limit
+	p.Limit = 0
+	flights, err := find(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	SortFlights(flights, p.Sort)
+	if limit > 0 && len(flights) > limit {
+		flights = flights[:limit]
+	}
+	return flights, nil
+}
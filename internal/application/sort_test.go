@@ -0,0 +1,75 @@
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// Тест сортировки билетов по каждому ключу со стабильным порядком равных
+func TestSortFlights(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 12, d, 10, 0, 0, 0, time.UTC) }
+	flights := []Flight{
+		{Destination: "AER", Price: 9000, DepartDate: day(20), Duration: 0, Transfers: 1, Airline: "SU"},
+		{Destination: "LED", Price: 5000, DepartDate: day(15), Duration: 90, Transfers: 0, Airline: "FV"},
+		{Destination: "IST", Price: 9000, DepartDate: day(12), Duration: 240, Transfers: 2, Airline: "TK"},
+		{Destination: "KZN", Price: 7000, DepartDate: day(18), Duration: 95, Transfers: 0, Airline: "DP"},
+	}
+
+	cases := map[string][]string{
+		"":              {"LED", "KZN", "IST", "AER"},
+		SortByPrice:     {"LED", "KZN", "IST", "AER"},
+		SortByDeparture: {"IST", "LED", "KZN", "AER"},
+		SortByDuration:  {"LED", "KZN", "IST", "AER"}, // без длительности — в конце
+		SortByTransfers: {"LED", "KZN", "AER", "IST"},
+	}
+	for by, want := range cases {
+		sorted := append([]Flight(nil), flights...)
+		SortFlights(sorted, by)
+		for i, f := range sorted {
+			if f.Destination != want[i] {
+				t.Errorf("sort %q: position %d is %s, want %v", by, i, f.Destination, want)
+				break
+			}
+		}
+	}
+}
+
+// Тест: сортировка применяется до лимита, поэтому лучший по ключу билет не отрезается
+func TestWithSorting_SortsBeforeLimit(t *testing.T) {
+	search := func(p SearchParams) ([]Flight, SearchParams) {
+		stub := &stubSearcher{flights: testFlights()}
+		flights, err := WithSorting(stub).SearchCheap(context.Background(), p)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return flights, stub.calledWith
+	}
+
+	flights, called := search(SearchParams{Limit: 1, Sort: SortByDuration})
+	if called.Limit != 0 {
+		t.Errorf("expected unlimited upstream search, got limit %d", called.Limit)
+	}
+	if got := airlinesOf(flights); len(got) != 1 || got[0] != "SU" {
+		t.Errorf("expected shortest SU fare, got %v", got)
+	}
+
+	// Без sort — по цене
+	flights, _ = search(SearchParams{Limit: 2})
+	if got := airlinesOf(flights); len(got) != 2 || got[0] != "DP" || got[1] != "S7" {
+		t.Errorf("expected cheapest DP, S7, got %v", got)
+	}
+
+	// В v3 Limit — размер страницы API, без sort порядок ответа сохраняется
+	flights, called = search(SearchParams{API: PricesAPIV3, Limit: 2})
+	if called.Limit != 2 {
+		t.Errorf("expected v3 page size 2, got %d", called.Limit)
+	}
+	if got := airlinesOf(flights); len(got) != 2 || got[0] != "DP" || got[1] != "SU" {
+		t.Errorf("expected API order DP, SU, got %v", got)
+	}
+	flights, _ = search(SearchParams{API: PricesAPIV3, Limit: 2, Sort: SortByDuration})
+	if got := airlinesOf(flights); len(got) != 2 || got[0] != "SU" || got[1] != "DP" {
+		t.Errorf("expected duration order SU, DP within the page, got %v", got)
+	}
+}
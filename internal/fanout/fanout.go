@@ -0,0 +1,28 @@
+// Package fanout выполняет независимые запросы параллельно с ограничением одновременных вызовов
+package fanout
+
+import "sync"
+
+// Map вызывает fn для каждого элемента items, одновременно — не больше limit вызовов
+// (limit <= 0 — без ограничения). Результаты и ошибки возвращаются по индексам items;
+// Map ждет завершения всех вызовов.
+func Map[T, R any](items []T, limit int, fn func(T) (R, error)) ([]R, []error) {
+	if limit <= 0 || limit > len(items) {
+		limit = len(items)
+	}
+	results := make([]R, len(items))
+	errs := make([]error, len(items))
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, item := range items {
+		wg.Add(1)
+		go func(i int, item T) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i], errs[i] = fn(item)
+		}(i, item)
+	}
+	wg.Wait()
+	return results, errs
+}
@@ -0,0 +1,53 @@
+package fanout
+
+import (
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMap_KeepsOrderAndErrors(t *testing.T) {
+	results, errs := Map([]int{1, 2, 3}, 2, func(n int) (string, error) {
+		if n == 2 {
+			return "", errors.New("boom")
+		}
+		return strconv.Itoa(n * 10), nil
+	})
+
+	if results[0] != "10" || results[1] != "" || results[2] != "30" {
+		t.Errorf("unexpected results: %v", results)
+	}
+	if errs[0] != nil || errs[1] == nil || errs[2] != nil {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
+
+func TestMap_Limit(t *testing.T) {
+	var running, peak int32
+	items := make([]int, 10)
+	Map(items, 3, func(int) (struct{}, error) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return struct{}{}, nil
+	})
+
+	if peak > 3 {
+		t.Errorf("expected at most 3 concurrent calls, got %d", peak)
+	}
+}
+
+func TestMap_Empty(t *testing.T) {
+	results, errs := Map(nil, 4, func(int) (int, error) { return 1, nil })
+	if len(results) != 0 || len(errs) != 0 {
+		t.Errorf("expected empty results, got %v %v", results, errs)
+	}
+}
@@ -0,0 +1,173 @@
+package aviasales
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"aviasales-bot/search-service/internal/fanout"
+)
+
+// AirlineParams параметры поиска популярных маршрутов авиакомпании
+type AirlineParams struct {
+	Airline    string // IATA код авиакомпании
+	Origin     string // IATA код города отправления, необязательно
+	DepartDate string // Дата вылета (YYYY-MM-DD или YYYY-MM), необязательно
+	Currency   string // Валюта (rub, usd, eur)
+	Limit      int    // Сколько популярных маршрутов проверять: 0 — defaultAirlineRoutes, не больше MaxAirlineRoutes
+}
+
+// airlineRoute маршрут авиакомпании из /v1/airline-directions
+type airlineRoute struct {
+	Origin      string
+	Destination string
+	Popularity  int
+}
+
+// airlineDirectionsResponse ответ /v1/airline-directions: маршрут "MOW-AER" → популярность
+type airlineDirectionsResponse struct {
+	Success bool           `json:"success"`
+	Data    map[string]int `json:"data"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// airlinePriceWorkers ограничивает число параллельных запросов цен по маршрутам
+const airlinePriceWorkers = 4
+
+// MaxAirlineRoutes наибольшее число маршрутов авиакомпании, цены которых запрашиваются за один поиск
+const MaxAirlineRoutes = 30
+
+// defaultAirlineRoutes сколько маршрутов проверяется, если AirlineParams.Limit не задан
+const defaultAirlineRoutes = 10
+
+// SearchAirlineRoutes возвращает популярные маршруты авиакомпании (/v1/airline-directions) с ценами.
+// Для каждого маршрута берется самый дешевый билет этой авиакомпании из /v1/prices/cheap;
+// маршруты без такого билета пропускаются. Результат отсортирован по возрастанию цены.
+// Маршрут, цену которого не удалось получить, логируется как airline_fare_failed и выпадает
+// из списка, не прерывая поиск по остальным.
+func (c *Client) SearchAirlineRoutes(ctx context.Context, p AirlineParams) ([]Flight, error) {
+	routes, err := c.airlineDirections(ctx, p.Airline)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Origin != "" {
+		filtered := routes[:0]
+		for _, route := range routes {
+			if strings.EqualFold(route.Origin, p.Origin) {
+				filtered = append(filtered, route)
+			}
+		}
+		routes = filtered
+	}
+	limit := p.Limit
+	if limit <= 0 {
+		limit = defaultAirlineRoutes
+	}
+	if limit > MaxAirlineRoutes {
+		limit = MaxAirlineRoutes
+	}
+	if len(routes) > limit {
+		routes = routes[:limit]
+	}
+
+	fares, errs := fanout.Map(routes, airlinePriceWorkers, func(route airlineRoute) (*Flight, error) {
+		return c.airlineFare(ctx, p, route)
+	})
+
+	failed := 0
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		failed++
+		// сам запрос уже залогирован в get со статусом и длительностью
+		if c.logger != nil {
+			c.logger.Error("airline_fare_failed", map[string]interface{}{
+				"airline": p.Airline,
+				"route":   routes[i].Origin + "-" + routes[i].Destination,
+				"error":   err.Error(),
+			})
+		}
+	}
+	if failed > 0 && failed == len(routes) {
+		return nil, fmt.Errorf("airline fares failed for all routes: %w", errors.Join(errs...))
+	}
+
+	flights := make([]Flight, 0, len(fares))
+	for _, fare := range fares {
+		if fare != nil {
+			flights = append(flights, *fare)
+		}
+	}
+	sort.SliceStable(flights, func(i, j int) bool { return flights[i].Price < flights[j].Price })
+
+	c.nameCities(ctx, flights)
+	return flights, nil
+}
+
+// airlineDirections возвращает маршруты авиакомпании по убыванию популярности
+func (c *Client) airlineDirections(ctx context.Context, airline string) ([]airlineRoute, error) {
+	q := url.Values{}
+	q.Set("airline_code", airline)
+	q.Set("limit", strconv.Itoa(1000))
+
+	var apiResp airlineDirectionsResponse
+	err := c.get(ctx, "/v1/airline-directions", q, map[string]interface{}{
+		"airline": airline,
+	}, &apiResp)
+	if err != nil {
+		return nil, err
+	}
+
+	if !apiResp.Success {
+		return nil, fmt.Errorf("API error: %s", apiResp.Error)
+	}
+
+	routes := make([]airlineRoute, 0, len(apiResp.Data))
+	for key, popularity := range apiResp.Data {
+		origin, destination, ok := strings.Cut(key, "-")
+		if !ok || origin == "" || destination == "" {
+			continue
+		}
+		routes = append(routes, airlineRoute{Origin: origin, Destination: destination, Popularity: popularity})
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Popularity != routes[j].Popularity {
+			return routes[i].Popularity > routes[j].Popularity
+		}
+		return routes[i].Origin+routes[i].Destination < routes[j].Origin+routes[j].Destination
+	})
+	return routes, nil
+}
+
+// airlineFare возвращает самый дешевый билет авиакомпании на маршруте или nil, если такого билета нет
+func (c *Client) airlineFare(ctx context.Context, p AirlineParams, route airlineRoute) (*Flight, error) {
+	flights, err := c.SearchCheap(ctx, SearchParams{
+		Origin:      route.Origin,
+		Destination: route.Destination,
+		DepartDate:  p.DepartDate,
+		Currency:    p.Currency,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var best *Flight
+	for i := range flights {
+		if flights[i].Airline != p.Airline {
+			continue
+		}
+		if best == nil || flights[i].Price < best.Price {
+			best = &flights[i]
+		}
+	}
+	if best != nil && best.Origin == "" {
+		best.Origin = route.Origin
+	}
+	return best, nil
+}
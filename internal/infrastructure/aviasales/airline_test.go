@@ -0,0 +1,125 @@
+package aviasales
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestClient_SearchAirlineRoutes(t *testing.T) {
+	var mu sync.Mutex
+	var priced []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch r.URL.Path {
+		case "/v1/airline-directions":
+			if q.Get("airline_code") != "DP" {
+				t.Errorf("expected airline_code=DP, got %q", q.Get("airline_code"))
+			}
+			_, _ = w.Write([]byte(`{"success": true, "data": {"MOW-AER": 500, "MOW-LED": 300, "LED-AER": 200, "MOW-KZN": 100, "MOW-MRV": 50, "broken": 10}}`))
+		case "/v1/prices/cheap":
+			mu.Lock()
+			priced = append(priced, q.Get("origin")+"-"+q.Get("destination"))
+			mu.Unlock()
+
+			switch q.Get("destination") {
+			case "AER":
+				_, _ = w.Write([]byte(`{"success": true, "data": {"AER": {
+					"0": {"price": 3900, "airline": "SU", "departure_at": "2024-12-15T10:30:00Z"},
+					"1": {"price": 4200, "airline": "DP", "departure_at": "2024-12-16T10:30:00Z"}
+				}}}`))
+			case "LED":
+				_, _ = w.Write([]byte(`{"success": true, "data": {"LED": {"0": {"price": 2100, "airline": "SU"}}}}`))
+			case "KZN":
+				_, _ = w.Write([]byte(`{"success": true, "data": {"KZN": {"0": {"price": 2500, "airline": "DP", "flight_number": 501}}}}`))
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		case "/data/ru/cities.json":
+			_, _ = w.Write([]byte(`[{"code": "MOW", "name": "Москва"}, {"code": "AER", "name": "Сочи"}, {"code": "KZN", "name": "Казань"}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	lg := &testLogger{}
+	c := NewClient(srv.URL, "TEST_TOKEN", "668475", WithLogger(lg))
+	// код города вылета сравнивается с маршрутами без учета регистра
+	flights, err := c.SearchAirlineRoutes(context.Background(), AirlineParams{Airline: "DP", Origin: "mow", Currency: "rub", Limit: 4})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Проверяются только 4 самых популярных маршрута из MOW
+	sort.Strings(priced)
+	if got := strings.Join(priced, ","); got != "MOW-AER,MOW-KZN,MOW-LED,MOW-MRV" {
+		t.Errorf("unexpected priced routes: %s", got)
+	}
+
+	// LED без билетов DP и MRV с ошибкой пропускаются, остальные — по возрастанию цены
+	if len(flights) != 2 {
+		t.Fatalf("expected 2 routes, got %d: %+v", len(flights), flights)
+	}
+	if flights[0].Destination != "KZN" || flights[0].Price != 2500 || flights[0].Origin != "MOW" || flights[0].DestinationName != "Казань" {
+		t.Errorf("unexpected first route: %+v", flights[0])
+	}
+	if flights[1].Destination != "AER" || flights[1].Price != 4200 || flights[1].Airline != "DP" {
+		t.Errorf("expected cheapest DP fare to AER, got %+v", flights[1])
+	}
+
+	// Ошибка маршрута логируется как ошибка, а не как вызов API с выдуманным статусом
+	if len(lg.errors) != 1 || lg.errors[0] != "airline_fare_failed MOW-MRV" {
+		t.Errorf("expected airline_fare_failed for MOW-MRV, got %v", lg.errors)
+	}
+}
+
+func TestClient_SearchAirlineRoutes_APIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"success": false, "error": "unknown airline"}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "TEST_TOKEN", "668475")
+	if _, err := c.SearchAirlineRoutes(context.Background(), AirlineParams{Airline: "XX"}); err == nil {
+		t.Error("expected API error")
+	}
+}
+
+// Если не удалось получить цену ни по одному маршруту, поиск возвращает ошибку вместо пустого списка
+func TestClient_SearchAirlineRoutes_AllFaresFailed(t *testing.T) {
+	var mu sync.Mutex
+	priced := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/airline-directions":
+			var routes []string
+			for i := 0; i < 40; i++ {
+				routes = append(routes, fmt.Sprintf(`"MOW-A%02d": %d`, i, 100-i))
+			}
+			_, _ = w.Write([]byte(`{"success": true, "data": {` + strings.Join(routes, ",") + `}}`))
+		case "/v1/prices/cheap":
+			mu.Lock()
+			priced++
+			mu.Unlock()
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "TEST_TOKEN", "668475")
+	if _, err := c.SearchAirlineRoutes(context.Background(), AirlineParams{Airline: "DP"}); err == nil {
+		t.Error("expected error when every route failed")
+	}
+	// Без Limit проверяется defaultAirlineRoutes маршрутов, а не все
+	if priced != defaultAirlineRoutes {
+		t.Errorf("expected %d priced routes, got %d", defaultAirlineRoutes, priced)
+	}
+}
@@ -0,0 +1,65 @@
+package aviasales
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// airport элемент справочника аэропортов /data/ru/airports.json
+type airport struct {
+	Code       string `json:"code"`
+	CityCode   string `json:"city_code"`
+	Flightable bool   `json:"flightable"`
+	IATAType   string `json:"iata_type"`
+}
+
+// CityAirports возвращает коды аэропортов города с регулярными рейсами (MOW → DME, SVO, VKO, ...).
+// Код, не найденный среди городов справочника (аэропорт или неизвестный город), возвращается как есть.
+func (c *Client) CityAirports(ctx context.Context, code string) ([]string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	airports, err := c.cityAirports(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if codes := airports[code]; len(codes) > 0 {
+		return append([]string(nil), codes...), nil
+	}
+	return []string{code}, nil
+}
+
+// cityAirports загружает справочник аэропортов один раз и кэширует его; после ошибки загрузка повторяется.
+// Справочник скачивается без блокировки, поэтому поиски по нескольким маршрутам не выстраиваются
+// в очередь за одной медленной загрузкой; при одновременной загрузке в кэше остается первый результат.
+func (c *Client) cityAirports(ctx context.Context) (map[string][]string, error) {
+	c.airportsMu.Lock()
+	cached := c.airports
+	c.airportsMu.Unlock()
+	if cached != nil {
+		return cached, nil
+	}
+
+	var list []airport
+	if err := c.get(ctx, "/data/ru/airports.json", url.Values{}, nil, &list); err != nil {
+		return nil, err
+	}
+
+	airports := make(map[string][]string)
+	for _, a := range list {
+		if !a.Flightable || a.CityCode == "" || (a.IATAType != "" && a.IATAType != "airport") {
+			continue
+		}
+		airports[a.CityCode] = append(airports[a.CityCode], a.Code)
+	}
+	for _, codes := range airports {
+		sort.Strings(codes)
+	}
+
+	c.airportsMu.Lock()
+	defer c.airportsMu.Unlock()
+	if c.airports == nil {
+		c.airports = airports
+	}
+	return c.airports, nil
+}
@@ -0,0 +1,92 @@
+package aviasales
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_CityAirports(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/data/ru/airports.json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		calls++
+		_, _ = w.Write([]byte(`[
+			{"code": "SVO", "city_code": "MOW", "flightable": true, "iata_type": "airport"},
+			{"code": "DME", "city_code": "MOW", "flightable": true, "iata_type": "airport"},
+			{"code": "BKA", "city_code": "MOW", "flightable": false, "iata_type": "airport"},
+			{"code": "XRK", "city_code": "MOW", "flightable": true, "iata_type": "railway"},
+			{"code": "LED", "city_code": "LED", "flightable": true, "iata_type": "airport"}
+		]`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "TEST_TOKEN", "668475")
+	airports, err := c.CityAirports(context.Background(), "mow")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(airports, ",") != "DME,SVO" {
+		t.Errorf("expected flightable airports DME,SVO, got %v", airports)
+	}
+
+	// Аэропорт и неизвестный код возвращаются как есть, справочник загружается один раз
+	for _, code := range []string{"SVO", "XXX"} {
+		if airports, _ := c.CityAirports(context.Background(), code); len(airports) != 1 || airports[0] != code {
+			t.Errorf("%s: expected code itself, got %v", code, airports)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected airports directory to be cached, got %d calls", calls)
+	}
+}
+
+// Тест: билеты разных маршрутов подписываются своим маршрутом
+func TestClient_CityAirports_FetchOutsideLock(t *testing.T) {
+	srv, overlapped := newOverlapServer(`[{"code":"IST","city_code":"IST","flightable":true,"iata_type":"airport"}]`)
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "TEST_TOKEN", "668475")
+	runTwice(func() {
+		if codes, err := c.CityAirports(context.Background(), "IST"); err != nil || len(codes) != 1 || codes[0] != "IST" {
+			t.Errorf("expected [IST], got %v, err=%v", codes, err)
+		}
+	})
+
+	if !overlapped() {
+		t.Error("expected the second lookup not to wait for the first fetch")
+	}
+}
+
+func TestClient_FormatFlightMessage_MixedRoutes(t *testing.T) {
+	c := NewClient("https://api.travelpayouts.com", "TEST_TOKEN", "668475")
+	depart := time.Date(2024, 12, 15, 10, 30, 0, 0, time.UTC)
+	flights := []Flight{
+		{Origin: "DME", Destination: "IST", DepartDate: depart, Price: 9500, Airline: "PC"},
+		{Origin: "SVO", Destination: "IST", DepartDate: depart, Price: 12000, Airline: "SU"},
+		{Origin: "SVO", Destination: "AYT", DepartDate: depart, Price: 13000, Airline: "SU"},
+	}
+
+	// Одно направление из разных аэропортов — подробный формат с маршрутом каждого билета
+	message := c.FormatFlightMessage("Москва", "Стамбул", flights[:2], 1, "rub")
+	if !strings.Contains(message, "🛫 DME → IST • PC") || !strings.Contains(message, "🛫 SVO → IST • SU") {
+		t.Errorf("message should contain route of each flight, got %q", message)
+	}
+	if message := c.FormatFlightMessage("Москва", "Стамбул", flights[:1], 1, "rub"); !strings.Contains(message, "🛫 PC •") {
+		t.Errorf("unexpected single route message: %q", message)
+	}
+
+	// Несколько направлений — список с фактическим аэропортом вылета
+	message = c.FormatFlightMessage("Москва", "Стамбул или Анталья", flights, 1, "rub")
+	for _, want := range []string{"Москва → Стамбул или Анталья", "✈️ DME → IST от", "✈️ SVO → AYT от"} {
+		if !strings.Contains(message, want) {
+			t.Errorf("expected message to contain %q, got:\n%s", want, message)
+		}
+	}
+}
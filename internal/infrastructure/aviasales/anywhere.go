@@ -0,0 +1,165 @@
+package aviasales
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// AnyDestination значение destination в Data API для поиска по всем направлениям
+const AnyDestination = "-"
+
+// IsAnywhere сообщает, что направление не задано: пустая строка, "-" или "anywhere"
+func IsAnywhere(destination string) bool {
+	switch strings.ToLower(strings.TrimSpace(destination)) {
+	case "", AnyDestination, "anywhere":
+		return true
+	default:
+		return false
+	}
+}
+
+// city элемент справочника городов /data/ru/cities.json
+type city struct {
+	Code             string            `json:"code"`
+	Name             string            `json:"name"`
+	NameTranslations map[string]string `json:"name_translations"`
+}
+
+// CityName возвращает название города по IATA коду; если справочник недоступен — сам код
+func (c *Client) CityName(ctx context.Context, code string) string {
+	names, err := c.cityNames(ctx)
+	if err != nil || names[code] == "" {
+		return code
+	}
+	return names[code]
+}
+
+// cityNames загружает справочник городов один раз и кэширует его; после ошибки загрузка повторяется.
+// Справочник скачивается без блокировки, чтобы медленный запрос не задерживал поиски с уже
+// загруженным справочником; при одновременной загрузке в кэше остается первый результат.
+func (c *Client) cityNames(ctx context.Context) (map[string]string, error) {
+	c.citiesMu.Lock()
+	cached := c.cities
+	c.citiesMu.Unlock()
+	if cached != nil {
+		return cached, nil
+	}
+
+	var cities []city
+	if err := c.get(ctx, "/data/ru/cities.json", url.Values{}, nil, &cities); err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string, len(cities))
+	for _, ct := range cities {
+		if name := coalesce(ct.Name, ct.NameTranslations["en"]); name != "" {
+			names[ct.Code] = name
+		}
+	}
+
+	c.citiesMu.Lock()
+	defer c.citiesMu.Unlock()
+	if c.cities == nil {
+		c.cities = names
+	}
+	return c.cities, nil
+}
+
+// cheapestPerDestination оставляет самый дешевый билет в каждое направление, по возрастанию цены
+func (c *Client) cheapestPerDestination(flights []Flight) []Flight {
+	best := make(map[string]Flight, len(flights))
+	for _, flight := range flights {
+		if current, ok := best[flight.Destination]; !ok || cheaperFlight(flight, current) {
+			best[flight.Destination] = flight
+		}
+	}
+
+	result := make([]Flight, 0, len(best))
+	for _, flight := range best {
+		result = append(result, flight)
+	}
+	sort.Slice(result, func(i, j int) bool { return cheaperFlight(result[i], result[j]) })
+	return result
+}
+
+// cheaperFlight сравнивает билеты по цене, при равной цене — по вылету и направлению;
+// полный порядок выдачи задает application.SortFlights
+func cheaperFlight(a, b Flight) bool {
+	if a.Price != b.Price {
+		return a.Price < b.Price
+	}
+	if !a.DepartDate.Equal(b.DepartDate) {
+		return a.DepartDate.Before(b.DepartDate)
+	}
+	return a.Destination < b.Destination
+}
+
+// nameCities заполняет названия городов рейсов; без справочника остаются IATA коды
+func (c *Client) nameCities(ctx context.Context, flights []Flight) {
+	names, err := c.cityNames(ctx)
+	if err != nil {
+		return
+	}
+	for i := range flights {
+		flights[i].OriginName = names[flights[i].Origin]
+		flights[i].DestinationName = names[flights[i].Destination]
+	}
+}
+
+// formatAnywhereMessage форматирует список самых дешевых направлений из одного или нескольких городов
+// с ценами в валюте currency; если билеты вылетают из разных аэропортов или городов, в строке указан фактический пункт вылета
+func (c *Client) formatAnywhereMessage(originCity, destCity string, flights []Flight, passengers int, currency string) string {
+	if len(flights) == 0 {
+		return fmt.Sprintf("😔 К сожалению, билеты из %s не найдены", originCity)
+	}
+
+	header := destCity
+	if IsAnywhere(destCity) {
+		header = "куда угодно"
+	}
+	mixedOrigins := false
+	for _, flight := range flights {
+		mixedOrigins = mixedOrigins || flight.Origin != flights[0].Origin
+	}
+
+	var msg strings.Builder
+	msg.WriteString(fmt.Sprintf("🌍 <b>%s → %s</b>\n\n", originCity, header))
+
+	for i, flight := range flights {
+		if i >= 10 { // Показываем максимум 10 направлений
+			break
+		}
+
+		from := originCity
+		if mixedOrigins {
+			from = coalesce(flight.OriginName, flight.Origin)
+		}
+		to := coalesce(flight.DestinationName, flight.Destination)
+		msg.WriteString(fmt.Sprintf("✈️ %s → %s от <b>%s</b>", from, to, c.formatPriceIn(flight.Price, currency)))
+		if !flight.DepartDate.IsZero() {
+			msg.WriteString(fmt.Sprintf(", %s", c.formatDate(flight.DepartDate)))
+		}
+		link := c.GeneratePartnerLink(flight, passengers)
+		msg.WriteString(fmt.Sprintf(" • <a href=\"%s\">купить</a>\n", link))
+	}
+
+	msg.WriteString("\n💡 <i>Цены указаны за одного пассажира</i>")
+
+	return msg.String()
+}
+
+// isAnywhereList сообщает, что рейсы нужно показать списком направлений
+func isAnywhereList(destCity string, flights []Flight) bool {
+	if IsAnywhere(destCity) {
+		return true
+	}
+	for _, flight := range flights {
+		if flight.Destination != flights[0].Destination {
+			return true
+		}
+	}
+	return false
+}
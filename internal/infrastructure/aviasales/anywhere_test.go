@@ -0,0 +1,146 @@
+package aviasales
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_SearchCheap_Anywhere(t *testing.T) {
+	var gotDestination string
+	citiesCalls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/prices/cheap":
+			gotDestination = r.URL.Query().Get("destination")
+			_, _ = w.Write([]byte(`{
+				"success": true,
+				"currency": "rub",
+				"data": {
+					"IST": {
+						"0": {"price": 8500, "airline": "PC", "departure_at": "2024-12-15T10:30:00Z"},
+						"1": {"price": 12000, "airline": "TK", "departure_at": "2024-12-16T10:30:00Z"}
+					},
+					"AER": {"0": {"price": 4200, "airline": "DP", "departure_at": "2024-12-20T08:00:00Z"}},
+					"BCN": {"0": {"price": 21000, "airline": "UX", "departure_at": "2024-12-21T08:00:00Z"}}
+				}
+			}`))
+		case "/data/ru/cities.json":
+			citiesCalls++
+			_, _ = w.Write([]byte(`[
+				{"code": "MOW", "name": "Москва", "name_translations": {"en": "Moscow"}},
+				{"code": "IST", "name": "Стамбул", "name_translations": {"en": "Istanbul"}},
+				{"code": "AER", "name": null, "name_translations": {"en": "Sochi"}}
+			]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "TEST_TOKEN", "668475")
+	for _, destination := range []string{"", "-", "anywhere"} {
+		flights, err := c.SearchCheap(context.Background(), SearchParams{Origin: "MOW", Destination: destination, DepartDate: "2024-12", Limit: 2})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if gotDestination != AnyDestination {
+			t.Errorf("expected destination %q, got %q", AnyDestination, gotDestination)
+		}
+
+		// Топ-2 направления: по одному самому дешевому билету, по возрастанию цены
+		if len(flights) != 2 {
+			t.Fatalf("expected 2 destinations, got %d", len(flights))
+		}
+		if flights[0].Destination != "AER" || flights[0].DestinationName != "Sochi" || flights[0].Origin != "MOW" || flights[0].OriginName != "Москва" {
+			t.Errorf("unexpected first flight: %+v", flights[0])
+		}
+		if flights[1].Destination != "IST" || flights[1].Price != 8500 || flights[1].DestinationName != "Стамбул" {
+			t.Errorf("unexpected second flight: %+v", flights[1])
+		}
+	}
+
+	if citiesCalls != 1 {
+		t.Errorf("expected cities directory to be loaded once, got %d", citiesCalls)
+	}
+	if name := c.CityName(context.Background(), "IST"); name != "Стамбул" {
+		t.Errorf("expected Стамбул, got %s", name)
+	}
+	if name := c.CityName(context.Background(), "XXX"); name != "XXX" {
+		t.Errorf("expected unknown code to be returned as is, got %s", name)
+	}
+}
+
+func TestClient_CityName_DirectoryUnavailable(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "TEST_TOKEN", "668475")
+	if name := c.CityName(context.Background(), "IST"); name != "IST" {
+		t.Errorf("expected fallback to code, got %s", name)
+	}
+	c.CityName(context.Background(), "IST")
+	if calls != 2 {
+		t.Errorf("expected failed load to be retried, got %d calls", calls)
+	}
+}
+
+func TestClient_CityName_FetchOutsideLock(t *testing.T) {
+	srv, overlapped := newOverlapServer(`[{"code":"IST","name":"Стамбул"}]`)
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "TEST_TOKEN", "668475")
+	runTwice(func() {
+		if name := c.CityName(context.Background(), "IST"); name != "Стамбул" {
+			t.Errorf("expected city name, got %s", name)
+		}
+	})
+
+	if !overlapped() {
+		t.Error("expected the second lookup not to wait for the first fetch")
+	}
+}
+
+func TestFormatFlightMessage_Anywhere(t *testing.T) {
+	c := NewClient("https://api.travelpayouts.com", "TEST_TOKEN", "668475")
+	flights := []Flight{
+		{Origin: "MOW", Destination: "IST", DestinationName: "Стамбул", Price: 8500, DepartDate: time.Date(2024, 12, 15, 10, 30, 0, 0, time.UTC)},
+		{Origin: "MOW", Destination: "AER", Price: 12400},
+	}
+
+	msg := c.FormatFlightMessage("Москва", "-", flights, 1, "rub")
+	for _, want := range []string{
+		"Москва → куда угодно",
+		"Москва → Стамбул от <b>8 500 ₽</b>, 15 дек",
+		"Москва → AER от <b>12 400 ₽</b> •",
+		"https://www.aviasales.com/search/MOW1512IST",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected message to contain %q, got:\n%s", want, msg)
+		}
+	}
+
+	// Разные направления без явного «куда угодно» тоже выводятся списком
+	if msg := c.FormatFlightMessage("Москва", "", flights[:1], 1, "rub"); !strings.Contains(msg, "куда угодно") {
+		t.Errorf("expected anywhere list for empty destination, got:\n%s", msg)
+	}
+	if msg := c.FormatFlightMessage("Москва", "Сочи", flights, 1, "rub"); !strings.Contains(msg, "Москва → Стамбул") {
+		t.Errorf("expected list for several destinations, got:\n%s", msg)
+	}
+	if msg := c.FormatFlightMessage("Москва", "anywhere", nil, 1, "rub"); !strings.Contains(msg, "билеты из Москва не найдены") {
+		t.Errorf("unexpected empty message: %s", msg)
+	}
+
+	// цены показываются в валюте запроса
+	msg = c.FormatFlightMessage("Москва", "-", flights, 1, "usd")
+	if !strings.Contains(msg, "Москва → Стамбул от <b>8 500 $</b>") || strings.Contains(msg, "₽") {
+		t.Errorf("expected prices in dollars, got:\n%s", msg)
+	}
+}
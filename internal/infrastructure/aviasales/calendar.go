@@ -0,0 +1,249 @@
+package aviasales
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CalendarType определяет, по какой дате строится календарь цен
+type CalendarType string
+
+const (
+	CalendarByDeparture CalendarType = "departure_date"
+	CalendarByReturn    CalendarType = "return_date"
+)
+
+// CalendarParams параметры календаря цен
+type CalendarParams struct {
+	Origin       string       // IATA код города отправления
+	Destination  string       // IATA код города назначения
+	Month        string       // Месяц вылета (YYYY-MM)
+	ReturnMonth  string       // Месяц возвращения (YYYY-MM), необязательно
+	CalendarType CalendarType // По дате вылета (по умолчанию) или возвращения
+	Length       int          // Длительность поездки в днях, необязательно
+	Currency     string       // Валюта (rub, usd, eur)
+}
+
+// PriceCalendar самые дешевые билеты на каждый день месяца
+type PriceCalendar struct {
+	Origin       string            `json:"origin"`
+	Destination  string            `json:"destination"`
+	Month        string            `json:"month"` // YYYY-MM
+	CalendarType CalendarType      `json:"calendar_type"`
+	Currency     string            `json:"currency"`
+	Days         map[string]Flight `json:"days"` // ключ — дата YYYY-MM-DD
+}
+
+// Dates возвращает даты календаря по возрастанию
+func (cal *PriceCalendar) Dates() []string {
+	dates := make([]string, 0, len(cal.Days))
+	for date := range cal.Days {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+	return dates
+}
+
+// Cheapest возвращает дату и билет с минимальной ценой; при равных ценах — более раннюю дату
+func (cal *PriceCalendar) Cheapest() (string, Flight, bool) {
+	var bestDate string
+	var best Flight
+	for _, date := range cal.Dates() {
+		if flight := cal.Days[date]; bestDate == "" || flight.Price < best.Price {
+			bestDate, best = date, flight
+		}
+	}
+	return bestDate, best, bestDate != ""
+}
+
+// calendarResponse ответ /v1/prices/calendar
+type calendarResponse struct {
+	Success  bool                              `json:"success"`
+	Data     map[string]map[string]interface{} `json:"data"`
+	Currency string                            `json:"currency"`
+	Error    string                            `json:"error,omitempty"`
+}
+
+// SearchCalendar возвращает самые дешевые билеты на каждый день месяца используя /v1/prices/calendar
+func (c *Client) SearchCalendar(ctx context.Context, p CalendarParams) (*PriceCalendar, error) {
+	calendarType := p.CalendarType
+	if calendarType == "" {
+		calendarType = CalendarByDeparture
+	}
+
+	q := url.Values{}
+	q.Set("origin", p.Origin)
+	q.Set("destination", p.Destination)
+	q.Set("depart_date", p.Month)
+	q.Set("calendar_type", string(calendarType))
+	if p.ReturnMonth != "" {
+		q.Set("return_date", p.ReturnMonth)
+	}
+	if p.Length > 0 {
+		q.Set("length", strconv.Itoa(p.Length))
+	}
+	if p.Currency != "" {
+		q.Set("currency", p.Currency)
+	}
+
+	var apiResp calendarResponse
+	err := c.get(ctx, "/v1/prices/calendar", q, map[string]interface{}{
+		"origin":        p.Origin,
+		"destination":   p.Destination,
+		"calendar_type": string(calendarType),
+	}, &apiResp)
+	if err != nil {
+		return nil, err
+	}
+
+	if !apiResp.Success {
+		return nil, fmt.Errorf("API error: %s", apiResp.Error)
+	}
+
+	cal := &PriceCalendar{
+		Origin:       p.Origin,
+		Destination:  p.Destination,
+		Month:        p.Month,
+		CalendarType: calendarType,
+		Currency:     apiResp.Currency,
+		Days:         make(map[string]Flight, len(apiResp.Data)),
+	}
+	if cal.Currency == "" {
+		cal.Currency = p.Currency
+	}
+	for date, data := range apiResp.Data {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			continue
+		}
+		destination, _ := data["destination"].(string)
+		if destination == "" {
+			destination = p.Destination
+		}
+		cal.Days[date] = *c.parseFlightData(destination, data)
+	}
+
+	// Месяц для сетки берем из ответа, если в запросе была точная дата или календарь по возвращению
+	if _, err := time.Parse("2006-01", cal.Month); err != nil || calendarType == CalendarByReturn {
+		if dates := cal.Dates(); len(dates) > 0 {
+			cal.Month = dates[0][:7]
+		}
+	}
+
+	return cal, nil
+}
+
+// FormatCalendarMessage форматирует календарь цен в компактную сетку месяца для Telegram.
+// Цены в сетке указаны в тысячах валюты календаря, если самая дорогая из них не меньше 10 000,
+// иначе в целых единицах; ширина колонок подстраивается под самое длинное значение.
+// Самый дешевый день отмечен звездочкой.
+func (c *Client) FormatCalendarMessage(originCity, destCity string, cal *PriceCalendar) string {
+	cheapestDate, cheapest, ok := cal.Cheapest()
+	month, err := time.Parse("2006-01", cal.Month)
+	if !ok || err != nil {
+		return fmt.Sprintf("😔 К сожалению, цены %s → %s на этот месяц не найдены", originCity, destCity)
+	}
+
+	scale := calendarScale(cal.Days)
+	cells := make(map[string]string, len(cal.Days))
+	width := 3
+	for date, flight := range cal.Days {
+		cell := formatScaled(flight.Price, scale)
+		if date == cheapestDate {
+			cell = "*" + cell
+		}
+		cells[date] = cell
+		width = max(width, len(cell))
+	}
+
+	var msg strings.Builder
+	msg.WriteString(fmt.Sprintf("📅 <b>%s → %s</b>, %s %d\n\n", originCity, destCity, monthNames[month.Month()-1], month.Year()))
+	msg.WriteString("<pre>\n")
+	var header strings.Builder
+	for _, weekday := range []string{"Пн", "Вт", "Ср", "Чт", "Пт", "Сб", "Вс"} {
+		header.WriteString(fmt.Sprintf("%*s ", width, weekday))
+	}
+	msg.WriteString(strings.TrimRight(header.String(), " ") + "\n")
+
+	// Неделя занимает две строки: числа месяца и цены под ними
+	offset := (int(month.Weekday()) + 6) % 7 // понедельник — первый день недели
+	days := month.AddDate(0, 1, -1).Day()
+	var dayRow, priceRow strings.Builder
+	for cell := 0; cell < offset+days; cell++ {
+		if cell > 0 && cell%7 == 0 {
+			msg.WriteString(strings.TrimRight(dayRow.String(), " ") + "\n")
+			msg.WriteString(strings.TrimRight(priceRow.String(), " ") + "\n")
+			dayRow.Reset()
+			priceRow.Reset()
+		}
+		if cell < offset {
+			dayRow.WriteString(strings.Repeat(" ", width+1))
+			priceRow.WriteString(strings.Repeat(" ", width+1))
+			continue
+		}
+
+		day := cell - offset + 1
+		date := fmt.Sprintf("%s-%02d", cal.Month, day)
+		dayRow.WriteString(fmt.Sprintf("%*d ", width, day))
+
+		price, found := cells[date]
+		if !found {
+			price = "·"
+		}
+		priceRow.WriteString(fmt.Sprintf("%*s ", width, price))
+	}
+	msg.WriteString(strings.TrimRight(dayRow.String(), " ") + "\n")
+	msg.WriteString(strings.TrimRight(priceRow.String(), " ") + "\n")
+	msg.WriteString("</pre>\n")
+
+	cheapestDay, _ := time.Parse("2006-01-02", cheapestDate)
+	msg.WriteString(fmt.Sprintf("🔥 Дешевле всего %s: <b>%s</b>", c.formatDate(cheapestDay), c.formatPriceIn(cheapest.Price, cal.Currency)))
+	if cheapest.Airline != "" {
+		msg.WriteString(fmt.Sprintf(" (%s)", cheapest.Airline))
+	}
+	if scale == 1000 {
+		msg.WriteString(fmt.Sprintf("\n💡 <i>Цены в сетке — в тысячах %s</i>", currencyPlural(cal.Currency)))
+	} else {
+		msg.WriteString(fmt.Sprintf("\n💡 <i>Цены в сетке — в %s</i>", currencyLocative(cal.Currency)))
+	}
+
+	return msg.String()
+}
+
+var monthNames = []string{
+	"январь", "февраль", "март", "апрель", "май", "июнь",
+	"июль", "август", "сентябрь", "октябрь", "ноябрь", "декабрь",
+}
+
+// calendarScale выбирает единицу сетки: тысячи, если самая дорогая цена не меньше 10 000
+// (рубли), иначе целые единицы (доллары, евро), чтобы цены не округлялись до нуля
+func calendarScale(days map[string]Flight) int {
+	for _, flight := range days {
+		if flight.Price >= 10000 {
+			return 1000
+		}
+	}
+	return 1
+}
+
+// formatScaled округляет цену до единиц сетки календаря
+func formatScaled(price, scale int) string {
+	return strconv.Itoa((price + scale/2) / scale)
+}
+
+// currencyLocative название валюты после предлога «в»: «в рублях», «в долларах»
+func currencyLocative(currency string) string {
+	switch strings.ToLower(currency) {
+	case "", "rub":
+		return "рублях"
+	case "usd":
+		return "долларах"
+	case "eur":
+		return "евро"
+	}
+	return strings.ToUpper(currency)
+}
@@ -0,0 +1,178 @@
+package aviasales
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestClient_SearchCalendar(t *testing.T) {
+	var gotPath string
+	var gotQuery url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotQuery = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"success": true,
+			"currency": "rub",
+			"data": {
+				"2024-12-01": {"origin": "MOW", "destination": "BCN", "price": 21000, "transfers": 1, "airline": "SU", "flight_number": 2514, "departure_at": "2024-12-01T06:05:00Z", "return_at": "2024-12-08T18:40:00Z", "expires_at": "2024-11-20T10:00:00Z"},
+				"2024-12-05": {"origin": "MOW", "destination": "BCN", "price": 12400, "transfers": 0, "airline": "UX", "flight_number": 1094, "departure_at": "2024-12-05T10:30:00Z"},
+				"2024-12-31": {"origin": "MOW", "destination": "BCN", "price": 35900, "transfers": 2, "airline": "TK", "departure_at": "2024-12-31T22:10:00Z"}
+			}
+		}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "TEST_TOKEN", "668475")
+	cal, err := c.SearchCalendar(context.Background(), CalendarParams{
+		Origin:      "MOW",
+		Destination: "BCN",
+		Month:       "2024-12",
+		Length:      7,
+		Currency:    "rub",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if gotPath != "/v1/prices/calendar" {
+		t.Errorf("expected path /v1/prices/calendar, got %s", gotPath)
+	}
+	if gotQuery.Get("calendar_type") != "departure_date" || gotQuery.Get("depart_date") != "2024-12" || gotQuery.Get("length") != "7" {
+		t.Errorf("unexpected query: %v", gotQuery)
+	}
+
+	if len(cal.Days) != 3 || cal.Month != "2024-12" || cal.Currency != "rub" {
+		t.Fatalf("unexpected calendar: %+v", cal)
+	}
+	day := cal.Days["2024-12-01"]
+	if day.Price != 21000 || day.Transfers != 1 || day.Airline != "SU" || day.DepartDate.Day() != 1 || day.ReturnDate.Day() != 8 {
+		t.Errorf("unexpected day: %+v", day)
+	}
+
+	date, cheapest, ok := cal.Cheapest()
+	if !ok || date != "2024-12-05" || cheapest.Price != 12400 {
+		t.Errorf("expected cheapest 2024-12-05 for 12400, got %s %+v", date, cheapest)
+	}
+}
+
+func TestClient_SearchCalendar_ReturnTypeAndAPIError(t *testing.T) {
+	var gotType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotType = r.URL.Query().Get("calendar_type")
+		if r.URL.Query().Get("origin") == "XXX" {
+			_, _ = w.Write([]byte(`{"success": false, "error": "unknown origin"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"success": true, "data": {"2025-01-03": {"price": 9000}}}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "TEST_TOKEN", "")
+	cal, err := c.SearchCalendar(context.Background(), CalendarParams{
+		Origin:       "MOW",
+		Destination:  "AER",
+		Month:        "2024-12-28",
+		CalendarType: CalendarByReturn,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotType != "return_date" {
+		t.Errorf("expected calendar_type return_date, got %s", gotType)
+	}
+	if cal.Month != "2025-01" || cal.Days["2025-01-03"].Destination != "AER" {
+		t.Errorf("expected month and destination from response, got %+v", cal)
+	}
+
+	if _, err := c.SearchCalendar(context.Background(), CalendarParams{Origin: "XXX", Destination: "AER", Month: "2024-12"}); err == nil {
+		t.Error("expected API error")
+	}
+}
+
+func TestClient_FormatCalendarMessage(t *testing.T) {
+	c := NewClient("https://api.travelpayouts.com", "TEST_TOKEN", "668475")
+	cal := &PriceCalendar{
+		Origin:      "MOW",
+		Destination: "BCN",
+		Month:       "2024-12",
+		Days: map[string]Flight{
+			"2024-12-01": {Price: 21000},
+			"2024-12-05": {Price: 12400, Airline: "UX"},
+			"2024-12-31": {Price: 35900},
+		},
+	}
+
+	message := c.FormatCalendarMessage("Москва", "Барселона", cal)
+
+	for _, want := range []string{
+		"Москва → Барселона</b>, декабрь 2024",
+		" Пн  Вт  Ср  Чт  Пт  Сб  Вс",
+		"Дешевле всего 5 дек: <b>12 400 ₽</b> (UX)",
+		"в тысячах рублей",
+	} {
+		if !strings.Contains(message, want) {
+			t.Errorf("message should contain %q:\n%s", want, message)
+		}
+	}
+
+	lines := strings.Split(message, "\n")
+	// 1 декабря 2024 — воскресенье: первая неделя состоит из одного дня в последней колонке
+	if lines[4] != strings.Repeat(" ", 24)+"  1" || lines[5] != strings.Repeat(" ", 24)+" 21" {
+		t.Errorf("unexpected first week:\n%q\n%q", lines[4], lines[5])
+	}
+	if lines[7] != "  ·   ·   · *12   ·   ·   ·" {
+		t.Errorf("expected cheapest day to be marked, got %q", lines[7])
+	}
+
+	// единицы сетки и цена берутся из валюты календаря
+	cal.Currency = "eur"
+	message = c.FormatCalendarMessage("Москва", "Барселона", cal)
+	if !strings.Contains(message, "в тысячах евро") || !strings.Contains(message, "<b>12 400 €</b>") || strings.Contains(message, "рублей") {
+		t.Errorf("expected euro units:\n%s", message)
+	}
+
+	// недорогие цены в долларах показываются целиком, а не округляются до нуля тысяч
+	usd := &PriceCalendar{
+		Month:    "2024-12",
+		Currency: "usd",
+		Days: map[string]Flight{
+			"2024-12-02": {Price: 180},
+			"2024-12-03": {Price: 320},
+			"2024-12-10": {Price: 640},
+		},
+	}
+	lines = strings.Split(c.FormatCalendarMessage("Москва", "Барселона", usd), "\n")
+	if lines[6] != "   2    3    4    5    6    7    8" || lines[7] != "*180  320    ·    ·    ·    ·    ·" {
+		t.Errorf("expected whole dollars in widened columns, got:\n%q\n%q", lines[6], lines[7])
+	}
+	if lines[3] != "  Пн   Вт   Ср   Чт   Пт   Сб   Вс" {
+		t.Errorf("expected header to follow column width, got %q", lines[3])
+	}
+	if last := lines[len(lines)-1]; last != "💡 <i>Цены в сетке — в долларах</i>" {
+		t.Errorf("unexpected units note: %q", last)
+	}
+
+	// от 100 тысяч значение со звездочкой не помещается в три символа
+	expensive := &PriceCalendar{
+		Month: "2024-12",
+		Days: map[string]Flight{
+			"2024-12-02": {Price: 125400},
+			"2024-12-03": {Price: 250000},
+		},
+	}
+	lines = strings.Split(c.FormatCalendarMessage("Москва", "Барселона", expensive), "\n")
+	if lines[7] != "*125  250    ·    ·    ·    ·    ·" {
+		t.Errorf("expected aligned thousands, got %q", lines[7])
+	}
+
+	empty := c.FormatCalendarMessage("Москва", "Барселона", &PriceCalendar{Month: "2024-12"})
+	if !strings.Contains(empty, "не найдены") {
+		t.Errorf("expected not found message, got %s", empty)
+	}
+}
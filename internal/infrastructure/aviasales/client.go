@@ -4,10 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -18,6 +20,17 @@ type Client struct {
 	marker  string
 	hc      *http.Client
 	logger  Logger
+
+	citiesMu sync.Mutex
+	cities   map[string]string // IATA код → название города, загружается лениво
+
+	airportsMu sync.Mutex
+	airports   map[string][]string // IATA код города → коды его аэропортов, загружается лениво
+
+	offersMu  sync.Mutex
+	offers    []SpecialOffer // кэш спецпредложений
+	offersAt  time.Time      // когда кэш спецпредложений был загружен
+	offersTTL time.Duration
 }
 
 type Option func(*Client)
@@ -29,13 +42,20 @@ func WithHTTPClient(hc *http.Client) Option {
 // Logger defines minimal logging capability needed by this client
 type Logger interface {
 	ExternalAPI(apiName, endpoint string, statusCode int, duration time.Duration, metadata map[string]interface{}) error
+	Error(event string, data map[string]interface{})
 }
 
 // WithLogger injects a logger into the client
 func WithLogger(l Logger) Option { return func(c *Client) { c.logger = l } }
 
+// WithOffersTTL задает, сколько спецпредложения хранятся в кэше (по умолчанию час)
+func WithOffersTTL(d time.Duration) Option { return func(c *Client) { c.offersTTL = d } }
+
+// defaultHTTPTimeout ограничивает один запрос к API, если WithHTTPClient не задан
+const defaultHTTPTimeout = 30 * time.Second
+
 func NewClient(baseURL, token, marker string, opts ...Option) *Client {
-	c := &Client{baseURL: baseURL, token: token, marker: marker, hc: http.DefaultClient}
+	c := &Client{baseURL: baseURL, token: token, marker: marker, hc: &http.Client{Timeout: defaultHTTPTimeout}, offersTTL: time.Hour}
 	for _, o := range opts {
 		o(c)
 	}
@@ -45,11 +65,11 @@ func NewClient(baseURL, token, marker string, opts ...Option) *Client {
 // SearchParams параметры поиска авиабилетов
 type SearchParams struct {
 	Origin      string // IATA код города отправления
-	Destination string // IATA код города назначения
+	Destination string // IATA код города назначения; пусто, "-" или "anywhere" — все направления
 	DepartDate  string // Дата вылета (YYYY-MM-DD или YYYY-MM)
 	ReturnDate  string // Дата возвращения (YYYY-MM-DD или YYYY-MM)
 	Currency    string // Валюта (rub, usd, eur)
-	Limit       int    // Максимальное количество результатов
+	Limit       int    // Максимальное количество результатов в порядке ответа API (при поиске «куда угодно» — самые дешевые направления)
 }
 
 // Flight представляет информацию о рейсе
@@ -62,10 +82,19 @@ type Flight struct {
 	Airline      string    `json:"airline"`
 	FlightNumber int       `json:"flight_number"`
 	Duration     int       `json:"duration"`
+	Transfers    int       `json:"transfers"`
 	Distance     int       `json:"distance"`
 	Gate         string    `json:"gate"`
 	ExpiresAt    time.Time `json:"expires_at"`
 	Actual       bool      `json:"actual"`
+
+	ReturnTransfers int    `json:"return_transfers"`        // пересадки на обратном пути (v3)
+	DurationTo      int    `json:"duration_to,omitempty"`   // длительность перелета туда в минутах (v3)
+	DurationBack    int    `json:"duration_back,omitempty"` // длительность перелета обратно в минутах (v3)
+	Link            string `json:"link,omitempty"`          // ссылка на поиск с этим билетом (v3)
+
+	OriginName      string `json:"origin_name,omitempty"`      // название города отправления
+	DestinationName string `json:"destination_name,omitempty"` // название города назначения
 }
 
 // TravelpayoutsResponse структура ответа от Travelpayouts API
@@ -78,15 +107,26 @@ type TravelpayoutsResponse struct {
 
 // SearchCheap ищет самые дешевые билеты используя /v1/prices/cheap endpoint
 func (c *Client) SearchCheap(ctx context.Context, p SearchParams) ([]Flight, error) {
-	u, err := url.Parse(c.baseURL)
-	if err != nil {
-		return nil, err
+	return c.searchPrices(ctx, "/v1/prices/cheap", p)
+}
+
+// SearchDirect ищет самые дешевые билеты без пересадок используя /v1/prices/direct endpoint
+func (c *Client) SearchDirect(ctx context.Context, p SearchParams) ([]Flight, error) {
+	return c.searchPrices(ctx, "/v1/prices/direct", p)
+}
+
+// searchPrices выполняет поиск через v1 endpoint цен с ответом в формате TravelpayoutsResponse.
+// Без направления возвращает самый дешевый билет в каждый город по возрастанию цены.
+func (c *Client) searchPrices(ctx context.Context, endpoint string, p SearchParams) ([]Flight, error) {
+	anywhere := IsAnywhere(p.Destination)
+	destination := p.Destination
+	if anywhere {
+		destination = AnyDestination
 	}
-	u.Path = "/v1/prices/cheap"
 
-	q := u.Query()
+	q := url.Values{}
 	q.Set("origin", p.Origin)
-	q.Set("destination", p.Destination)
+	q.Set("destination", destination)
 	q.Set("depart_date", p.DepartDate)
 	if p.ReturnDate != "" {
 		q.Set("return_date", p.ReturnDate)
@@ -94,6 +134,57 @@ func (c *Client) SearchCheap(ctx context.Context, p SearchParams) ([]Flight, err
 	if p.Currency != "" {
 		q.Set("currency", p.Currency)
 	}
+
+	var apiResp TravelpayoutsResponse
+	err := c.get(ctx, endpoint, q, map[string]interface{}{
+		"origin":      p.Origin,
+		"destination": destination,
+	}, &apiResp)
+	if err != nil {
+		return nil, err
+	}
+
+	if !apiResp.Success {
+		return nil, fmt.Errorf("API error: %s", apiResp.Error)
+	}
+
+	flights := c.parseFlights(apiResp.Data)
+	// Ответ не всегда содержит origin: маршрут билета должен быть виден и при поиске из нескольких городов
+	for i := range flights {
+		if flights[i].Origin == "" {
+			flights[i].Origin = p.Origin
+		}
+	}
+	if anywhere {
+		flights = c.cheapestPerDestination(flights)
+	}
+
+	// Ограничиваем количество результатов если указан лимит
+	if p.Limit > 0 && len(flights) > p.Limit {
+		flights = flights[:p.Limit]
+	}
+	if anywhere {
+		c.nameCities(ctx, flights)
+	}
+
+	return flights, nil
+}
+
+// get выполняет GET запрос к Data API с токеном и marker, логирует вызов и декодирует JSON ответ в out
+func (c *Client) get(ctx context.Context, endpoint string, q url.Values, meta map[string]interface{}, out interface{}) error {
+	return c.fetch(ctx, endpoint, q, meta, func(body io.Reader) error {
+		return json.NewDecoder(body).Decode(out)
+	})
+}
+
+// fetch выполняет GET запрос к Data API с токеном и marker, логирует вызов и передает тело ответа в decode
+func (c *Client) fetch(ctx context.Context, endpoint string, q url.Values, meta map[string]interface{}, decode func(io.Reader) error) error {
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return err
+	}
+	u.Path = endpoint
+
 	q.Set("token", c.token)
 	if c.marker != "" {
 		q.Set("marker", c.marker)
@@ -102,50 +193,25 @@ func (c *Client) SearchCheap(ctx context.Context, p SearchParams) ([]Flight, err
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	start := time.Now()
 	resp, err := c.hc.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
 
 	if c.logger != nil {
-		_ = c.logger.ExternalAPI(
-			"travelpayouts",
-			"/v1/prices/cheap",
-			resp.StatusCode,
-			time.Since(start),
-			map[string]interface{}{
-				"origin":      p.Origin,
-				"destination": p.Destination,
-			},
-		)
+		_ = c.logger.ExternalAPI("travelpayouts", endpoint, resp.StatusCode, time.Since(start), meta)
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
-	}
-
-	var apiResp TravelpayoutsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
-		return nil, err
-	}
-
-	if !apiResp.Success {
-		return nil, fmt.Errorf("API error: %s", apiResp.Error)
-	}
-
-	flights := c.parseFlights(apiResp.Data)
-
-	// Ограничиваем количество результатов если указан лимит
-	if p.Limit > 0 && len(flights) > p.Limit {
-		flights = flights[:p.Limit]
+		return fmt.Errorf("unexpected status: %s", resp.Status)
 	}
 
-	return flights, nil
+	return decode(resp.Body)
 }
 
 // parseFlights парсит данные из ответа API в структуру Flight
@@ -153,10 +219,16 @@ func (c *Client) parseFlights(data map[string]map[string]interface{}) []Flight {
 	var flights []Flight
 
 	for destination, routes := range data {
-		for _, routeData := range routes {
+		// Ключ внутренней карты — число пересадок ("0", "1", "2")
+		for key, routeData := range routes {
 			if routeMap, ok := routeData.(map[string]interface{}); ok {
 				flight := c.parseFlightData(destination, routeMap)
 				if flight != nil {
+					if _, explicit := routeMap["transfers"]; !explicit {
+						if transfers, err := strconv.Atoi(key); err == nil && transfers >= 0 {
+							flight.Transfers = transfers
+						}
+					}
 					flights = append(flights, *flight)
 				}
 			}
@@ -187,6 +259,9 @@ func (c *Client) parseFlightData(destination string, data map[string]interface{}
 	if duration, ok := data["duration"].(float64); ok {
 		flight.Duration = int(duration)
 	}
+	if transfers, ok := data["transfers"].(float64); ok {
+		flight.Transfers = int(transfers)
+	}
 	if distance, ok := data["distance"].(float64); ok {
 		flight.Distance = int(distance)
 	}
@@ -199,17 +274,17 @@ func (c *Client) parseFlightData(destination string, data map[string]interface{}
 
 	// Парсим даты
 	if departStr, ok := data["departure_at"].(string); ok {
-		if departTime, err := time.Parse("2006-01-02T15:04:05.000Z", departStr); err == nil {
+		if departTime, err := parseAPITime(departStr); err == nil {
 			flight.DepartDate = departTime
 		}
 	}
 	if returnStr, ok := data["return_at"].(string); ok {
-		if returnTime, err := time.Parse("2006-01-02T15:04:05.000Z", returnStr); err == nil {
+		if returnTime, err := parseAPITime(returnStr); err == nil {
 			flight.ReturnDate = returnTime
 		}
 	}
 	if expiresStr, ok := data["expires_at"].(string); ok {
-		if expiresTime, err := time.Parse("2006-01-02T15:04:05.000Z", expiresStr); err == nil {
+		if expiresTime, err := parseAPITime(expiresStr); err == nil {
 			flight.ExpiresAt = expiresTime
 		}
 	}
@@ -217,14 +292,23 @@ func (c *Client) parseFlightData(destination string, data map[string]interface{}
 	return flight
 }
 
-// GeneratePartnerLink генерирует партнерскую ссылку для покупки билета
+// parseAPITime парсит время из ответа API: RFC3339 с миллисекундами (2024-12-15T10:30:00.000Z) или без них
+func parseAPITime(s string) (time.Time, error) {
+	return time.Parse(time.RFC3339, s)
+}
+
+// GeneratePartnerLink генерирует партнерскую ссылку для покупки билета;
+// для билета в одну сторону (без ReturnDate) ссылка ведет на поиск без обратного рейса
 func (c *Client) GeneratePartnerLink(flight Flight, passengers int) string {
 	// Формат ссылки Aviasales: https://www.aviasales.com/search/ORIGIN+DDMM+DESTINATION+DDMM
 	baseURL := "https://www.aviasales.com/search/"
 
 	// Форматируем даты в формат DDMM
 	departDate := flight.DepartDate.Format("0201") // MMDD
-	returnDate := flight.ReturnDate.Format("0201") // MMDD
+	returnDate := ""
+	if !flight.ReturnDate.IsZero() {
+		returnDate = flight.ReturnDate.Format("0201") // MMDD
+	}
 
 	// Строим поисковый запрос
 	searchQuery := fmt.Sprintf("%s%s%s%s",
@@ -239,12 +323,21 @@ func (c *Client) GeneratePartnerLink(flight Flight, passengers int) string {
 	return fmt.Sprintf("%s%s?%s", baseURL, searchQuery, params.Encode())
 }
 
-// FormatFlightMessage форматирует сообщение с информацией о рейсах для отправки пользователю
-func (c *Client) FormatFlightMessage(originCity, destCity string, flights []Flight, passengers int) string {
+// FormatFlightMessage форматирует сообщение с информацией о рейсах для отправки пользователю,
+// цены указываются в валюте запроса currency (пусто — рубли).
+// Для поиска «куда угодно» выводит список направлений вида «Москва → Стамбул от 8 500 ₽».
+func (c *Client) FormatFlightMessage(originCity, destCity string, flights []Flight, passengers int, currency string) string {
+	if isAnywhereList(destCity, flights) {
+		return c.formatAnywhereMessage(originCity, destCity, flights, passengers, currency)
+	}
+
 	if len(flights) == 0 {
 		return fmt.Sprintf("😔 К сожалению, билеты %s → %s не найдены", originCity, destCity)
 	}
 
+	// При поиске по нескольким аэропортам или городам у каждого билета показываем его маршрут
+	mixed := mixedRoutes(flights)
+
 	var msg strings.Builder
 	msg.WriteString(fmt.Sprintf("✈️ <b>%s → %s</b>\n\n", originCity, destCity))
 
@@ -253,16 +346,24 @@ func (c *Client) FormatFlightMessage(originCity, destCity string, flights []Flig
 			break
 		}
 
-		msg.WriteString(fmt.Sprintf("🎫 <b>%s</b>\n", c.formatPrice(flight.Price)))
-		msg.WriteString(fmt.Sprintf("📅 %s → %s\n",
-			c.formatDate(flight.DepartDate),
-			c.formatDate(flight.ReturnDate)))
-		msg.WriteString(fmt.Sprintf("🛫 %s", flight.Airline))
+		msg.WriteString(fmt.Sprintf("🎫 <b>%s</b>\n", c.formatPriceIn(flight.Price, currency)))
+		if flight.ReturnDate.IsZero() {
+			msg.WriteString(fmt.Sprintf("📅 %s\n", c.formatDate(flight.DepartDate)))
+		} else {
+			msg.WriteString(fmt.Sprintf("📅 %s → %s\n",
+				c.formatDate(flight.DepartDate),
+				c.formatDate(flight.ReturnDate)))
+		}
+		if mixed {
+			msg.WriteString(fmt.Sprintf("🛫 %s → %s • %s", flight.Origin, flight.Destination, flight.Airline))
+		} else {
+			msg.WriteString(fmt.Sprintf("🛫 %s", flight.Airline))
+		}
 
 		if flight.Duration > 0 {
 			msg.WriteString(fmt.Sprintf(" • %s", c.formatDuration(flight.Duration)))
 		}
-		msg.WriteString("\n")
+		msg.WriteString(fmt.Sprintf(" • %s\n", formatTransfers(flight.Transfers)))
 
 		// Добавляем ссылку на покупку
 		link := c.GeneratePartnerLink(flight, passengers)
@@ -274,8 +375,23 @@ func (c *Client) FormatFlightMessage(originCity, destCity string, flights []Flig
 	return msg.String()
 }
 
-// formatPrice форматирует цену с разделителями тысяч
+// mixedRoutes сообщает, что билеты летят по разным маршрутам
+func mixedRoutes(flights []Flight) bool {
+	for _, flight := range flights {
+		if flight.Origin != flights[0].Origin || flight.Destination != flights[0].Destination {
+			return true
+		}
+	}
+	return false
+}
+
+// formatPrice форматирует цену в рублях с разделителями тысяч
 func (c *Client) formatPrice(price int) string {
+	return c.formatPriceIn(price, "rub")
+}
+
+// formatPriceIn форматирует цену с разделителями тысяч и знаком валюты (rub, usd, eur; пусто — рубли)
+func (c *Client) formatPriceIn(price int, currency string) string {
 	priceStr := strconv.Itoa(price)
 	var result strings.Builder
 
@@ -286,7 +402,34 @@ func (c *Client) formatPrice(price int) string {
 		result.WriteRune(digit)
 	}
 
-	return result.String() + " ₽"
+	return result.String() + " " + currencySign(currency)
+}
+
+// currencySign возвращает знак валюты; для неизвестной валюты — ее код
+func currencySign(currency string) string {
+	switch strings.ToLower(currency) {
+	case "", "rub":
+		return "₽"
+	case "usd":
+		return "$"
+	case "eur":
+		return "€"
+	}
+	return strings.ToUpper(currency)
+}
+
+// currencyPlural возвращает название валюты в родительном падеже множественного числа
+// («в тысячах рублей»); для неизвестной валюты — ее код
+func currencyPlural(currency string) string {
+	switch strings.ToLower(currency) {
+	case "", "rub":
+		return "рублей"
+	case "usd":
+		return "долларов"
+	case "eur":
+		return "евро"
+	}
+	return strings.ToUpper(currency)
 }
 
 // formatDate форматирует дату для отображения
@@ -305,3 +448,18 @@ func (c *Client) formatDuration(minutes int) string {
 	mins := minutes % 60
 	return fmt.Sprintf("%dч %02dм", hours, mins)
 }
+
+// formatTransfers описывает число пересадок: "прямой", "1 пересадка", "2 пересадки", "5 пересадок"
+func formatTransfers(n int) string {
+	if n <= 0 {
+		return "прямой"
+	}
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return fmt.Sprintf("%d пересадка", n)
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		return fmt.Sprintf("%d пересадки", n)
+	default:
+		return fmt.Sprintf("%d пересадок", n)
+	}
+}
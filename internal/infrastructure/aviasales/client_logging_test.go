@@ -3,15 +3,19 @@ package aviasales
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
 
 // testLogger is a lightweight mock used only in tests to verify logging calls
 type testLogger struct {
+	mu           sync.Mutex
+	errors       []string // события Error
 	lastExternal struct {
 		apiName    string
 		endpoint   string
@@ -23,6 +27,8 @@ type testLogger struct {
 }
 
 func (l *testLogger) ExternalAPI(apiName, endpoint string, statusCode int, duration time.Duration, metadata map[string]interface{}) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	l.lastExternal.apiName = apiName
 	l.lastExternal.endpoint = endpoint
 	l.lastExternal.statusCode = statusCode
@@ -32,6 +38,12 @@ func (l *testLogger) ExternalAPI(apiName, endpoint string, statusCode int, durat
 	return nil
 }
 
+func (l *testLogger) Error(event string, data map[string]interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errors = append(l.errors, fmt.Sprintf("%s %v", event, data["route"]))
+}
+
 // RoundTripper mock
 type rtFunc func(*http.Request) (*http.Response, error)
 
@@ -6,6 +6,8 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
@@ -118,9 +120,15 @@ func TestClient_SearchCheap_MonthSearch(t *testing.T) {
 	for _, flight := range flights {
 		if flight.Price == 15000 {
 			found15000 = true
+			if flight.Transfers != 0 {
+				t.Errorf("expected transfers 0 from key, got %d", flight.Transfers)
+			}
 		}
 		if flight.Price == 17500 {
 			found17500 = true
+			if flight.Transfers != 1 {
+				t.Errorf("expected transfers 1 from key, got %d", flight.Transfers)
+			}
 		}
 		if flight.Origin != "MOW" {
 			t.Errorf("expected origin MOW, got %s", flight.Origin)
@@ -233,6 +241,70 @@ func TestClient_GeneratePartnerLink(t *testing.T) {
 	if !strings.Contains(link, "passengers=2") {
 		t.Errorf("expected link to contain passengers=2, got %s", link)
 	}
+
+	// у билета в одну сторону нет обратного сегмента, а не возврат 1 января
+	flight.ReturnDate = time.Time{}
+	link = c.GeneratePartnerLink(flight, 1)
+	if !strings.HasPrefix(link, "https://www.aviasales.com/search/MOW1512PAR?") {
+		t.Errorf("expected one-way link, got %s", link)
+	}
+}
+
+// Тест поиска прямых рейсов через /v1/prices/direct на httptest сервере
+func TestClient_SearchDirect(t *testing.T) {
+	var gotPath string
+	var gotQuery url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotQuery = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"success": true,
+			"currency": "rub",
+			"data": {
+				"BCN": {
+					"0": {
+						"price": 21000,
+						"airline": "UX",
+						"flight_number": 1094,
+						"departure_at": "2024-12-15T10:30:00.000Z",
+						"return_at": "2024-12-22T15:45:00.000Z",
+						"expires_at": "2024-11-15T12:00:00.000Z"
+					}
+				}
+			}
+		}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "TEST_TOKEN", "668475")
+	flights, err := c.SearchDirect(context.Background(), SearchParams{
+		Origin:      "MOW",
+		Destination: "BCN",
+		DepartDate:  "2024-12",
+		ReturnDate:  "2024-12",
+		Currency:    "rub",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if gotPath != "/v1/prices/direct" {
+		t.Errorf("expected path /v1/prices/direct, got %s", gotPath)
+	}
+	if gotQuery.Get("origin") != "MOW" || gotQuery.Get("destination") != "BCN" || gotQuery.Get("return_date") != "2024-12" {
+		t.Errorf("unexpected query: %v", gotQuery)
+	}
+	if gotQuery.Get("token") != "TEST_TOKEN" || gotQuery.Get("marker") != "668475" {
+		t.Errorf("expected token and marker in query, got %v", gotQuery)
+	}
+
+	if len(flights) != 1 {
+		t.Fatalf("expected 1 flight, got %d", len(flights))
+	}
+	if flights[0].Destination != "BCN" || flights[0].Price != 21000 || flights[0].Airline != "UX" || flights[0].Transfers != 0 {
+		t.Errorf("unexpected flight: %+v", flights[0])
+	}
 }
 
 // Тест обработки ошибок API
@@ -303,10 +375,11 @@ func TestClient_FormatFlightMessage(t *testing.T) {
 			Price:       17500,
 			Airline:     "AF",
 			Duration:    220,
+			Transfers:   1,
 		},
 	}
 
-	message := c.FormatFlightMessage("Москва", "Париж", flights, 2)
+	message := c.FormatFlightMessage("Москва", "Париж", flights, 2, "rub")
 
 	// Проверяем что сообщение содержит основную информацию
 	if !strings.Contains(message, "Москва → Париж") {
@@ -333,4 +406,32 @@ func TestClient_FormatFlightMessage(t *testing.T) {
 	if !strings.Contains(message, "Купить билет") {
 		t.Error("message should contain purchase links")
 	}
+	if !strings.Contains(message, "SU • 3ч 35м • прямой") || !strings.Contains(message, "AF • 3ч 40м • 1 пересадка") {
+		t.Errorf("message should contain transfers, got %q", message)
+	}
+
+	// для билета в одну сторону показывается только дата вылета
+	flights[0].ReturnDate = time.Time{}
+	message = c.FormatFlightMessage("Москва", "Париж", flights[:1], 2, "rub")
+	if !strings.Contains(message, "📅 15 дек\n") || strings.Contains(message, "1 янв") {
+		t.Errorf("expected one-way dates, got %q", message)
+	}
+}
+
+// Тест склонения числа пересадок
+func TestFormatTransfers(t *testing.T) {
+	cases := map[int]string{
+		0:  "прямой",
+		1:  "1 пересадка",
+		2:  "2 пересадки",
+		4:  "4 пересадки",
+		5:  "5 пересадок",
+		11: "11 пересадок",
+		21: "21 пересадка",
+	}
+	for n, want := range cases {
+		if got := formatTransfers(n); got != want {
+			t.Errorf("formatTransfers(%d) = %q, want %q", n, got, want)
+		}
+	}
 }
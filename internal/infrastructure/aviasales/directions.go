@@ -0,0 +1,42 @@
+package aviasales
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// PopularDirections возвращает популярные направления из города с актуальными ценами используя /v1/city-directions.
+// Направления отсортированы по возрастанию цены, названия городов заполняются из справочника.
+func (c *Client) PopularDirections(ctx context.Context, origin, currency string) ([]Flight, error) {
+	q := url.Values{}
+	q.Set("origin", origin)
+	if currency != "" {
+		q.Set("currency", currency)
+	}
+
+	var apiResp TravelpayoutsResponse
+	err := c.get(ctx, "/v1/city-directions", q, map[string]interface{}{
+		"origin": origin,
+	}, &apiResp)
+	if err != nil {
+		return nil, err
+	}
+
+	if !apiResp.Success {
+		return nil, fmt.Errorf("API error: %s", apiResp.Error)
+	}
+
+	flights := make([]Flight, 0, len(apiResp.Data))
+	for destination, data := range apiResp.Data {
+		flight := c.parseFlightData(destination, data)
+		if flight.Origin == "" {
+			flight.Origin = origin
+		}
+		flights = append(flights, *flight)
+	}
+
+	flights = c.cheapestPerDestination(flights)
+	c.nameCities(ctx, flights)
+	return flights, nil
+}
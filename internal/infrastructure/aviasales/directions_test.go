@@ -0,0 +1,65 @@
+package aviasales
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_PopularDirections(t *testing.T) {
+	var gotQuery string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/city-directions":
+			gotQuery = r.URL.RawQuery
+			_, _ = w.Write([]byte(`{
+				"success": true,
+				"currency": "rub",
+				"data": {
+					"IST": {"origin": "MOW", "destination": "IST", "price": 8500, "transfers": 1, "airline": "PC", "flight_number": 397, "departure_at": "2024-12-15T10:30:00Z", "return_at": "2024-12-22T15:45:00Z"},
+					"AER": {"destination": "AER", "price": 3673, "transfers": 0, "airline": "WZ", "flight_number": 125, "departure_at": "2024-12-20T08:00:00Z"}
+				}
+			}`))
+		case "/data/ru/cities.json":
+			_, _ = w.Write([]byte(`[{"code": "MOW", "name": "Москва"}, {"code": "AER", "name": "Сочи"}, {"code": "IST", "name": "Стамбул"}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "TEST_TOKEN", "668475")
+	flights, err := c.PopularDirections(context.Background(), "MOW", "rub")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if gotQuery != "currency=rub&marker=668475&origin=MOW&token=TEST_TOKEN" {
+		t.Errorf("unexpected query: %s", gotQuery)
+	}
+	if len(flights) != 2 {
+		t.Fatalf("expected 2 directions, got %d", len(flights))
+	}
+	if flights[0].Destination != "AER" || flights[0].Origin != "MOW" || flights[0].Price != 3673 || flights[0].DestinationName != "Сочи" {
+		t.Errorf("unexpected first direction: %+v", flights[0])
+	}
+	if flights[1].Destination != "IST" || flights[1].Transfers != 1 || flights[1].FlightNumber != 397 || flights[1].ReturnDate.IsZero() {
+		t.Errorf("unexpected second direction: %+v", flights[1])
+	}
+	if link := c.GeneratePartnerLink(flights[1], 1); link != "https://www.aviasales.com/search/MOW1512IST2212?marker=668475&passengers=1" {
+		t.Errorf("unexpected link: %s", link)
+	}
+}
+
+func TestClient_PopularDirections_APIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"success": false, "error": "unknown origin"}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "TEST_TOKEN", "668475")
+	if _, err := c.PopularDirections(context.Background(), "XXX", ""); err == nil {
+		t.Error("expected API error")
+	}
+}
@@ -0,0 +1,191 @@
+package aviasales
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// GroupBy определяет период группировки grouped_prices
+type GroupBy string
+
+const (
+	GroupByDeparture GroupBy = "departure_at" // самый дешевый билет на каждый день вылета
+	GroupByMonth     GroupBy = "month"        // самый дешевый билет на каждый месяц вылета
+)
+
+// GroupedParams параметры поиска самых дешевых билетов по периодам
+type GroupedParams struct {
+	Origin      string  // IATA код города отправления
+	Destination string  // IATA код города назначения
+	GroupBy     GroupBy // Период группировки, по умолчанию месяц
+	DepartureAt string  // Дата или месяц вылета (YYYY-MM-DD или YYYY-MM), необязательно
+	ReturnAt    string  // Дата или месяц возвращения, необязательно
+	Direct      bool    // Только прямые рейсы
+	Currency    string  // Валюта (rub, usd, eur)
+}
+
+// GroupedPrices самый дешевый билет на каждый период
+type GroupedPrices struct {
+	Origin      string            `json:"origin"`
+	Destination string            `json:"destination"`
+	GroupBy     GroupBy           `json:"group_by"`
+	Currency    string            `json:"currency"`
+	Periods     map[string]Flight `json:"periods"` // ключ — YYYY-MM для месяцев, YYYY-MM-DD для дней
+}
+
+// Keys возвращает периоды по возрастанию
+func (g *GroupedPrices) Keys() []string {
+	keys := make([]string, 0, len(g.Periods))
+	for key := range g.Periods {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Cheapest возвращает период и билет с минимальной ценой; при равных ценах — более ранний период
+func (g *GroupedPrices) Cheapest() (string, Flight, bool) {
+	var bestKey string
+	var best Flight
+	for _, key := range g.Keys() {
+		if flight := g.Periods[key]; bestKey == "" || flight.Price < best.Price {
+			bestKey, best = key, flight
+		}
+	}
+	return bestKey, best, bestKey != ""
+}
+
+// groupedResponse ответ /aviasales/v3/grouped_prices: период → билет
+type groupedResponse struct {
+	Success  bool                              `json:"success"`
+	Data     map[string]map[string]interface{} `json:"data"`
+	Currency string                            `json:"currency"`
+	Error    string                            `json:"error,omitempty"`
+}
+
+// SearchGroupedPrices возвращает самый дешевый билет на каждый день или месяц используя /aviasales/v3/grouped_prices
+func (c *Client) SearchGroupedPrices(ctx context.Context, p GroupedParams) (*GroupedPrices, error) {
+	groupBy := p.GroupBy
+	if groupBy == "" {
+		groupBy = GroupByMonth
+	}
+
+	q := url.Values{}
+	q.Set("origin", p.Origin)
+	q.Set("destination", p.Destination)
+	q.Set("group_by", string(groupBy))
+	if p.DepartureAt != "" {
+		q.Set("departure_at", p.DepartureAt)
+	}
+	if p.ReturnAt != "" {
+		q.Set("return_at", p.ReturnAt)
+	}
+	if p.Direct {
+		q.Set("direct", "true")
+	}
+	if p.Currency != "" {
+		q.Set("currency", p.Currency)
+	}
+
+	var apiResp groupedResponse
+	err := c.get(ctx, "/aviasales/v3/grouped_prices", q, map[string]interface{}{
+		"origin":      p.Origin,
+		"destination": p.Destination,
+		"group_by":    string(groupBy),
+	}, &apiResp)
+	if err != nil {
+		return nil, err
+	}
+
+	if !apiResp.Success {
+		return nil, fmt.Errorf("API error: %s", apiResp.Error)
+	}
+
+	grouped := &GroupedPrices{
+		Origin:      p.Origin,
+		Destination: p.Destination,
+		GroupBy:     groupBy,
+		Currency:    coalesce(apiResp.Currency, p.Currency),
+		Periods:     make(map[string]Flight, len(apiResp.Data)),
+	}
+	for period, data := range apiResp.Data {
+		flight := c.parseV3Price(data)
+		if flight.Destination == "" {
+			flight.Destination = p.Destination
+		}
+		grouped.Periods[period] = flight
+	}
+
+	return grouped, nil
+}
+
+// FormatGroupedMessage форматирует самые дешевые билеты по периодам для Telegram:
+// строка на месяц («Март — от 12 300 ₽», цена в валюте g.Currency) или день вылета, самый дешевый период отмечен огнем
+func (c *Client) FormatGroupedMessage(originCity, destCity string, g *GroupedPrices) string {
+	cheapestKey, _, ok := g.Cheapest()
+	if !ok {
+		return fmt.Sprintf("😔 К сожалению, билеты %s → %s не найдены", originCity, destCity)
+	}
+
+	// год указываем, если периоды в разных годах; ключи из ответа API могут быть любыми
+	keys := g.Keys()
+	withYear := false
+	firstYear := 0
+	for _, key := range keys {
+		year := periodYear(key)
+		switch {
+		case year == 0:
+		case firstYear == 0:
+			firstYear = year
+		case year != firstYear:
+			withYear = true
+		}
+	}
+
+	var msg strings.Builder
+	msg.WriteString(fmt.Sprintf("📆 <b>%s → %s</b>\n\n", originCity, destCity))
+
+	for _, key := range keys {
+		flight := g.Periods[key]
+		line := fmt.Sprintf("%s — от <b>%s</b>", c.formatPeriod(key, withYear), c.formatPriceIn(flight.Price, g.Currency))
+		if key == cheapestKey {
+			line = "🔥 " + line
+		}
+		msg.WriteString(line + "\n")
+	}
+
+	msg.WriteString("\n💡 <i>Самые дешевые найденные цены за период</i>")
+
+	return msg.String()
+}
+
+// periodYear возвращает год периода YYYY-MM или YYYY-MM-DD; 0 — ключ не является датой
+func periodYear(key string) int {
+	for _, layout := range []string{"2006-01", "2006-01-02"} {
+		if t, err := time.Parse(layout, key); err == nil {
+			return t.Year()
+		}
+	}
+	return 0
+}
+
+// formatPeriod форматирует период grouped_prices: «Март» («Март 2025») или «15 мар»
+func (c *Client) formatPeriod(key string, withYear bool) string {
+	if month, err := time.Parse("2006-01", key); err == nil {
+		name := []rune(monthNames[month.Month()-1])
+		name[0] = unicode.ToUpper(name[0])
+		if withYear {
+			return fmt.Sprintf("%s %d", string(name), month.Year())
+		}
+		return string(name)
+	}
+	if day, err := time.Parse("2006-01-02", key); err == nil {
+		return c.formatDate(day)
+	}
+	return key
+}
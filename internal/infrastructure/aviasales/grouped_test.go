@@ -0,0 +1,106 @@
+package aviasales
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestClient_SearchGroupedPrices(t *testing.T) {
+	var gotPath string
+	var gotQuery url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotQuery = r.URL.Query()
+		_, _ = w.Write([]byte(`{
+			"success": true,
+			"currency": "rub",
+			"data": {
+				"2025-03": {"origin": "MOW", "destination": "AER", "price": 12300, "airline": "SU", "flight_number": "1120", "departure_at": "2025-03-11T07:05:00+03:00", "transfers": 0, "link": "/search/MOW1103AER1"},
+				"2025-01": {"origin": "MOW", "destination": "AER", "price": 15400, "airline": "DP", "departure_at": "2025-01-20T10:00:00+03:00", "transfers": 1}
+			}
+		}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "TEST_TOKEN", "668475")
+	grouped, err := c.SearchGroupedPrices(context.Background(), GroupedParams{Origin: "MOW", Destination: "AER", Direct: true, Currency: "rub"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if gotPath != "/aviasales/v3/grouped_prices" {
+		t.Errorf("unexpected path %s", gotPath)
+	}
+	if gotQuery.Get("group_by") != "month" || gotQuery.Get("direct") != "true" || gotQuery.Get("destination") != "AER" {
+		t.Errorf("unexpected query: %v", gotQuery)
+	}
+
+	if grouped.GroupBy != GroupByMonth || grouped.Currency != "rub" || len(grouped.Periods) != 2 {
+		t.Fatalf("unexpected grouped prices: %+v", grouped)
+	}
+	if keys := grouped.Keys(); keys[0] != "2025-01" || keys[1] != "2025-03" {
+		t.Errorf("expected periods in order, got %v", keys)
+	}
+	key, best, ok := grouped.Cheapest()
+	if !ok || key != "2025-03" || best.Price != 12300 || best.FlightNumber != 1120 || best.Link == "" {
+		t.Errorf("unexpected cheapest: %s %+v", key, best)
+	}
+}
+
+func TestClient_SearchGroupedPrices_APIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"success": false, "error": "destination is required"}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "TEST_TOKEN", "668475")
+	if _, err := c.SearchGroupedPrices(context.Background(), GroupedParams{Origin: "MOW", GroupBy: GroupByDeparture}); err == nil {
+		t.Error("expected API error")
+	}
+}
+
+func TestFormatGroupedMessage(t *testing.T) {
+	c := NewClient("https://api.travelpayouts.com", "TEST_TOKEN", "668475")
+
+	months := &GroupedPrices{Periods: map[string]Flight{
+		"2025-03": {Price: 12300},
+		"2025-01": {Price: 15400},
+	}}
+	msg := c.FormatGroupedMessage("Москва", "Сочи", months)
+	for _, want := range []string{"📆 <b>Москва → Сочи</b>", "Январь — от <b>15 400 ₽</b>\n🔥 Март — от <b>12 300 ₽</b>"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected message to contain %q, got:\n%s", want, msg)
+		}
+	}
+
+	// Периоды в разных годах подписываются годом
+	months.Periods["2024-12"] = Flight{Price: 18000}
+	if msg := c.FormatGroupedMessage("Москва", "Сочи", months); !strings.Contains(msg, "Декабрь 2024 — от <b>18 000 ₽</b>") {
+		t.Errorf("expected year in month names, got:\n%s", msg)
+	}
+
+	days := &GroupedPrices{GroupBy: GroupByDeparture, Periods: map[string]Flight{"2025-03-11": {Price: 9900}}}
+	if msg := c.FormatGroupedMessage("Москва", "Сочи", days); !strings.Contains(msg, "🔥 11 мар — от <b>9 900 ₽</b>") {
+		t.Errorf("unexpected days message:\n%s", msg)
+	}
+
+	// Короткие и нераспознанные ключи из ответа API выводятся как есть
+	odd := &GroupedPrices{Periods: map[string]Flight{"": {Price: 5000}, "Q1": {Price: 7000}, "2025-03": {Price: 6000}}}
+	if msg := c.FormatGroupedMessage("Москва", "Сочи", odd); !strings.Contains(msg, "Q1 — от <b>7 000 ₽</b>") || !strings.Contains(msg, "Март — от") {
+		t.Errorf("unexpected message for odd keys:\n%s", msg)
+	}
+
+	// цены показываются в валюте запроса
+	usd := &GroupedPrices{Currency: "usd", Periods: map[string]Flight{"2025-03": {Price: 250}}}
+	if msg := c.FormatGroupedMessage("Москва", "Сочи", usd); !strings.Contains(msg, "Март — от <b>250 $</b>") || strings.Contains(msg, "₽") {
+		t.Errorf("expected prices in dollars, got:\n%s", msg)
+	}
+
+	if msg := c.FormatGroupedMessage("Москва", "Сочи", &GroupedPrices{}); !strings.Contains(msg, "не найдены") {
+		t.Errorf("unexpected empty message: %s", msg)
+	}
+}
@@ -0,0 +1,61 @@
+package aviasales
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+// LatestParams параметры ленты последних найденных цен
+type LatestParams struct {
+	Origin            string // IATA код города отправления, необязательно
+	Destination       string // IATA код города назначения, необязательно (пусто — куда угодно)
+	Currency          string // Валюта (rub, usd, eur)
+	PeriodType        string // Период дат вылета: year или month
+	BeginningOfPeriod string // Начало периода (YYYY-MM-DD), обязательно для period_type=month
+	OneWay            bool   // Только билеты в одну сторону
+	Page              int    // Номер страницы, начиная с 1
+	Limit             int    // Размер страницы (до 1000)
+	ShowToAffiliates  bool   // Только цены, найденные с партнерским marker
+	Sorting           string // price, route или distance_unit_price
+	TripClass         int    // 0 — эконом, 1 — бизнес, 2 — первый класс
+}
+
+// SearchLatest возвращает цены, найденные пользователями за последние 48 часов, используя /v2/prices/latest
+func (c *Client) SearchLatest(ctx context.Context, p LatestParams) ([]Flight, error) {
+	q := url.Values{}
+	if p.Origin != "" {
+		q.Set("origin", p.Origin)
+	}
+	if p.Destination != "" {
+		q.Set("destination", p.Destination)
+	}
+	if p.Currency != "" {
+		q.Set("currency", p.Currency)
+	}
+	if p.PeriodType != "" {
+		q.Set("period_type", p.PeriodType)
+	}
+	if p.BeginningOfPeriod != "" {
+		q.Set("beginning_of_period", p.BeginningOfPeriod)
+	}
+	if p.Sorting != "" {
+		q.Set("sorting", p.Sorting)
+	}
+	if p.Page > 0 {
+		q.Set("page", strconv.Itoa(p.Page))
+	}
+	if p.Limit > 0 {
+		q.Set("limit", strconv.Itoa(p.Limit))
+	}
+	q.Set("one_way", strconv.FormatBool(p.OneWay))
+	q.Set("show_to_affiliates", strconv.FormatBool(p.ShowToAffiliates))
+	q.Set("trip_class", strconv.Itoa(p.TripClass))
+
+	flights, _, err := c.getV2Prices(ctx, "/v2/prices/latest", q, map[string]interface{}{
+		"origin":      p.Origin,
+		"destination": p.Destination,
+		"page":        p.Page,
+	})
+	return flights, err
+}
@@ -0,0 +1,78 @@
+package aviasales
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestClient_SearchLatest(t *testing.T) {
+	var gotPath string
+	var gotQuery url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotQuery = r.URL.Query()
+		_, _ = w.Write([]byte(`{
+			"success": true,
+			"currency": "rub",
+			"data": [
+				{"origin": "MOW", "destination": "AER", "depart_date": "2024-12-20", "return_date": "", "value": 4200, "number_of_changes": 0, "trip_class": 0, "gate": "Pobeda", "distance": 1359, "duration": 150, "found_at": "2024-12-01T08:00:00Z", "actual": true},
+				{"origin": "MOW", "destination": "IST", "depart_date": "2024-12-22", "value": 15800, "number_of_changes": 1, "trip_class": 0, "gate": "Turkish", "distance": 1750, "actual": true}
+			]
+		}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "TEST_TOKEN", "668475")
+	flights, err := c.SearchLatest(context.Background(), LatestParams{
+		Origin:            "MOW",
+		Currency:          "rub",
+		PeriodType:        "month",
+		BeginningOfPeriod: "2024-12-01",
+		OneWay:            true,
+		Page:              2,
+		Limit:             50,
+		ShowToAffiliates:  true,
+		Sorting:           "route",
+		TripClass:         1,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if gotPath != "/v2/prices/latest" {
+		t.Errorf("expected path /v2/prices/latest, got %s", gotPath)
+	}
+	expected := map[string]string{
+		"origin":              "MOW",
+		"period_type":         "month",
+		"beginning_of_period": "2024-12-01",
+		"one_way":             "true",
+		"page":                "2",
+		"limit":               "50",
+		"show_to_affiliates":  "true",
+		"sorting":             "route",
+		"trip_class":          "1",
+		"token":               "TEST_TOKEN",
+	}
+	for key, value := range expected {
+		if gotQuery.Get(key) != value {
+			t.Errorf("expected %s=%s, got %q", key, value, gotQuery.Get(key))
+		}
+	}
+	if _, ok := gotQuery["destination"]; ok {
+		t.Error("expected no destination for search to anywhere")
+	}
+
+	if len(flights) != 2 {
+		t.Fatalf("expected 2 flights, got %d", len(flights))
+	}
+	if flights[0].Destination != "AER" || flights[0].Price != 4200 || flights[0].Duration != 150 || !flights[0].ReturnDate.IsZero() {
+		t.Errorf("unexpected first flight: %+v", flights[0])
+	}
+	if flights[1].Transfers != 1 || formatAPIDate(flights[1].DepartDate) != "2024-12-22" {
+		t.Errorf("unexpected second flight: %+v", flights[1])
+	}
+}
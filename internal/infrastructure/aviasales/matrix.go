@@ -0,0 +1,156 @@
+package aviasales
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// MatrixParams параметры матрицы цен
+type MatrixParams struct {
+	Origin      string // IATA код города отправления
+	Destination string // IATA код города назначения
+	DepartDate  string // Дата вылета (YYYY-MM-DD); для месячной матрицы достаточно YYYY-MM
+	ReturnDate  string // Дата возвращения (YYYY-MM-DD), для недельной матрицы
+	Currency    string // Валюта (rub, usd, eur)
+}
+
+// PriceMatrix цены по сетке дат вылета и возвращения
+type PriceMatrix struct {
+	Origin      string   `json:"origin"`
+	Destination string   `json:"destination"`
+	Currency    string   `json:"currency"`
+	Flights     []Flight `json:"flights"` // по возрастанию даты вылета, затем даты возвращения
+}
+
+// Lookup возвращает билет для пары дат YYYY-MM-DD; returnDate пустой для билетов в одну сторону
+func (m *PriceMatrix) Lookup(departDate, returnDate string) (Flight, bool) {
+	for _, flight := range m.Flights {
+		if formatAPIDate(flight.DepartDate) == departDate && formatAPIDate(flight.ReturnDate) == returnDate {
+			return flight, true
+		}
+	}
+	return Flight{}, false
+}
+
+// v2PricesResponse ответ v2 endpoints цен (month-matrix, week-matrix, latest)
+type v2PricesResponse struct {
+	Success  bool                     `json:"success"`
+	Data     []map[string]interface{} `json:"data"`
+	Currency string                   `json:"currency"`
+	Error    string                   `json:"error,omitempty"`
+}
+
+// SearchWeekMatrix возвращает цены на ±3 дня от дат вылета и возвращения используя /v2/prices/week-matrix
+func (c *Client) SearchWeekMatrix(ctx context.Context, p MatrixParams) (*PriceMatrix, error) {
+	q := url.Values{}
+	q.Set("depart_date", p.DepartDate)
+	if p.ReturnDate != "" {
+		q.Set("return_date", p.ReturnDate)
+	}
+	return c.searchMatrix(ctx, "/v2/prices/week-matrix", p, q)
+}
+
+// SearchMonthMatrix возвращает цены на каждый день месяца вылета используя /v2/prices/month-matrix
+func (c *Client) SearchMonthMatrix(ctx context.Context, p MatrixParams) (*PriceMatrix, error) {
+	month := p.DepartDate
+	if len(month) == len("2006-01") {
+		month += "-01"
+	}
+
+	q := url.Values{}
+	q.Set("month", month)
+	return c.searchMatrix(ctx, "/v2/prices/month-matrix", p, q)
+}
+
+// searchMatrix запрашивает матрицу цен и сортирует ячейки по датам
+func (c *Client) searchMatrix(ctx context.Context, endpoint string, p MatrixParams, q url.Values) (*PriceMatrix, error) {
+	q.Set("origin", p.Origin)
+	q.Set("destination", p.Destination)
+	q.Set("show_to_affiliates", "true")
+	if p.Currency != "" {
+		q.Set("currency", p.Currency)
+	}
+
+	flights, currency, err := c.getV2Prices(ctx, endpoint, q, map[string]interface{}{
+		"origin":      p.Origin,
+		"destination": p.Destination,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(flights, func(i, j int) bool {
+		if !flights[i].DepartDate.Equal(flights[j].DepartDate) {
+			return flights[i].DepartDate.Before(flights[j].DepartDate)
+		}
+		return flights[i].ReturnDate.Before(flights[j].ReturnDate)
+	})
+
+	return &PriceMatrix{
+		Origin:      p.Origin,
+		Destination: p.Destination,
+		Currency:    coalesce(currency, p.Currency),
+		Flights:     flights,
+	}, nil
+}
+
+// getV2Prices выполняет запрос к v2 endpoint цен и парсит массив data в Flight
+func (c *Client) getV2Prices(ctx context.Context, endpoint string, q url.Values, meta map[string]interface{}) ([]Flight, string, error) {
+	var apiResp v2PricesResponse
+	if err := c.get(ctx, endpoint, q, meta, &apiResp); err != nil {
+		return nil, "", err
+	}
+
+	if !apiResp.Success {
+		return nil, "", fmt.Errorf("API error: %s", apiResp.Error)
+	}
+
+	flights := make([]Flight, 0, len(apiResp.Data))
+	for _, data := range apiResp.Data {
+		flights = append(flights, c.parseV2Price(data))
+	}
+	return flights, apiResp.Currency, nil
+}
+
+// parseV2Price парсит элемент data v2 endpoints: цена в value, даты в depart_date/return_date
+func (c *Client) parseV2Price(data map[string]interface{}) Flight {
+	destination, _ := data["destination"].(string)
+	flight := *c.parseFlightData(destination, data)
+
+	if value, ok := data["value"].(float64); ok {
+		flight.Price = int(value)
+	}
+	if changes, ok := data["number_of_changes"].(float64); ok {
+		flight.Transfers = int(changes)
+	}
+	if departStr, ok := data["depart_date"].(string); ok {
+		if departDate, err := time.Parse("2006-01-02", departStr); err == nil {
+			flight.DepartDate = departDate
+		}
+	}
+	if returnStr, ok := data["return_date"].(string); ok {
+		if returnDate, err := time.Parse("2006-01-02", returnStr); err == nil {
+			flight.ReturnDate = returnDate
+		}
+	}
+
+	return flight
+}
+
+// formatAPIDate форматирует дату как YYYY-MM-DD; нулевая дата — пустая строка
+func formatAPIDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+func coalesce(a, b string) string {
+	if a != "" {
+		return a
+	}
+	return b
+}
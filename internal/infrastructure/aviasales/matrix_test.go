@@ -0,0 +1,99 @@
+package aviasales
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+const matrixResponse = `{
+	"success": true,
+	"currency": "rub",
+	"data": [
+		{"origin": "MOW", "destination": "BCN", "depart_date": "2024-12-16", "return_date": "2024-12-22", "value": 18000, "number_of_changes": 0, "gate": "Pobeda", "distance": 3007, "actual": true},
+		{"origin": "MOW", "destination": "BCN", "depart_date": "2024-12-15", "return_date": "2024-12-23", "value": 21000, "number_of_changes": 1, "gate": "Aeroflot", "distance": 3007, "actual": true},
+		{"origin": "MOW", "destination": "BCN", "depart_date": "2024-12-15", "return_date": "2024-12-22", "value": 19500, "number_of_changes": 1, "gate": "Aeroflot", "distance": 3007, "actual": true}
+	]
+}`
+
+func TestClient_SearchWeekMatrix(t *testing.T) {
+	var gotPath string
+	var gotQuery url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotQuery = r.URL.Query()
+		_, _ = w.Write([]byte(matrixResponse))
+	}))
+	defer srv.Close()
+
+	lg := &testLogger{}
+	c := NewClient(srv.URL, "TEST_TOKEN", "668475", WithLogger(lg))
+	matrix, err := c.SearchWeekMatrix(context.Background(), MatrixParams{
+		Origin:      "MOW",
+		Destination: "BCN",
+		DepartDate:  "2024-12-15",
+		ReturnDate:  "2024-12-22",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if gotPath != "/v2/prices/week-matrix" {
+		t.Errorf("expected path /v2/prices/week-matrix, got %s", gotPath)
+	}
+	if gotQuery.Get("depart_date") != "2024-12-15" || gotQuery.Get("return_date") != "2024-12-22" || gotQuery.Get("show_to_affiliates") != "true" {
+		t.Errorf("unexpected query: %v", gotQuery)
+	}
+	if !lg.lastExternal.called || lg.lastExternal.endpoint != "/v2/prices/week-matrix" || lg.lastExternal.metadata["origin"] != "MOW" {
+		t.Errorf("expected external API call to be logged, got %+v", lg.lastExternal)
+	}
+
+	if len(matrix.Flights) != 3 || matrix.Currency != "rub" {
+		t.Fatalf("unexpected matrix: %+v", matrix)
+	}
+	first := matrix.Flights[0]
+	if first.Price != 19500 || first.Transfers != 1 || first.Gate != "Aeroflot" || formatAPIDate(first.DepartDate) != "2024-12-15" {
+		t.Errorf("expected flights sorted by dates, got first %+v", first)
+	}
+
+	flight, ok := matrix.Lookup("2024-12-16", "2024-12-22")
+	if !ok || flight.Price != 18000 || flight.Transfers != 0 {
+		t.Errorf("unexpected lookup result: %+v %v", flight, ok)
+	}
+	if _, ok := matrix.Lookup("2024-12-18", "2024-12-22"); ok {
+		t.Error("expected missing cell")
+	}
+}
+
+func TestClient_SearchMonthMatrix(t *testing.T) {
+	var gotPath, gotMonth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotMonth = r.URL.Query().Get("month")
+		if r.URL.Query().Get("origin") == "XXX" {
+			_, _ = w.Write([]byte(`{"success": false, "error": "unknown origin"}`))
+			return
+		}
+		_, _ = w.Write([]byte(matrixResponse))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "TEST_TOKEN", "668475")
+	matrix, err := c.SearchMonthMatrix(context.Background(), MatrixParams{Origin: "MOW", Destination: "BCN", DepartDate: "2024-12", Currency: "rub"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if gotPath != "/v2/prices/month-matrix" || gotMonth != "2024-12-01" {
+		t.Errorf("unexpected request: %s month=%s", gotPath, gotMonth)
+	}
+	if len(matrix.Flights) != 3 {
+		t.Errorf("expected 3 flights, got %d", len(matrix.Flights))
+	}
+
+	if _, err := c.SearchMonthMatrix(context.Background(), MatrixParams{Origin: "XXX", Destination: "BCN", DepartDate: "2024-12"}); err == nil {
+		t.Error("expected API error")
+	}
+}
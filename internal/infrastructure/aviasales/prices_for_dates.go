@@ -0,0 +1,121 @@
+package aviasales
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// PricesForDatesParams параметры поиска через /aviasales/v3/prices_for_dates
+type PricesForDatesParams struct {
+	Origin      string // IATA код города отправления
+	Destination string // IATA код города назначения; пусто, "-" или "anywhere" — все направления
+	DepartureAt string // Дата вылета (YYYY-MM-DD или YYYY-MM)
+	ReturnAt    string // Дата возвращения (YYYY-MM-DD или YYYY-MM), необязательно
+	OneWay      bool   // Билеты в одну сторону
+	Direct      bool   // Только прямые рейсы
+	Unique      bool   // Один билет на направление
+	Sorting     string // price (по умолчанию) или route
+	Currency    string // Валюта (rub, usd, eur)
+	Limit       int    // Размер страницы (до 1000)
+	Page        int    // Номер страницы, начиная с 1
+}
+
+// v3PricesResponse ответ /aviasales/v3/prices_for_dates: типизированный массив билетов
+type v3PricesResponse struct {
+	Success  bool                     `json:"success"`
+	Data     []map[string]interface{} `json:"data"`
+	Currency string                   `json:"currency"`
+	Error    string                   `json:"error,omitempty"`
+}
+
+// SearchPricesForDates ищет самые дешевые билеты на даты используя /aviasales/v3/prices_for_dates.
+// В отличие от v1 поддерживает билеты в одну сторону, прямые рейсы, сортировку и постраничный вывод.
+func (c *Client) SearchPricesForDates(ctx context.Context, p PricesForDatesParams) ([]Flight, error) {
+	anywhere := IsAnywhere(p.Destination)
+
+	q := url.Values{}
+	q.Set("origin", p.Origin)
+	if !anywhere {
+		q.Set("destination", p.Destination)
+	}
+	if p.DepartureAt != "" {
+		q.Set("departure_at", p.DepartureAt)
+	}
+	if p.ReturnAt != "" {
+		q.Set("return_at", p.ReturnAt)
+	}
+	q.Set("one_way", strconv.FormatBool(p.OneWay))
+	q.Set("direct", strconv.FormatBool(p.Direct))
+	q.Set("unique", strconv.FormatBool(p.Unique))
+	if p.Sorting != "" {
+		q.Set("sorting", p.Sorting)
+	}
+	if p.Currency != "" {
+		q.Set("currency", p.Currency)
+	}
+	if p.Limit > 0 {
+		q.Set("limit", strconv.Itoa(p.Limit))
+	}
+	if p.Page > 0 {
+		q.Set("page", strconv.Itoa(p.Page))
+	}
+
+	var apiResp v3PricesResponse
+	err := c.get(ctx, "/aviasales/v3/prices_for_dates", q, map[string]interface{}{
+		"origin":      p.Origin,
+		"destination": p.Destination,
+		"direct":      p.Direct,
+	}, &apiResp)
+	if err != nil {
+		return nil, err
+	}
+
+	if !apiResp.Success {
+		return nil, fmt.Errorf("API error: %s", apiResp.Error)
+	}
+
+	flights := make([]Flight, 0, len(apiResp.Data))
+	for _, data := range apiResp.Data {
+		flights = append(flights, c.parseV3Price(data))
+	}
+	if anywhere {
+		c.nameCities(ctx, flights)
+	}
+
+	return flights, nil
+}
+
+// parseV3Price парсит элемент data v3: номер рейса строкой, пересадки и длительность по направлениям, ссылка
+func (c *Client) parseV3Price(data map[string]interface{}) Flight {
+	destination, _ := data["destination"].(string)
+	flight := *c.parseFlightData(destination, data)
+
+	if number, ok := data["flight_number"].(string); ok {
+		flight.FlightNumber, _ = strconv.Atoi(number)
+	}
+	if transfers, ok := data["return_transfers"].(float64); ok {
+		flight.ReturnTransfers = int(transfers)
+	}
+	if duration, ok := data["duration_to"].(float64); ok {
+		flight.DurationTo = int(duration)
+	}
+	if duration, ok := data["duration_back"].(float64); ok {
+		flight.DurationBack = int(duration)
+	}
+	if link, ok := data["link"].(string); ok {
+		flight.Link = c.searchLink(link)
+	}
+
+	return flight
+}
+
+// searchLink превращает относительную ссылку v3 (/search/...) в абсолютную с партнерским marker
+func (c *Client) searchLink(link string) string {
+	if strings.HasPrefix(link, "/") {
+		link = "https://www.aviasales.com" + link
+	}
+	return c.offerLink(link)
+}
@@ -0,0 +1,130 @@
+package aviasales
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestClient_SearchPricesForDates(t *testing.T) {
+	var gotPath string
+	var gotQuery url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotQuery = r.URL.Query()
+		_, _ = w.Write([]byte(`{
+			"success": true,
+			"currency": "rub",
+			"data": [
+				{
+					"origin": "MOW", "destination": "LED", "origin_airport": "SVO", "destination_airport": "LED",
+					"price": 2130, "airline": "SU", "flight_number": "6",
+					"departure_at": "2024-12-15T06:00:00+03:00", "return_at": "2024-12-22T21:30:00+03:00",
+					"transfers": 0, "return_transfers": 1, "duration": 240, "duration_to": 85, "duration_back": 155,
+					"link": "/search/MOW1512LED22121?t=SU17342318001734236900000085SVOLED"
+				}
+			]
+		}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "TEST_TOKEN", "668475")
+	flights, err := c.SearchPricesForDates(context.Background(), PricesForDatesParams{
+		Origin:      "MOW",
+		Destination: "LED",
+		DepartureAt: "2024-12-15",
+		ReturnAt:    "2024-12",
+		Direct:      true,
+		Unique:      true,
+		Sorting:     "route",
+		Currency:    "rub",
+		Limit:       5,
+		Page:        2,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if gotPath != "/aviasales/v3/prices_for_dates" {
+		t.Errorf("unexpected path %s", gotPath)
+	}
+	expected := map[string]string{
+		"origin":       "MOW",
+		"destination":  "LED",
+		"departure_at": "2024-12-15",
+		"return_at":    "2024-12",
+		"one_way":      "false",
+		"direct":       "true",
+		"unique":       "true",
+		"sorting":      "route",
+		"currency":     "rub",
+		"limit":        "5",
+		"page":         "2",
+	}
+	for key, value := range expected {
+		if gotQuery.Get(key) != value {
+			t.Errorf("expected %s=%s, got %q", key, value, gotQuery.Get(key))
+		}
+	}
+
+	if len(flights) != 1 {
+		t.Fatalf("expected 1 flight, got %d", len(flights))
+	}
+	f := flights[0]
+	if f.Origin != "MOW" || f.Destination != "LED" || f.Price != 2130 || f.Airline != "SU" || f.FlightNumber != 6 {
+		t.Errorf("unexpected flight: %+v", f)
+	}
+	if f.Transfers != 0 || f.ReturnTransfers != 1 || f.Duration != 240 || f.DurationTo != 85 || f.DurationBack != 155 {
+		t.Errorf("unexpected transfers/durations: %+v", f)
+	}
+	if !f.DepartDate.Equal(time.Date(2024, 12, 15, 3, 0, 0, 0, time.UTC)) || f.ReturnDate.IsZero() {
+		t.Errorf("unexpected dates: %v %v", f.DepartDate, f.ReturnDate)
+	}
+	if f.Link != "https://www.aviasales.com/search/MOW1512LED22121?marker=668475&t=SU17342318001734236900000085SVOLED" {
+		t.Errorf("unexpected link: %s", f.Link)
+	}
+}
+
+func TestClient_SearchPricesForDates_AnywhereOneWay(t *testing.T) {
+	var gotQuery url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/data/ru/cities.json" {
+			_, _ = w.Write([]byte(`[{"code": "MOW", "name": "Москва"}, {"code": "AER", "name": "Сочи"}]`))
+			return
+		}
+		gotQuery = r.URL.Query()
+		_, _ = w.Write([]byte(`{"success": true, "data": [{"origin": "MOW", "destination": "AER", "price": 3500, "departure_at": "2024-12-20T08:00:00+03:00"}]}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "TEST_TOKEN", "668475")
+	flights, err := c.SearchPricesForDates(context.Background(), PricesForDatesParams{Origin: "MOW", Destination: "-", DepartureAt: "2024-12", OneWay: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := gotQuery["destination"]; ok {
+		t.Error("expected no destination for search to anywhere")
+	}
+	if gotQuery.Get("one_way") != "true" || gotQuery.Get("direct") != "false" {
+		t.Errorf("unexpected flags: %v", gotQuery)
+	}
+	if len(flights) != 1 || flights[0].DestinationName != "Сочи" || flights[0].ReturnTransfers != 0 || flights[0].Link != "" {
+		t.Errorf("unexpected flights: %+v", flights)
+	}
+}
+
+func TestClient_SearchPricesForDates_APIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"success": false, "error": "invalid departure_at"}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "TEST_TOKEN", "668475")
+	if _, err := c.SearchPricesForDates(context.Background(), PricesForDatesParams{Origin: "MOW", Destination: "LED"}); err == nil {
+		t.Error("expected API error")
+	}
+}
@@ -0,0 +1,201 @@
+package aviasales
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SpecialOffer спецпредложение авиакомпании на одном маршруте
+type SpecialOffer struct {
+	Airline         string    `json:"airline"`      // название авиакомпании
+	AirlineCode     string    `json:"airline_code"` // IATA код авиакомпании
+	Title           string    `json:"title"`
+	Description     string    `json:"description,omitempty"`
+	Origin          string    `json:"origin"`
+	Destination     string    `json:"destination"`
+	OriginName      string    `json:"origin_name,omitempty"`
+	DestinationName string    `json:"destination_name,omitempty"`
+	Price           int       `json:"price"`
+	Currency        string    `json:"currency"`
+	OneWay          bool      `json:"one_way"`
+	SaleStart       time.Time `json:"sale_start"`             // с какого момента действует предложение
+	SaleEnd         time.Time `json:"sale_end"`               // до какого момента можно купить билет
+	FlightStart     time.Time `json:"flight_start,omitempty"` // начало периода вылета
+	FlightEnd       time.Time `json:"flight_end,omitempty"`   // конец периода вылета
+	Link            string    `json:"link"`
+}
+
+// specialOffersFeed XML лента /v2/prices/special-offers
+type specialOffersFeed struct {
+	Offers []struct {
+		Airline         string `xml:"airline,attr"`
+		AirlineCode     string `xml:"airline_code,attr"`
+		Title           string `xml:"title,attr"`
+		Href            string `xml:"href,attr"`
+		SaleDateBegin   string `xml:"sale_date_begin,attr"`
+		SaleDateEnd     string `xml:"sale_date_end,attr"`
+		FlightDateBegin string `xml:"flight_date_begin,attr"`
+		FlightDateEnd   string `xml:"flight_date_end,attr"`
+		Description     string `xml:"description"`
+		Routes          []struct {
+			FromIATA string `xml:"from_iata,attr"`
+			ToIATA   string `xml:"to_iata,attr"`
+			FromName string `xml:"from_name,attr"`
+			ToName   string `xml:"to_name,attr"`
+			OneWay   string `xml:"oneway,attr"`
+			Price    struct {
+				Value    string `xml:",chardata"`
+				Currency string `xml:"currency,attr"`
+			} `xml:"price"`
+		} `xml:"route"`
+	} `xml:"offer"`
+}
+
+// SpecialOffers возвращает действующие спецпредложения авиакомпаний из XML ленты /v2/prices/special-offers.
+// Лента кэшируется в памяти на WithOffersTTL; если обновить ее не удалось, отдается устаревший кэш.
+// Лента загружается без блокировки кэша, поэтому медленный ответ API не задерживает вызовы,
+// которым хватает кэша.
+func (c *Client) SpecialOffers(ctx context.Context) ([]SpecialOffer, error) {
+	now := time.Now()
+	c.offersMu.Lock()
+	cached, cachedAt := c.offers, c.offersAt
+	c.offersMu.Unlock()
+	if cached != nil && now.Sub(cachedAt) < c.offersTTL {
+		return activeOffers(cached, now), nil
+	}
+
+	var feed specialOffersFeed
+	err := c.fetch(ctx, "/v2/prices/special-offers", url.Values{}, nil, func(body io.Reader) error {
+		return xml.NewDecoder(body).Decode(&feed)
+	})
+	var offers []SpecialOffer
+	if err == nil {
+		offers = c.parseSpecialOffers(feed)
+	}
+
+	c.offersMu.Lock()
+	defer c.offersMu.Unlock()
+	if err != nil {
+		if c.offers != nil {
+			return activeOffers(c.offers, now), nil
+		}
+		return nil, err
+	}
+	// пока шла загрузка, кэш мог обновить более поздний вызов
+	if c.offers == nil || !c.offersAt.After(now) {
+		c.offers = offers
+		c.offersAt = now
+	}
+	return activeOffers(c.offers, now), nil
+}
+
+// parseSpecialOffers раскладывает предложения ленты по маршрутам, по возрастанию цены
+func (c *Client) parseSpecialOffers(feed specialOffersFeed) []SpecialOffer {
+	offers := []SpecialOffer{}
+	for _, item := range feed.Offers {
+		for _, route := range item.Routes {
+			price, _ := strconv.ParseFloat(strings.TrimSpace(route.Price.Value), 64)
+			offer := SpecialOffer{
+				Airline:         item.Airline,
+				AirlineCode:     item.AirlineCode,
+				Title:           item.Title,
+				Description:     strings.TrimSpace(item.Description),
+				Origin:          route.FromIATA,
+				Destination:     route.ToIATA,
+				OriginName:      route.FromName,
+				DestinationName: route.ToName,
+				Price:           int(price),
+				Currency:        strings.ToLower(coalesce(route.Price.Currency, "rub")),
+				OneWay:          route.OneWay == "true" || route.OneWay == "1",
+				SaleStart:       parseOfferDate(item.SaleDateBegin),
+				SaleEnd:         parseOfferDate(item.SaleDateEnd),
+				FlightStart:     parseOfferDate(item.FlightDateBegin),
+				FlightEnd:       parseOfferDate(item.FlightDateEnd),
+				Link:            c.offerLink(item.Href),
+			}
+			if offer.Origin == "" || offer.Destination == "" {
+				continue
+			}
+			offers = append(offers, offer)
+		}
+	}
+
+	sort.SliceStable(offers, func(i, j int) bool { return offers[i].Price < offers[j].Price })
+	return offers
+}
+
+// offerLink добавляет партнерский marker к ссылке предложения
+func (c *Client) offerLink(href string) string {
+	u, err := url.Parse(href)
+	if err != nil || href == "" || c.marker == "" {
+		return href
+	}
+	q := u.Query()
+	q.Set("marker", c.marker)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// parseOfferDate парсит дату ленты: unix-время в секундах, RFC3339 или YYYY-MM-DD
+func parseOfferDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}
+	}
+	if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(sec, 0).UTC()
+	}
+	if t, err := parseAPITime(s); err == nil {
+		return t
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t
+	}
+	return time.Time{}
+}
+
+// activeOffers отбрасывает предложения, продажа по которым уже закончилась
+func activeOffers(offers []SpecialOffer, now time.Time) []SpecialOffer {
+	active := make([]SpecialOffer, 0, len(offers))
+	for _, offer := range offers {
+		if offer.SaleEnd.IsZero() || offer.SaleEnd.After(now) {
+			active = append(active, offer)
+		}
+	}
+	return active
+}
+
+// FormatOffersMessage форматирует спецпредложения для Telegram; цена выводится в валюте предложения
+func (c *Client) FormatOffersMessage(offers []SpecialOffer) string {
+	if len(offers) == 0 {
+		return "😔 Сейчас нет действующих спецпредложений"
+	}
+
+	var msg strings.Builder
+	msg.WriteString("🔥 <b>Спецпредложения авиакомпаний</b>\n\n")
+
+	for i, offer := range offers {
+		if i >= 10 { // Показываем максимум 10 предложений
+			break
+		}
+
+		msg.WriteString(fmt.Sprintf("✈️ %s → %s от <b>%s</b>",
+			coalesce(offer.OriginName, offer.Origin),
+			coalesce(offer.DestinationName, offer.Destination),
+			c.formatPriceIn(offer.Price, offer.Currency)))
+		msg.WriteString(fmt.Sprintf(" • %s", offer.Airline))
+		if !offer.SaleEnd.IsZero() {
+			msg.WriteString(fmt.Sprintf(" • до %s", c.formatDate(offer.SaleEnd)))
+		}
+		msg.WriteString(fmt.Sprintf(" • <a href=\"%s\">подробнее</a>\n", offer.Link))
+	}
+
+	return strings.TrimRight(msg.String(), "\n")
+}
@@ -0,0 +1,186 @@
+package aviasales
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const specialOffersXML = `<?xml version="1.0" encoding="UTF-8"?>
+<offers>
+  <offer airline="Победа" airline_code="DP" title="Распродажа в Сочи" href="https://www.aviasales.ru/offers/dp-sochi" sale_date_begin="1733011200" sale_date_end="4102444800" flight_date_begin="2025-01-10" flight_date_end="2025-03-31">
+    <description>
+      Билеты в Сочи и Калининград по специальным тарифам
+    </description>
+    <route from_iata="MOW" to_iata="AER" from_name="Москва" to_name="Сочи" oneway="true"><price currency="RUB">2999</price></route>
+    <route from_iata="MOW" to_iata="KGD" from_name="Москва" to_name="Калининград" oneway="false"><price currency="RUB">5499</price></route>
+  </offer>
+  <offer airline="Аэрофлот" airline_code="SU" title="Закончившаяся акция" href="https://www.aviasales.ru/offers/su" sale_date_begin="1577836800" sale_date_end="1580428800">
+    <route from_iata="MOW" to_iata="LED"><price>1500</price></route>
+  </offer>
+  <offer airline="S7" airline_code="S7" title="Без маршрута" href="https://www.aviasales.ru/offers/s7" sale_date_end="2100-01-01T00:00:00Z">
+    <route from_iata="" to_iata="OVB"><price>4000</price></route>
+  </offer>
+</offers>`
+
+func TestClient_SpecialOffers(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path != "/v2/prices/special-offers" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = w.Write([]byte(specialOffersXML))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "TEST_TOKEN", "668475")
+	offers, err := c.SpecialOffers(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Закончившаяся акция и маршрут без города вылета отброшены, остальное — по возрастанию цены
+	if len(offers) != 2 {
+		t.Fatalf("expected 2 offers, got %d: %+v", len(offers), offers)
+	}
+	first := offers[0]
+	if first.Origin != "MOW" || first.Destination != "AER" || first.DestinationName != "Сочи" || first.Price != 2999 || first.Currency != "rub" || !first.OneWay {
+		t.Errorf("unexpected first offer: %+v", first)
+	}
+	if first.AirlineCode != "DP" || first.Description != "Билеты в Сочи и Калининград по специальным тарифам" {
+		t.Errorf("unexpected offer details: %+v", first)
+	}
+	if !first.SaleStart.Equal(time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)) || first.FlightEnd.Format("2006-01-02") != "2025-03-31" {
+		t.Errorf("unexpected validity dates: %+v", first)
+	}
+	if first.Link != "https://www.aviasales.ru/offers/dp-sochi?marker=668475" {
+		t.Errorf("unexpected link: %s", first.Link)
+	}
+	if offers[1].Destination != "KGD" || offers[1].OneWay {
+		t.Errorf("unexpected second offer: %+v", offers[1])
+	}
+
+	// Повторный вызов отдается из кэша
+	if _, err := c.SpecialOffers(context.Background()); err != nil || calls != 1 {
+		t.Errorf("expected cached offers, got err=%v calls=%d", err, calls)
+	}
+}
+
+func TestClient_SpecialOffers_StaleCacheOnError(t *testing.T) {
+	fail := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(specialOffersXML))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "TEST_TOKEN", "668475", WithOffersTTL(0))
+	if _, err := c.SpecialOffers(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fail = true
+	offers, err := c.SpecialOffers(context.Background())
+	if err != nil || len(offers) != 2 {
+		t.Errorf("expected stale offers on error, got %d offers, err=%v", len(offers), err)
+	}
+
+	empty := NewClient(srv.URL, "TEST_TOKEN", "668475")
+	if _, err := empty.SpecialOffers(context.Background()); err == nil {
+		t.Error("expected error without cache")
+	}
+}
+
+// Тест: кэш не блокируется на время загрузки ленты, параллельные вызовы не ждут друг друга
+// newOverlapServer отвечает body, только дождавшись второго одновременного запроса (или секунды).
+// overlapped сообщает, были ли запросы одновременными, то есть загрузка шла без блокировки кэша.
+func newOverlapServer(body string) (srv *httptest.Server, overlapped func() bool) {
+	var mu sync.Mutex
+	inFlight := 0
+	both := make(chan struct{})
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		if inFlight++; inFlight == 2 {
+			close(both)
+		}
+		mu.Unlock()
+
+		select {
+		case <-both:
+		case <-time.After(time.Second):
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	return srv, func() bool {
+		select {
+		case <-both:
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// runTwice выполняет fn в двух горутинах одновременно и ждет обеих
+func runTwice(fn func()) {
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn()
+		}()
+	}
+	wg.Wait()
+}
+
+func TestClient_SpecialOffers_FetchOutsideLock(t *testing.T) {
+	srv, overlapped := newOverlapServer(specialOffersXML)
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "TEST_TOKEN", "668475")
+	runTwice(func() {
+		if offers, err := c.SpecialOffers(context.Background()); err != nil || len(offers) != 2 {
+			t.Errorf("expected 2 offers, got %d, err=%v", len(offers), err)
+		}
+	})
+
+	if !overlapped() {
+		t.Error("expected the second call not to wait for the first fetch")
+	}
+}
+
+func TestFormatOffersMessage(t *testing.T) {
+	c := NewClient("https://api.travelpayouts.com", "TEST_TOKEN", "668475")
+	msg := c.FormatOffersMessage([]SpecialOffer{{
+		Airline:         "Победа",
+		Origin:          "MOW",
+		Destination:     "AER",
+		OriginName:      "Москва",
+		DestinationName: "Сочи",
+		Price:           2999,
+		SaleEnd:         time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC),
+		Link:            "https://www.aviasales.ru/offers/dp-sochi?marker=668475",
+	}})
+
+	want := "✈️ Москва → Сочи от <b>2 999 ₽</b> • Победа • до 31 янв • <a href=\"https://www.aviasales.ru/offers/dp-sochi?marker=668475\">подробнее</a>"
+	if !strings.Contains(msg, want) {
+		t.Errorf("expected message to contain %q, got:\n%s", want, msg)
+	}
+	// цена выводится в валюте предложения
+	if msg := c.FormatOffersMessage([]SpecialOffer{{Airline: "Pegasus", Origin: "IST", Destination: "AYT", Price: 49, Currency: "usd"}}); !strings.Contains(msg, "от <b>49 $</b>") {
+		t.Errorf("expected price in usd, got:\n%s", msg)
+	}
+	if msg := c.FormatOffersMessage(nil); !strings.Contains(msg, "нет действующих") {
+		t.Errorf("unexpected empty message: %s", msg)
+	}
+}
@@ -0,0 +1,455 @@
+package redisstreams
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"aviasales-bot/search-service/internal/streams"
+)
+
+var (
+	_ streams.RedisClient        = (*Client)(nil)
+	_ streams.RedisProducer      = (*Client)(nil)
+	_ streams.RedisIdempotency   = (*Client)(nil)
+	_ streams.RedisPendingClient = (*Client)(nil)
+	_ streams.RedisDeadLetter    = (*Client)(nil)
+)
+
+func newTestClient(t *testing.T) (*Client, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	c, err := NewClient("redis://"+mr.Addr(), WithBlock(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c, mr
+}
+
+func TestNewClient_InvalidURL(t *testing.T) {
+	if _, err := NewClient("not-a-url"); err == nil {
+		t.Fatal("expected error for invalid url")
+	}
+}
+
+func TestClient_XReadGroup_CreatesGroupWithMkStream(t *testing.T) {
+	c, mr := newTestClient(t)
+	ctx := context.Background()
+
+	events, err := c.XReadGroup(ctx, "search-service", "worker-1", "search.requests", 1)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("expected no events, got %v", events)
+	}
+
+	if !mr.Exists("search.requests") {
+		t.Fatal("expected stream to be created by MKSTREAM")
+	}
+
+	// повторное создание группы не должно падать с BUSYGROUP
+	c.groups = make(map[string]bool)
+	if err := c.EnsureGroup(ctx, "search.requests", "search-service"); err != nil {
+		t.Fatalf("ensure group: %v", err)
+	}
+}
+
+func TestClient_XAddReadAck(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := context.Background()
+
+	if err := c.EnsureGroup(ctx, "search.requests", "search-service"); err != nil {
+		t.Fatalf("ensure group: %v", err)
+	}
+
+	id, err := c.XAdd(ctx, "search.requests", map[string]interface{}{
+		"request_id": "req-1",
+		"chat_id":    "12345",
+		"params":     `{"origin":"MOW","destination":"PAR","depart_date":"2024-12-15"}`,
+	})
+	if err != nil {
+		t.Fatalf("xadd: %v", err)
+	}
+
+	events, err := c.XReadGroup(ctx, "search-service", "worker-1", "search.requests", 10)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+	if events[0][streams.MessageIDField] != id {
+		t.Errorf("expected message id %s, got %v", id, events[0][streams.MessageIDField])
+	}
+	if events[0]["request_id"] != "req-1" {
+		t.Errorf("expected request_id req-1, got %v", events[0]["request_id"])
+	}
+
+	if err := c.XAck(ctx, "search.requests", "search-service", id); err != nil {
+		t.Fatalf("ack: %v", err)
+	}
+
+	pending, err := c.rdb.XPending(ctx, "search.requests", "search-service").Result()
+	if err != nil {
+		t.Fatalf("xpending: %v", err)
+	}
+	if pending.Count != 0 {
+		t.Errorf("expected no pending messages after ack, got %d", pending.Count)
+	}
+}
+
+func TestClient_SetWithTTLAndGet(t *testing.T) {
+	c, mr := newTestClient(t)
+	ctx := context.Background()
+
+	val, err := c.Get(ctx, "processed:req-1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if val != nil {
+		t.Fatalf("expected nil for missing key, got %v", val)
+	}
+
+	if err := c.SetWithTTL(ctx, "processed:req-1", "1", time.Hour); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+
+	val, err = c.Get(ctx, "processed:req-1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if val != "1" {
+		t.Errorf("expected value 1, got %v", val)
+	}
+
+	if ttl := mr.TTL("processed:req-1"); ttl != time.Hour {
+		t.Errorf("expected ttl 1h, got %v", ttl)
+	}
+
+	mr.FastForward(2 * time.Hour)
+
+	val, err = c.Get(ctx, "processed:req-1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if val != nil {
+		t.Errorf("expected key to expire, got %v", val)
+	}
+}
+
+func TestClient_ConsumerRoundTrip(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := context.Background()
+
+	consumer := streams.NewSearchRequestConsumer(c, "search-service", streams.WithConsumerName("replica-1"))
+	if err := c.EnsureGroup(ctx, "search.requests", "search-service"); err != nil {
+		t.Fatalf("ensure group: %v", err)
+	}
+
+	if _, err := c.XAdd(ctx, "search.requests", map[string]interface{}{
+		"request_id":     "req-1",
+		"correlation_id": "corr-1",
+		"chat_id":        "12345",
+		"params":         `{"origin":"MOW","destination":"PAR","depart_date":"2024-12-15","passengers":2}`,
+	}); err != nil {
+		t.Fatalf("xadd: %v", err)
+	}
+
+	request, err := consumer.Consume(ctx)
+	if err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+	if request.MessageID == "" {
+		t.Error("expected message id to be set")
+	}
+	if request.Params.Origin != "MOW" || request.Params.Passengers != 2 {
+		t.Errorf("unexpected params: %+v", request.Params)
+	}
+
+	// pending entry записан на консьюмера этого экземпляра
+	pending, err := c.rdb.XPending(ctx, "search.requests", "search-service").Result()
+	if err != nil {
+		t.Fatalf("xpending: %v", err)
+	}
+	if pending.Consumers["replica-1"] != 1 {
+		t.Errorf("expected entry pending for replica-1, got %v", pending.Consumers)
+	}
+
+	if err := consumer.Ack(ctx, request); err != nil {
+		t.Fatalf("ack: %v", err)
+	}
+}
+
+func TestClient_XAutoClaim_ReturnsIdleEntriesWithDeliveryCount(t *testing.T) {
+	c, mr := newTestClient(t)
+	ctx := context.Background()
+	now := time.Now()
+	mr.SetTime(now)
+
+	if err := c.EnsureGroup(ctx, "search.requests", "search-service"); err != nil {
+		t.Fatalf("ensure group: %v", err)
+	}
+	id, err := c.XAdd(ctx, "search.requests", map[string]interface{}{"request_id": "req-1"})
+	if err != nil {
+		t.Fatalf("xadd: %v", err)
+	}
+
+	// воркер прочитал сообщение и упал без ack
+	if _, err := c.XReadGroup(ctx, "search-service", "crashed-worker", "search.requests", 1); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	events, _, err := c.XAutoClaim(ctx, "search.requests", "search-service", "worker-2", time.Minute, "0-0", 10)
+	if err != nil {
+		t.Fatalf("autoclaim: %v", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("expected no entries before idle threshold, got %v", events)
+	}
+
+	mr.SetTime(now.Add(2 * time.Minute))
+
+	events, next, err := c.XAutoClaim(ctx, "search.requests", "search-service", "worker-2", time.Minute, "0-0", 10)
+	if err != nil {
+		t.Fatalf("autoclaim: %v", err)
+	}
+	if next != "0-0" {
+		t.Errorf("expected cursor 0-0, got %s", next)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected 1 reclaimed entry, got %d", len(events))
+	}
+	if events[0][streams.MessageIDField] != id {
+		t.Errorf("expected message id %s, got %v", id, events[0][streams.MessageIDField])
+	}
+	if events[0][streams.DeliveryCountField] != int64(2) {
+		t.Errorf("expected delivery count 2, got %v", events[0][streams.DeliveryCountField])
+	}
+}
+
+// Тест: сообщение, еще обрабатываемое тем же консьюмером между перехваченными,
+// не оставляет перехваченные без количества доставок
+func TestClient_XClaimJustID_ResetsIdle(t *testing.T) {
+	c, mr := newTestClient(t)
+	ctx := context.Background()
+	now := time.Now()
+	mr.SetTime(now)
+
+	if err := c.EnsureGroup(ctx, "search.requests", "search-service"); err != nil {
+		t.Fatalf("ensure group: %v", err)
+	}
+	id, err := c.XAdd(ctx, "search.requests", map[string]interface{}{"request_id": "req-1"})
+	if err != nil {
+		t.Fatalf("xadd: %v", err)
+	}
+	if _, err := c.XReadGroup(ctx, "search-service", "replica-1", "search.requests", 1); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	// запрос ждет воркера дольше minIdle, но пул сбрасывает его простой
+	mr.SetTime(now.Add(2 * time.Minute))
+	if err := c.XClaimJustID(ctx, "search.requests", "search-service", "replica-1", []string{id}); err != nil {
+		t.Fatalf("xclaim justid: %v", err)
+	}
+
+	events, _, err := c.XAutoClaim(ctx, "search.requests", "search-service", "replica-2", time.Minute, "0-0", 10)
+	if err != nil {
+		t.Fatalf("autoclaim: %v", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("expected touched entry not to be reclaimed, got %v", events)
+	}
+
+	pending, err := c.rdb.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: "search.requests",
+		Group:  "search-service",
+		Start:  "-",
+		End:    "+",
+		Count:  10,
+	}).Result()
+	if err != nil {
+		t.Fatalf("xpending: %v", err)
+	}
+	// счетчик доставок не проверяется: Redis не увеличивает его при JUSTID, а miniredis увеличивает
+	if len(pending) != 1 || pending[0].Consumer != "replica-1" {
+		t.Errorf("expected entry to stay with replica-1, got %+v", pending)
+	}
+}
+
+func TestClient_XAutoClaim_DeliveryCountWithInFlightEntryInRange(t *testing.T) {
+	c, mr := newTestClient(t)
+	ctx := context.Background()
+	now := time.Now()
+	mr.SetTime(now)
+
+	if err := c.EnsureGroup(ctx, "search.requests", "search-service"); err != nil {
+		t.Fatalf("ensure group: %v", err)
+	}
+	var ids []string
+	for _, requestID := range []string{"req-1", "req-2", "req-3"} {
+		id, err := c.XAdd(ctx, "search.requests", map[string]interface{}{"request_id": requestID})
+		if err != nil {
+			t.Fatalf("xadd: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	if _, err := c.XReadGroup(ctx, "search-service", "crashed-worker", "search.requests", 3); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	// второй запрос снова взят в работу и не простаивает
+	mr.SetTime(now.Add(2 * time.Minute))
+	if err := c.rdb.XClaim(ctx, &redis.XClaimArgs{
+		Stream:   "search.requests",
+		Group:    "search-service",
+		Consumer: "worker-2",
+		Messages: []string{ids[1]},
+	}).Err(); err != nil {
+		t.Fatalf("xclaim: %v", err)
+	}
+
+	events, _, err := c.XAutoClaim(ctx, "search.requests", "search-service", "worker-2", time.Minute, "0-0", 10)
+	if err != nil {
+		t.Fatalf("autoclaim: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 reclaimed entries, got %v", events)
+	}
+	for i, want := range []string{ids[0], ids[2]} {
+		if events[i][streams.MessageIDField] != want {
+			t.Errorf("entry %d: expected message id %s, got %v", i, want, events[i][streams.MessageIDField])
+		}
+		if events[i][streams.DeliveryCountField] != int64(2) {
+			t.Errorf("entry %d: expected delivery count 2, got %v", i, events[i][streams.DeliveryCountField])
+		}
+	}
+}
+
+func TestClient_DeadLetterQueueRoundTrip(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := context.Background()
+
+	dlq := streams.NewDeadLetterQueue(c, "search-service")
+	consumer := streams.NewSearchRequestConsumer(c, "search-service", streams.WithDeadLetterQueue(dlq))
+	if err := c.EnsureGroup(ctx, "search.requests", "search-service"); err != nil {
+		t.Fatalf("ensure group: %v", err)
+	}
+
+	if _, err := c.XAdd(ctx, "search.requests", map[string]interface{}{
+		"request_id": "req-1",
+		"params":     `{"origin":"MOW","destination":"PAR"}`,
+	}); err != nil {
+		t.Fatalf("xadd: %v", err)
+	}
+
+	if _, err := consumer.Consume(ctx); err == nil {
+		t.Fatal("expected missing chat_id error")
+	}
+
+	pending, err := c.rdb.XPending(ctx, "search.requests", "search-service").Result()
+	if err != nil {
+		t.Fatalf("xpending: %v", err)
+	}
+	if pending.Count != 0 {
+		t.Errorf("expected dead-lettered message to be acked, got %d pending", pending.Count)
+	}
+
+	letters, err := dlq.List(ctx, 10)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(letters) != 1 || letters[0].Reason != "missing chat_id" || letters[0].DeliveryCount != 1 {
+		t.Fatalf("unexpected dead letters: %+v", letters)
+	}
+
+	if _, err := dlq.Redrive(ctx, letters[0].ID); err != nil {
+		t.Fatalf("redrive: %v", err)
+	}
+
+	letters, err = dlq.List(ctx, 10)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(letters) != 0 {
+		t.Errorf("expected empty dlq after redrive, got %+v", letters)
+	}
+
+	length, err := c.rdb.XLen(ctx, "search.requests").Result()
+	if err != nil {
+		t.Fatalf("xlen: %v", err)
+	}
+	if length != 2 {
+		t.Errorf("expected redriven message in search.requests, got length %d", length)
+	}
+}
+
+func TestClient_CompareAndSet(t *testing.T) {
+	c, mr := newTestClient(t)
+	ctx := context.Background()
+
+	if _, err := c.SetNX(ctx, "processed:req-1", "processing:b", time.Minute); err != nil {
+		t.Fatalf("setnx: %v", err)
+	}
+
+	ok, err := c.CompareAndSet(ctx, "processed:req-1", "processing:a", "done", time.Hour)
+	if err != nil || ok {
+		t.Fatalf("expected foreign lease not to be overwritten, got %v %v", ok, err)
+	}
+	if v, _ := mr.Get("processed:req-1"); v != "processing:b" {
+		t.Errorf("expected foreign lease to stay, got %q", v)
+	}
+
+	ok, err = c.CompareAndSet(ctx, "processed:req-1", "processing:b", "done", time.Hour)
+	if err != nil || !ok {
+		t.Fatalf("expected own lease to be replaced, got %v %v", ok, err)
+	}
+	if v, _ := mr.Get("processed:req-1"); v != "done" {
+		t.Errorf("expected stored value, got %q", v)
+	}
+	if ttl := mr.TTL("processed:req-1"); ttl != time.Hour {
+		t.Errorf("expected ttl 1h, got %v", ttl)
+	}
+
+	// Истекший lease без нового владельца не мешает сохранить результат
+	ok, err = c.CompareAndSet(ctx, "processed:req-2", "processing:a", "done", time.Hour)
+	if err != nil || !ok {
+		t.Fatalf("expected missing key to be set, got %v %v", ok, err)
+	}
+}
+
+func TestClient_SetNXAndDelIfEqual(t *testing.T) {
+	c, mr := newTestClient(t)
+	ctx := context.Background()
+
+	ok, err := c.SetNX(ctx, "processed:req-1", "processing:a", time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("expected first SETNX to succeed, got %v %v", ok, err)
+	}
+
+	ok, err = c.SetNX(ctx, "processed:req-1", "processing:b", time.Minute)
+	if err != nil || ok {
+		t.Fatalf("expected second SETNX to fail, got %v %v", ok, err)
+	}
+
+	if ttl := mr.TTL("processed:req-1"); ttl != time.Minute {
+		t.Errorf("expected lease ttl 1m, got %v", ttl)
+	}
+
+	deleted, err := c.DelIfEqual(ctx, "processed:req-1", "processing:b")
+	if err != nil || deleted {
+		t.Fatalf("expected foreign lease not to be released, got %v %v", deleted, err)
+	}
+
+	deleted, err = c.DelIfEqual(ctx, "processed:req-1", "processing:a")
+	if err != nil || !deleted {
+		t.Fatalf("expected own lease to be released, got %v %v", deleted, err)
+	}
+	if mr.Exists("processed:req-1") {
+		t.Error("expected key to be deleted")
+	}
+}
@@ -0,0 +1,121 @@
+package httpiface
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"aviasales-bot/search-service/internal/streams"
+)
+
+// DeadLetterStore доступ к dead-letter stream запросов поиска
+type DeadLetterStore interface {
+	List(ctx context.Context, count int64) ([]streams.DeadLetter, error)
+	Redrive(ctx context.Context, id string) (string, error)
+}
+
+type adminHandler struct {
+	dlq    DeadLetterStore
+	token  string
+	logger loggerInterface
+}
+
+// NewAdminHandler создает handler админских endpoints /admin/*.
+// Запросы должны передавать token в заголовке X-Admin-Token; с пустым token
+// handler закрыт и отвечает 403 на любой запрос.
+func NewAdminHandler(dlq DeadLetterStore, token string, lg loggerInterface) http.Handler {
+	return &adminHandler{dlq: dlq, token: token, logger: lg}
+}
+
+func (h *adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.token == "" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	// сравнение за постоянное время не раскрывает совпавший префикс токена
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Admin-Token")), []byte(h.token)) != 1 {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/admin/dlq":
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		h.handleDLQList(w, r)
+	case "/admin/dlq/redrive":
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		h.handleDLQRedrive(w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// handleDLQList возвращает сообщения из DLQ: GET /admin/dlq?count=N
+func (h *adminHandler) handleDLQList(w http.ResponseWriter, r *http.Request) {
+	count := parseIntOrDefault(r.URL.Query().Get("count"), 50)
+
+	letters, err := h.dlq.List(r.Context(), int64(count))
+	if err != nil {
+		h.writeError(w, r, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"messages": letters,
+		"count":    len(letters),
+	})
+}
+
+// handleDLQRedrive возвращает сообщение из DLQ в search.requests: POST /admin/dlq/redrive?id=ID
+func (h *adminHandler) handleDLQRedrive(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		h.writeError(w, r, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	newID, err := h.dlq.Redrive(r.Context(), id)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	if h.logger != nil {
+		h.logger.Info("dlq_redrive", map[string]interface{}{
+			"dlq_id":     id,
+			"message_id": newID,
+			"ts":         time.Now().UTC().Format(time.RFC3339),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    true,
+		"message_id": newID,
+	})
+}
+
+func (h *adminHandler) writeError(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+	if h.logger != nil {
+		h.logger.Error("http_request", map[string]interface{}{
+			"path":    r.URL.Path,
+			"status":  status,
+			"success": false,
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package httpiface
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"aviasales-bot/search-service/internal/streams"
+)
+
+// mockDeadLetterStore реализует DeadLetterStore для тестов
+type mockDeadLetterStore struct {
+	letters   []streams.DeadLetter
+	redriven  string
+	shouldErr bool
+}
+
+func (m *mockDeadLetterStore) List(_ context.Context, count int64) ([]streams.DeadLetter, error) {
+	if m.shouldErr {
+		return nil, &mockError{"redis unavailable"}
+	}
+	return m.letters, nil
+}
+
+func (m *mockDeadLetterStore) Redrive(_ context.Context, id string) (string, error) {
+	if m.shouldErr {
+		return "", &mockError{"redis unavailable"}
+	}
+	m.redriven = id
+	return "2-0", nil
+}
+
+func TestAdminDLQ_List(t *testing.T) {
+	store := &mockDeadLetterStore{letters: []streams.DeadLetter{
+		{ID: "1-0", OriginalID: "0-1", Reason: "missing chat_id", DeliveryCount: 1},
+	}}
+	h := NewAdminHandler(store, "secret", nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/admin/dlq?count=10", nil)
+	r.Header.Set("X-Admin-Token", "secret")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: %d", w.Code)
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	if int(response["count"].(float64)) != 1 {
+		t.Errorf("expected count 1, got %v", response["count"])
+	}
+}
+
+// Без настроенного токена админские endpoints закрыты для всех
+func TestAdminDLQ_EmptyTokenForbidden(t *testing.T) {
+	store := &mockDeadLetterStore{}
+	h := NewAdminHandler(store, "", nil)
+
+	for _, r := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/admin/dlq", nil),
+		httptest.NewRequest(http.MethodPost, "/admin/dlq/redrive?id=1-0", nil),
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s %s: expected 403, got %d", r.Method, r.URL.Path, w.Code)
+		}
+	}
+	if store.redriven != "" {
+		t.Errorf("expected no redrive, got %q", store.redriven)
+	}
+}
+
+func TestAdminDLQ_Redrive(t *testing.T) {
+	store := &mockDeadLetterStore{}
+	lg := &testLogger{}
+	h := NewAdminHandler(store, "secret", lg)
+
+	r := httptest.NewRequest(http.MethodPost, "/admin/dlq/redrive?id=1-0", nil)
+	r.Header.Set("X-Admin-Token", "secret")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: %d", w.Code)
+	}
+	if store.redriven != "1-0" {
+		t.Errorf("expected 1-0 to be redriven, got %s", store.redriven)
+	}
+	if len(lg.entries) == 0 || lg.entries[0].event != "dlq_redrive" {
+		t.Errorf("expected dlq_redrive log entry, got %+v", lg.entries)
+	}
+}
+
+func TestAdminDLQ_Errors(t *testing.T) {
+	cases := []struct {
+		name   string
+		method string
+		target string
+		token  string
+		store  *mockDeadLetterStore
+		status int
+	}{
+		{"unauthorized", http.MethodGet, "/admin/dlq", "", &mockDeadLetterStore{}, http.StatusUnauthorized},
+		{"wrong token same length", http.MethodGet, "/admin/dlq", "secreT", &mockDeadLetterStore{}, http.StatusUnauthorized},
+		{"wrong token prefix", http.MethodGet, "/admin/dlq", "secret2", &mockDeadLetterStore{}, http.StatusUnauthorized},
+		{"list method", http.MethodPost, "/admin/dlq", "secret", &mockDeadLetterStore{}, http.StatusMethodNotAllowed},
+		{"redrive method", http.MethodGet, "/admin/dlq/redrive?id=1-0", "secret", &mockDeadLetterStore{}, http.StatusMethodNotAllowed},
+		{"redrive missing id", http.MethodPost, "/admin/dlq/redrive", "secret", &mockDeadLetterStore{}, http.StatusBadRequest},
+		{"list store error", http.MethodGet, "/admin/dlq", "secret", &mockDeadLetterStore{shouldErr: true}, http.StatusBadGateway},
+		{"redrive store error", http.MethodPost, "/admin/dlq/redrive?id=1-0", "secret", &mockDeadLetterStore{shouldErr: true}, http.StatusBadGateway},
+		{"unknown path", http.MethodGet, "/admin/unknown", "secret", &mockDeadLetterStore{}, http.StatusNotFound},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewAdminHandler(tc.store, "secret", &testLogger{})
+			r := httptest.NewRequest(tc.method, tc.target, nil)
+			if tc.token != "" {
+				r.Header.Set("X-Admin-Token", tc.token)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Errorf("expected status %d, got %d", tc.status, w.Code)
+			}
+		})
+	}
+}
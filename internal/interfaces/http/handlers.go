@@ -1,9 +1,12 @@
 package httpiface
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	app "aviasales-bot/search-service/internal/application"
@@ -51,6 +54,22 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		h.handleFlightSearch(w, r)
 	case "/flights/message":
 		h.handleFlightMessage(w, r)
+	case "/flights/calendar":
+		h.handleFlightCalendar(w, r)
+	case "/flights/week-matrix":
+		h.handleFlightMatrix(w, r, h.fs.SearchWeekMatrix)
+	case "/flights/month-matrix":
+		h.handleFlightMatrix(w, r, h.fs.SearchMonthMatrix)
+	case "/flights/grouped":
+		h.handleFlightGrouped(w, r)
+	case "/flights/latest":
+		h.handleFlightLatest(w, r)
+	case "/flights/airline":
+		h.handleFlightAirline(w, r)
+	case "/deals":
+		h.handleDeals(w, r)
+	case "/directions/popular":
+		h.handlePopularDirections(w, r)
 	default:
 		w.WriteHeader(http.StatusNotFound)
 	}
@@ -69,13 +88,28 @@ func (h *handler) handleFlightSearch(w http.ResponseWriter, r *http.Request) {
 		ReturnDate:  q.Get("return_date"),
 		Currency:    coalesce(q.Get("currency"), "rub"),
 		Limit:       parseIntOrDefault(q.Get("limit"), 10),
+		Sort:        q.Get("sort"),
+		FlexDays:    parseIntOrDefault(q.Get("flex_days"), 0),
+
+		ExpandAirports: q.Get("expand_airports") == "true",
+
+		API:     coalesce(q.Get("api"), app.PricesAPIV1),
+		OneWay:  q.Get("one_way") == "true",
+		Sorting: q.Get("sorting"),
+		Unique:  q.Get("unique") == "true",
+		Page:    parseIntOrDefault(q.Get("page"), 0),
+	}
+
+	// Направление необязательно: без него ищем самый дешевый билет в каждый город
+	if app.IsAnywhere(p.Destination) {
+		p.Destination = app.AnyDestination
 	}
 
 	// Валидация обязательных параметров
-	if p.Origin == "" || p.Destination == "" || p.DepartDate == "" {
+	if p.Origin == "" || p.DepartDate == "" {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{
-			"error": "origin, destination and depart_date are required",
+			"error": "origin and depart_date are required",
 		})
 		if h.logger != nil {
 			durMs := time.Since(start).Milliseconds()
@@ -92,8 +126,19 @@ func (h *handler) handleFlightSearch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if msg := validatePricesOptions(p); msg != "" {
+		h.writeError(w, r, start, http.StatusBadRequest, msg)
+		return
+	}
+	filter, msg := parseFilter(q)
+	if msg != "" {
+		h.writeError(w, r, start, http.StatusBadRequest, msg)
+		return
+	}
+	p.Filter = filter
+
 	ctx := r.Context()
-	flights, err := h.fs.SearchCheap(ctx, p)
+	flights, extra, err := h.search(ctx, p, q.Get("direct") == "true")
 	if err != nil {
 		w.WriteHeader(http.StatusBadGateway)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -116,11 +161,11 @@ func (h *handler) handleFlightSearch(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+	_ = json.NewEncoder(w).Encode(withExtra(map[string]interface{}{
 		"success": true,
 		"flights": flights,
 		"count":   len(flights),
-	})
+	}, extra))
 	if h.logger != nil {
 		durMs := time.Since(start).Milliseconds()
 		if durMs == 0 {
@@ -149,6 +194,21 @@ func (h *handler) handleFlightMessage(w http.ResponseWriter, r *http.Request) {
 		ReturnDate:  q.Get("return_date"),
 		Currency:    coalesce(q.Get("currency"), "rub"),
 		Limit:       parseIntOrDefault(q.Get("limit"), 3),
+		Sort:        q.Get("sort"),
+		FlexDays:    parseIntOrDefault(q.Get("flex_days"), 0),
+
+		ExpandAirports: q.Get("expand_airports") == "true",
+
+		API:     coalesce(q.Get("api"), app.PricesAPIV1),
+		OneWay:  q.Get("one_way") == "true",
+		Sorting: q.Get("sorting"),
+		Unique:  q.Get("unique") == "true",
+		Page:    parseIntOrDefault(q.Get("page"), 0),
+	}
+
+	// Направление необязательно: без него ищем самый дешевый билет в каждый город
+	if app.IsAnywhere(p.Destination) {
+		p.Destination = app.AnyDestination
 	}
 
 	originCity := coalesce(q.Get("origin_city"), p.Origin)
@@ -156,10 +216,10 @@ func (h *handler) handleFlightMessage(w http.ResponseWriter, r *http.Request) {
 	passengers := parseIntOrDefault(q.Get("passengers"), 1)
 
 	// Валидация обязательных параметров
-	if p.Origin == "" || p.Destination == "" || p.DepartDate == "" {
+	if p.Origin == "" || p.DepartDate == "" {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{
-			"error": "origin, destination and depart_date are required",
+			"error": "origin and depart_date are required",
 		})
 		if h.logger != nil {
 			durMs := time.Since(start).Milliseconds()
@@ -176,8 +236,19 @@ func (h *handler) handleFlightMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if msg := validatePricesOptions(p); msg != "" {
+		h.writeError(w, r, start, http.StatusBadRequest, msg)
+		return
+	}
+	filter, msg := parseFilter(q)
+	if msg != "" {
+		h.writeError(w, r, start, http.StatusBadRequest, msg)
+		return
+	}
+	p.Filter = filter
+
 	ctx := r.Context()
-	flights, err := h.fs.SearchCheap(ctx, p)
+	flights, extra, err := h.search(ctx, p, q.Get("direct") == "true")
 	if err != nil {
 		w.WriteHeader(http.StatusBadGateway)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -198,17 +269,22 @@ func (h *handler) handleFlightMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	message := h.fs.FormatFlightMessage(originCity, destCity, flights, passengers)
+	// Название города отправления берем из найденных рейсов, если его не передали
+	if q.Get("origin_city") == "" && len(flights) > 0 && flights[0].OriginName != "" {
+		originCity = flights[0].OriginName
+	}
+
+	message := h.fs.FormatFlightMessage(originCity, destCity, flights, passengers, p.Currency)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+	_ = json.NewEncoder(w).Encode(withExtra(map[string]interface{}{
 		"success":    true,
 		"message":    message,
 		"flights":    flights,
 		"count":      len(flights),
 		"passengers": passengers,
-	})
+	}, extra))
 	if h.logger != nil {
 		durMs := time.Since(start).Milliseconds()
 		if durMs == 0 {
@@ -224,6 +300,496 @@ func (h *handler) handleFlightMessage(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleFlightCalendar обрабатывает запросы календаря цен /flights/calendar
+func (h *handler) handleFlightCalendar(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	q := r.URL.Query()
+
+	// Парсим параметры запроса
+	p := app.CalendarParams{
+		Origin:       q.Get("origin"),
+		Destination:  q.Get("destination"),
+		Month:        q.Get("depart_date"),
+		ReturnMonth:  q.Get("return_date"),
+		CalendarType: coalesce(q.Get("calendar_type"), "departure_date"),
+		Length:       parseIntOrDefault(q.Get("length"), 0),
+		Currency:     coalesce(q.Get("currency"), "rub"),
+	}
+
+	originCity := coalesce(q.Get("origin_city"), p.Origin)
+	destCity := coalesce(q.Get("dest_city"), p.Destination)
+
+	// Валидация обязательных параметров
+	if p.Origin == "" || p.Destination == "" || p.Month == "" {
+		h.writeError(w, r, start, http.StatusBadRequest, "origin, destination and depart_date are required")
+		return
+	}
+	if p.CalendarType != "departure_date" && p.CalendarType != "return_date" {
+		h.writeError(w, r, start, http.StatusBadRequest, "calendar_type must be departure_date or return_date")
+		return
+	}
+
+	cal, err := h.fs.SearchCalendar(r.Context(), p)
+	if err != nil {
+		h.writeError(w, r, start, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	message := h.fs.FormatCalendarMessage(originCity, destCity, cal)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"calendar": cal,
+		"message":  message,
+		"count":    len(cal.Days),
+	})
+	h.logSuccess(r, start, len(cal.Days))
+}
+
+// handleFlightMatrix обрабатывает запросы матриц цен /flights/week-matrix и /flights/month-matrix
+func (h *handler) handleFlightMatrix(w http.ResponseWriter, r *http.Request, search func(context.Context, app.MatrixParams) (*app.PriceMatrix, error)) {
+	start := time.Now()
+	q := r.URL.Query()
+
+	// Парсим параметры запроса
+	p := app.MatrixParams{
+		Origin:      q.Get("origin"),
+		Destination: q.Get("destination"),
+		DepartDate:  q.Get("depart_date"),
+		ReturnDate:  q.Get("return_date"),
+		Currency:    coalesce(q.Get("currency"), "rub"),
+	}
+
+	// Валидация обязательных параметров
+	if p.Origin == "" || p.Destination == "" || p.DepartDate == "" {
+		h.writeError(w, r, start, http.StatusBadRequest, "origin, destination and depart_date are required")
+		return
+	}
+
+	matrix, err := search(r.Context(), p)
+	if err != nil {
+		h.writeError(w, r, start, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"matrix":  matrix,
+		"count":   len(matrix.Flights),
+	})
+	h.logSuccess(r, start, len(matrix.Flights))
+}
+
+// handleFlightGrouped обрабатывает запросы лучших цен по периодам /flights/grouped
+func (h *handler) handleFlightGrouped(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	q := r.URL.Query()
+
+	// Парсим параметры запроса
+	p := app.GroupedParams{
+		Origin:      q.Get("origin"),
+		Destination: q.Get("destination"),
+		GroupBy:     coalesce(q.Get("group_by"), "month"),
+		DepartureAt: q.Get("depart_date"),
+		ReturnAt:    q.Get("return_date"),
+		Direct:      q.Get("direct") == "true",
+		Currency:    coalesce(q.Get("currency"), "rub"),
+	}
+
+	originCity := coalesce(q.Get("origin_city"), p.Origin)
+	destCity := coalesce(q.Get("dest_city"), p.Destination)
+
+	// Валидация обязательных параметров
+	if p.Origin == "" || p.Destination == "" {
+		h.writeError(w, r, start, http.StatusBadRequest, "origin and destination are required")
+		return
+	}
+	if p.GroupBy != "month" && p.GroupBy != "departure_at" {
+		h.writeError(w, r, start, http.StatusBadRequest, "group_by must be month or departure_at")
+		return
+	}
+
+	grouped, err := h.fs.SearchGroupedPrices(r.Context(), p)
+	if err != nil {
+		h.writeError(w, r, start, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	message := h.fs.FormatGroupedMessage(originCity, destCity, grouped)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"grouped": grouped,
+		"message": message,
+		"count":   len(grouped.Periods),
+	})
+	h.logSuccess(r, start, len(grouped.Periods))
+}
+
+// handleFlightLatest обрабатывает запросы ленты последних цен /flights/latest
+func (h *handler) handleFlightLatest(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	q := r.URL.Query()
+
+	// Парсим параметры запроса; destination необязателен — поиск «куда угодно»
+	p := app.LatestParams{
+		Origin:            q.Get("origin"),
+		Destination:       q.Get("destination"),
+		Currency:          coalesce(q.Get("currency"), "rub"),
+		PeriodType:        coalesce(q.Get("period_type"), "year"),
+		BeginningOfPeriod: q.Get("beginning_of_period"),
+		OneWay:            q.Get("one_way") == "true",
+		Page:              parseIntOrDefault(q.Get("page"), 1),
+		Limit:             parseIntOrDefault(q.Get("limit"), 30),
+		ShowToAffiliates:  q.Get("show_to_affiliates") != "false",
+		Sorting:           coalesce(q.Get("sorting"), "price"),
+		TripClass:         parseIntOrDefault(q.Get("trip_class"), 0),
+	}
+
+	// Валидация параметров
+	switch {
+	case p.Origin == "":
+		h.writeError(w, r, start, http.StatusBadRequest, "origin is required")
+		return
+	case p.PeriodType != "year" && p.PeriodType != "month":
+		h.writeError(w, r, start, http.StatusBadRequest, "period_type must be year or month")
+		return
+	case p.PeriodType == "month" && p.BeginningOfPeriod == "":
+		h.writeError(w, r, start, http.StatusBadRequest, "beginning_of_period is required for period_type=month")
+		return
+	case p.Sorting != "price" && p.Sorting != "route" && p.Sorting != "distance_unit_price":
+		h.writeError(w, r, start, http.StatusBadRequest, "sorting must be price, route or distance_unit_price")
+		return
+	case p.TripClass < 0 || p.TripClass > 2:
+		h.writeError(w, r, start, http.StatusBadRequest, "trip_class must be 0, 1 or 2")
+		return
+	case p.Page < 1 || p.Limit < 1 || p.Limit > 1000:
+		h.writeError(w, r, start, http.StatusBadRequest, "page must be positive and limit between 1 and 1000")
+		return
+	}
+
+	flights, err := h.fs.SearchLatest(r.Context(), p)
+	if err != nil {
+		h.writeError(w, r, start, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	// Полная страница означает, что следующая может быть непустой
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"flights":  flights,
+		"count":    len(flights),
+		"page":     p.Page,
+		"limit":    p.Limit,
+		"has_more": len(flights) == p.Limit,
+	})
+	h.logSuccess(r, start, len(flights))
+}
+
+// handleFlightAirline обрабатывает запросы маршрутов авиакомпании /flights/airline
+func (h *handler) handleFlightAirline(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	q := r.URL.Query()
+
+	// Парсим параметры запроса
+	p := app.AirlineParams{
+		Airline:    strings.ToUpper(q.Get("airline")),
+		Origin:     strings.ToUpper(q.Get("origin")),
+		DepartDate: q.Get("depart_date"),
+		Currency:   coalesce(q.Get("currency"), "rub"),
+		Limit:      parseIntOrDefault(q.Get("limit"), 10),
+	}
+	passengers := parseIntOrDefault(q.Get("passengers"), 1)
+
+	p.Limit = max(1, min(p.Limit, app.MaxAirlineRoutes))
+
+	// Валидация обязательных параметров
+	if len(p.Airline) != 2 {
+		h.writeError(w, r, start, http.StatusBadRequest, "airline must be a 2-letter IATA code")
+		return
+	}
+
+	flights, err := h.fs.SearchAirlineRoutes(r.Context(), p)
+	if err != nil {
+		h.writeError(w, r, start, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	routes := make([]direction, 0, len(flights))
+	for _, flight := range flights {
+		routes = append(routes, direction{Flight: flight, Link: h.fs.GeneratePartnerLink(flight, passengers)})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"airline": p.Airline,
+		"routes":  routes,
+		"count":   len(routes),
+	})
+	h.logSuccess(r, start, len(routes))
+}
+
+// direction маршрут с партнерской ссылкой
+type direction struct {
+	app.Flight
+	Link string `json:"link"`
+}
+
+// handlePopularDirections обрабатывает запросы популярных направлений /directions/popular
+func (h *handler) handlePopularDirections(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	q := r.URL.Query()
+
+	origin := q.Get("origin")
+	currency := coalesce(q.Get("currency"), "rub")
+	limit := parseIntOrDefault(q.Get("limit"), 10)
+	passengers := parseIntOrDefault(q.Get("passengers"), 1)
+
+	// Валидация обязательных параметров
+	if origin == "" {
+		h.writeError(w, r, start, http.StatusBadRequest, "origin is required")
+		return
+	}
+
+	flights, err := h.fs.PopularDirections(r.Context(), origin, currency)
+	if err != nil {
+		h.writeError(w, r, start, http.StatusBadGateway, err.Error())
+		return
+	}
+	if limit > 0 && len(flights) > limit {
+		flights = flights[:limit]
+	}
+
+	directions := make([]direction, 0, len(flights))
+	for _, flight := range flights {
+		directions = append(directions, direction{Flight: flight, Link: h.fs.GeneratePartnerLink(flight, passengers)})
+	}
+
+	originCity := q.Get("origin_city")
+	if originCity == "" && len(flights) > 0 {
+		originCity = flights[0].OriginName
+	}
+	message := h.fs.FormatFlightMessage(coalesce(originCity, origin), app.AnyDestination, flights, passengers, currency)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    true,
+		"directions": directions,
+		"message":    message,
+		"count":      len(directions),
+	})
+	h.logSuccess(r, start, len(directions))
+}
+
+// handleDeals обрабатывает запросы спецпредложений /deals
+func (h *handler) handleDeals(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	q := r.URL.Query()
+
+	origin := q.Get("origin")
+	limit := parseIntOrDefault(q.Get("limit"), 20)
+
+	offers, err := h.fs.SpecialOffers(r.Context())
+	if err != nil {
+		h.writeError(w, r, start, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	// Необязательный фильтр по городу вылета
+	if origin != "" {
+		filtered := make([]app.SpecialOffer, 0, len(offers))
+		for _, offer := range offers {
+			if offer.Origin == origin {
+				filtered = append(filtered, offer)
+			}
+		}
+		offers = filtered
+	}
+	if limit > 0 && len(offers) > limit {
+		offers = offers[:limit]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"offers":  offers,
+		"message": h.fs.FormatOffersMessage(offers),
+		"count":   len(offers),
+	})
+	h.logSuccess(r, start, len(offers))
+}
+
+// writeError отвечает JSON ошибкой и логирует неуспешный запрос
+func (h *handler) writeError(w http.ResponseWriter, r *http.Request, start time.Time, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+	if h.logger != nil {
+		h.logger.Error("http_request", map[string]interface{}{
+			"path":        r.URL.Path,
+			"status":      status,
+			"success":     false,
+			"duration_ms": durationMs(start),
+		})
+	}
+}
+
+// logSuccess логирует успешный запрос
+func (h *handler) logSuccess(r *http.Request, start time.Time, count int) {
+	if h.logger != nil {
+		h.logger.Info("http_request", map[string]interface{}{
+			"path":        r.URL.Path,
+			"status":      http.StatusOK,
+			"success":     true,
+			"count":       count,
+			"duration_ms": durationMs(start),
+		})
+	}
+}
+
+// durationMs возвращает длительность запроса в миллисекундах (не меньше 1)
+func durationMs(start time.Time) int64 {
+	durMs := time.Since(start).Milliseconds()
+	if durMs == 0 {
+		durMs = 1
+	}
+	return durMs
+}
+
+// validatePricesOptions проверяет выбор версии API цен и опции v3; возвращает текст ошибки
+func validatePricesOptions(p app.SearchParams) string {
+	switch {
+	case p.API != app.PricesAPIV1 && p.API != app.PricesAPIV3:
+		return "api must be v1 or v3"
+	case p.Sorting != "" && p.Sorting != "price" && p.Sorting != "route":
+		return "sorting must be price or route"
+	case p.Page < 0:
+		return "page must be positive"
+	case !app.ValidSort(p.Sort):
+		return "sort must be price, departure, duration or transfers"
+	}
+	if p.FlexDays != 0 {
+		if err := app.ValidateFlex(p); err != nil {
+			return err.Error()
+		}
+		if app.IsMultiRoute(p) {
+			return "flex_days cannot be combined with several origins, destinations or expand_airports"
+		}
+	}
+	if err := app.ValidateRoutes(p); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// parseFilter читает ограничения на найденные билеты; возвращает текст ошибки.
+// airlines и exclude_airlines — списки IATA кодов через запятую, max_duration — в минутах,
+// depart_hours и return_hours — интервалы часов вида 18-24.
+func parseFilter(q url.Values) (app.Filter, string) {
+	f := app.Filter{
+		Airlines:        app.ParseCodes(q.Get("airlines")),
+		ExcludeAirlines: app.ParseCodes(q.Get("exclude_airlines")),
+	}
+
+	for _, field := range []struct {
+		name string
+		dst  *int
+	}{
+		{"min_price", &f.MinPrice},
+		{"max_price", &f.MaxPrice},
+		{"max_duration", &f.MaxDuration},
+	} {
+		if s := q.Get(field.name); s != "" {
+			v, err := strconv.Atoi(s)
+			if err != nil {
+				return f, field.name + " must be a number"
+			}
+			*field.dst = v
+		}
+	}
+	if s := q.Get("max_transfers"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			return f, "max_transfers must be a number"
+		}
+		f.MaxTransfers = &v
+	}
+
+	for _, field := range []struct {
+		name string
+		dst  **app.HourWindow
+	}{
+		{"depart_hours", &f.DepartHours},
+		{"return_hours", &f.ReturnHours},
+	} {
+		if s := q.Get(field.name); s != "" {
+			window, err := app.ParseHourWindow(s)
+			if err != nil {
+				return f, field.name + ": " + err.Error()
+			}
+			*field.dst = window
+		}
+	}
+
+	if err := f.Validate(); err != nil {
+		return f, err.Error()
+	}
+	return f, ""
+}
+
+// search ищет самые дешевые билеты; direct ограничивает поиск прямыми рейсами.
+// С FlexDays ищет на окно дат вокруг даты вылета, а с несколькими городами или ExpandAirports —
+// по всем маршрутам сразу; подробности таких поисков возвращаются в extra для ответа.
+func (h *handler) search(ctx context.Context, p app.SearchParams, direct bool) (flights []app.Flight, extra map[string]interface{}, err error) {
+	switch {
+	case p.FlexDays > 0:
+		flex, err := app.NewFlexSearcher(h.fs).Search(ctx, p, direct)
+		if err != nil {
+			return nil, nil, err
+		}
+		extra = map[string]interface{}{"days": flex.Days, "best": flex.Best}
+		if len(flex.Errors) > 0 {
+			extra["errors"] = flex.Errors
+		}
+		return flex.Flights, extra, nil
+	case app.IsMultiRoute(p):
+		multi, err := app.NewMultiSearcher(h.fs, app.WithAirportResolver(h.fs)).Search(ctx, p, direct)
+		if err != nil {
+			return nil, nil, err
+		}
+		extra = map[string]interface{}{"routes": multi.Routes}
+		if len(multi.Errors) > 0 {
+			extra["errors"] = multi.Errors
+		}
+		return multi.Flights, extra, nil
+	case direct:
+		flights, err = h.fs.SearchDirect(ctx, p)
+	default:
+		flights, err = h.fs.SearchCheap(ctx, p)
+	}
+	return flights, nil, err
+}
+
+// withExtra добавляет в ответ подробности поиска с гибкими датами или по нескольким маршрутам
+func withExtra(resp, extra map[string]interface{}) map[string]interface{} {
+	for k, v := range extra {
+		resp[k] = v
+	}
+	return resp
+}
+
 func coalesce(a, b string) string {
 	if a != "" {
 		return a
@@ -36,7 +36,8 @@ func (t *incomingRequestTestLogger) clear() {
 	t.events = nil
 }
 
-type incomingRequestMockFlightSearcher struct{}
+// incomingRequestMockFlightSearcher переопределяет поиск; остальные методы берет из mockFlightSearcher
+type incomingRequestMockFlightSearcher struct{ mockFlightSearcher }
 
 func (m *incomingRequestMockFlightSearcher) SearchCheap(_ context.Context, p app.SearchParams) ([]app.Flight, error) {
 	return []app.Flight{
@@ -52,11 +53,15 @@ func (m *incomingRequestMockFlightSearcher) SearchCheap(_ context.Context, p app
 	}, nil
 }
 
+func (m *incomingRequestMockFlightSearcher) SearchDirect(ctx context.Context, p app.SearchParams) ([]app.Flight, error) {
+	return m.SearchCheap(ctx, p)
+}
+
 func (m *incomingRequestMockFlightSearcher) GeneratePartnerLink(flight app.Flight, passengers int) string {
 	return "https://test.com"
 }
 
-func (m *incomingRequestMockFlightSearcher) FormatFlightMessage(originCity, destCity string, flights []app.Flight, passengers int) string {
+func (m *incomingRequestMockFlightSearcher) FormatFlightMessage(originCity, destCity string, flights []app.Flight, passengers int, currency string) string {
 	return "Test message"
 }
 
@@ -247,16 +252,20 @@ func TestHandleFlightMessage_LogsUpstreamError(t *testing.T) {
 	}
 }
 
-type incomingRequestMockFlightSearcherWithError struct{}
+type incomingRequestMockFlightSearcherWithError struct{ mockFlightSearcher }
 
 func (m *incomingRequestMockFlightSearcherWithError) SearchCheap(_ context.Context, p app.SearchParams) ([]app.Flight, error) {
 	return nil, errors.New("upstream error")
 }
 
+func (m *incomingRequestMockFlightSearcherWithError) SearchDirect(_ context.Context, p app.SearchParams) ([]app.Flight, error) {
+	return nil, errors.New("upstream error")
+}
+
 func (m *incomingRequestMockFlightSearcherWithError) GeneratePartnerLink(flight app.Flight, passengers int) string {
 	return "https://test.com"
 }
 
-func (m *incomingRequestMockFlightSearcherWithError) FormatFlightMessage(originCity, destCity string, flights []app.Flight, passengers int) string {
+func (m *incomingRequestMockFlightSearcherWithError) FormatFlightMessage(originCity, destCity string, flights []app.Flight, passengers int, currency string) string {
 	return "Test message"
 }
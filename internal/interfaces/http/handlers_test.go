@@ -3,9 +3,11 @@ package httpiface
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"sync"
 	"testing"
 	"time"
 
@@ -14,18 +16,40 @@ import (
 
 // mockFlightSearcher реализует FlightSearcher интерфейс для тестов
 type mockFlightSearcher struct {
+	mu          sync.Mutex // поиск с гибкими датами вызывает мок параллельно
 	calledWith  app.SearchParams
+	direct      bool // последний поиск шел через SearchDirect
 	shouldError bool
 	mockFlights []app.Flight
 	mockMessage string
 	mockLink    string
+	messageFor  string // originCity → destCity последнего FormatFlightMessage
+	perRoute    bool     // отдавать билеты по маршруту запроса, как Data API для каждой пары городов
+	routes      []string // маршруты всех поисков
+
+	calendarWith app.CalendarParams
+	matrixWith   app.MatrixParams
+	matrixKind   string
+	latestWith   app.LatestParams
+	airlineWith  app.AirlineParams
+	groupedWith  app.GroupedParams
 }
 
 func (m *mockFlightSearcher) SearchCheap(_ context.Context, p app.SearchParams) ([]app.Flight, error) {
+	m.mu.Lock()
 	m.calledWith = p
+	m.routes = append(m.routes, p.Origin+"-"+p.Destination)
+	m.mu.Unlock()
 	if m.shouldError {
 		return nil, &mockError{"search failed"}
 	}
+	if m.perRoute {
+		if p.Origin == "VKO" {
+			return nil, &mockError{"upstream timeout"}
+		}
+		price := map[string]int{"SVO-IST": 14000, "SVO-AYT": 12000, "DME-IST": 9500, "DME-AYT": 16000}[p.Origin+"-"+p.Destination]
+		return []app.Flight{{Origin: p.Origin, Destination: p.Destination, Price: price, Airline: "SU"}}, nil
+	}
 	if len(m.mockFlights) > 0 {
 		return m.mockFlights, nil
 	}
@@ -52,6 +76,14 @@ func (m *mockFlightSearcher) SearchCheap(_ context.Context, p app.SearchParams)
 	}, nil
 }
 
+func (m *mockFlightSearcher) SearchDirect(ctx context.Context, p app.SearchParams) ([]app.Flight, error) {
+	flights, err := m.SearchCheap(ctx, p)
+	m.mu.Lock()
+	m.direct = true
+	m.mu.Unlock()
+	return flights, err
+}
+
 func (m *mockFlightSearcher) GeneratePartnerLink(flight app.Flight, passengers int) string {
 	if m.mockLink != "" {
 		return m.mockLink
@@ -59,13 +91,108 @@ func (m *mockFlightSearcher) GeneratePartnerLink(flight app.Flight, passengers i
 	return "https://www.aviasales.com/search/MOW1512PAR2212?marker=668475&passengers=2"
 }
 
-func (m *mockFlightSearcher) FormatFlightMessage(originCity, destCity string, flights []app.Flight, passengers int) string {
+func (m *mockFlightSearcher) FormatFlightMessage(originCity, destCity string, flights []app.Flight, passengers int, currency string) string {
+	m.messageFor = originCity + " → " + destCity
 	if m.mockMessage != "" {
 		return m.mockMessage
 	}
 	return "✈️ <b>Москва → Париж</b>\n\n🎫 <b>15 000 ₽</b>\n📅 15 дек → 22 дек\n🛫 SU • 3ч 35м\n🔗 <a href=\"https://www.aviasales.com/search/MOW1512PAR2212?marker=668475&passengers=2\">Купить билет</a>"
 }
 
+func (m *mockFlightSearcher) SearchCalendar(_ context.Context, p app.CalendarParams) (*app.PriceCalendar, error) {
+	m.calendarWith = p
+	if m.shouldError {
+		return nil, &mockError{"calendar failed"}
+	}
+	return &app.PriceCalendar{
+		Origin:       p.Origin,
+		Destination:  p.Destination,
+		Month:        "2024-12",
+		CalendarType: p.CalendarType,
+		Currency:     "rub",
+		Days: map[string]app.Flight{
+			"2024-12-01": {Origin: p.Origin, Destination: p.Destination, Price: 21000},
+			"2024-12-05": {Origin: p.Origin, Destination: p.Destination, Price: 12400},
+		},
+	}, nil
+}
+
+func (m *mockFlightSearcher) FormatCalendarMessage(originCity, destCity string, cal *app.PriceCalendar) string {
+	return "📅 <b>" + originCity + " → " + destCity + "</b>"
+}
+
+func (m *mockFlightSearcher) SearchWeekMatrix(ctx context.Context, p app.MatrixParams) (*app.PriceMatrix, error) {
+	m.matrixKind = "week"
+	return m.matrix(ctx, p)
+}
+
+func (m *mockFlightSearcher) SearchMonthMatrix(ctx context.Context, p app.MatrixParams) (*app.PriceMatrix, error) {
+	m.matrixKind = "month"
+	return m.matrix(ctx, p)
+}
+
+func (m *mockFlightSearcher) matrix(ctx context.Context, p app.MatrixParams) (*app.PriceMatrix, error) {
+	m.matrixWith = p
+	flights, err := m.SearchCheap(ctx, app.SearchParams{Origin: p.Origin, Destination: p.Destination})
+	if err != nil {
+		return nil, err
+	}
+	return &app.PriceMatrix{Origin: p.Origin, Destination: p.Destination, Currency: p.Currency, Flights: flights}, nil
+}
+
+func (m *mockFlightSearcher) SearchLatest(ctx context.Context, p app.LatestParams) ([]app.Flight, error) {
+	m.latestWith = p
+	return m.SearchCheap(ctx, app.SearchParams{Origin: p.Origin, Destination: p.Destination})
+}
+
+func (m *mockFlightSearcher) CityAirports(_ context.Context, code string) ([]string, error) {
+	if code == "MOW" {
+		return []string{"DME", "SVO", "VKO"}, nil
+	}
+	return []string{code}, nil
+}
+
+func (m *mockFlightSearcher) PopularDirections(ctx context.Context, origin, currency string) ([]app.Flight, error) {
+	return m.SearchCheap(ctx, app.SearchParams{Origin: origin, Destination: app.AnyDestination, Currency: currency})
+}
+
+func (m *mockFlightSearcher) SearchAirlineRoutes(ctx context.Context, p app.AirlineParams) ([]app.Flight, error) {
+	m.airlineWith = p
+	return m.SearchCheap(ctx, app.SearchParams{Origin: p.Origin, Destination: app.AnyDestination, Currency: p.Currency})
+}
+
+func (m *mockFlightSearcher) SpecialOffers(_ context.Context) ([]app.SpecialOffer, error) {
+	if m.shouldError {
+		return nil, &mockError{"offers failed"}
+	}
+	return []app.SpecialOffer{
+		{Airline: "Победа", AirlineCode: "DP", Origin: "MOW", Destination: "AER", Price: 2999, Link: "https://www.aviasales.ru/offers/dp"},
+		{Airline: "S7", AirlineCode: "S7", Origin: "OVB", Destination: "MOW", Price: 4500, Link: "https://www.aviasales.ru/offers/s7"},
+		{Airline: "Аэрофлот", AirlineCode: "SU", Origin: "MOW", Destination: "LED", Price: 5200, Link: "https://www.aviasales.ru/offers/su"},
+	}, nil
+}
+
+func (m *mockFlightSearcher) FormatOffersMessage(offers []app.SpecialOffer) string {
+	return fmt.Sprintf("offers: %d", len(offers))
+}
+
+func (m *mockFlightSearcher) SearchGroupedPrices(ctx context.Context, p app.GroupedParams) (*app.GroupedPrices, error) {
+	m.groupedWith = p
+	flights, err := m.SearchCheap(ctx, app.SearchParams{Origin: p.Origin, Destination: p.Destination})
+	if err != nil {
+		return nil, err
+	}
+	periods := make(map[string]app.Flight, len(flights))
+	for _, flight := range flights {
+		periods[flight.DepartDate.Format("2006-01-02")] = flight
+	}
+	return &app.GroupedPrices{Origin: p.Origin, Destination: p.Destination, GroupBy: p.GroupBy, Currency: p.Currency, Periods: periods}, nil
+}
+
+func (m *mockFlightSearcher) FormatGroupedMessage(originCity, destCity string, g *app.GroupedPrices) string {
+	return fmt.Sprintf("%s → %s: %d", originCity, destCity, len(g.Periods))
+}
+
 type mockError struct{ msg string }
 
 func (e *mockError) Error() string { return e.msg }
@@ -104,6 +231,29 @@ func TestFlightSearch_ReturnsFlights(t *testing.T) {
 	}
 }
 
+func TestFlightSearch_DirectUsesDirectSearch(t *testing.T) {
+	flightSearcher := &mockFlightSearcher{}
+	h := NewHandler(flightSearcher)
+
+	r := httptest.NewRequest(http.MethodGet, "/flights/search?origin=MOW&destination=PAR&depart_date=2024-12&direct=true", nil)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+	if w.Code != 200 {
+		t.Fatalf("status: %d", w.Code)
+	}
+	if !flightSearcher.direct {
+		t.Error("expected direct=true to use SearchDirect")
+	}
+
+	flightSearcher.direct = false
+	r = httptest.NewRequest(http.MethodGet, "/flights/message?origin=MOW&destination=PAR&depart_date=2024-12", nil)
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if flightSearcher.direct {
+		t.Error("expected SearchCheap without direct flag")
+	}
+}
+
 func TestFlightSearch_MissingParams_ReturnsBadRequest(t *testing.T) {
 	flightSearcher := &mockFlightSearcher{}
 	h := NewHandler(flightSearcher)
@@ -219,3 +369,627 @@ func TestMethodNotAllowed(t *testing.T) {
 		t.Fatalf("expected 405, got %d", w.Code)
 	}
 }
+
+// Тесты endpoint /flights/calendar
+func TestFlightCalendar_ReturnsCalendarAndMessage(t *testing.T) {
+	flightSearcher := &mockFlightSearcher{}
+	h := NewHandler(flightSearcher)
+
+	u, _ := url.Parse("/flights/calendar?origin=MOW&destination=BCN&depart_date=2024-12&length=7&origin_city=Москва&dest_city=Барселона")
+	r := httptest.NewRequest(http.MethodGet, u.String(), nil)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+	if w.Code != 200 {
+		t.Fatalf("status: %d", w.Code)
+	}
+
+	p := flightSearcher.calendarWith
+	if p.Month != "2024-12" || p.CalendarType != "departure_date" || p.Length != 7 || p.Currency != "rub" {
+		t.Errorf("unexpected calendar params: %+v", p)
+	}
+
+	var response struct {
+		Success  bool              `json:"success"`
+		Calendar app.PriceCalendar `json:"calendar"`
+		Message  string            `json:"message"`
+		Count    int               `json:"count"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	if !response.Success || response.Count != 2 || response.Calendar.Days["2024-12-05"].Price != 12400 {
+		t.Errorf("unexpected response: %+v", response)
+	}
+	if response.Message != "📅 <b>Москва → Барселона</b>" {
+		t.Errorf("unexpected message: %s", response.Message)
+	}
+}
+
+func TestFlightCalendar_Errors(t *testing.T) {
+	cases := []struct {
+		name     string
+		query    string
+		searcher *mockFlightSearcher
+		status   int
+	}{
+		{"missing params", "origin=MOW", &mockFlightSearcher{}, http.StatusBadRequest},
+		{"bad calendar type", "origin=MOW&destination=BCN&depart_date=2024-12&calendar_type=weekly", &mockFlightSearcher{}, http.StatusBadRequest},
+		{"upstream error", "origin=MOW&destination=BCN&depart_date=2024-12", &mockFlightSearcher{shouldError: true}, http.StatusBadGateway},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			lg := &testLogger{}
+			h := NewHandlerWithLogger(tc.searcher, lg)
+			r := httptest.NewRequest(http.MethodGet, "/flights/calendar?"+tc.query, nil)
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, r)
+			if w.Code != tc.status {
+				t.Fatalf("expected status %d, got %d", tc.status, w.Code)
+			}
+			if len(lg.entries) == 0 || lg.entries[len(lg.entries)-1].level != "error" {
+				t.Errorf("expected error log, got %+v", lg.entries)
+			}
+		})
+	}
+}
+
+// Тесты endpoints /flights/week-matrix и /flights/month-matrix
+func TestFlightMatrix_RoutesByKind(t *testing.T) {
+	cases := []struct {
+		path string
+		kind string
+	}{
+		{"/flights/week-matrix?origin=MOW&destination=PAR&depart_date=2024-12-15&return_date=2024-12-22", "week"},
+		{"/flights/month-matrix?origin=MOW&destination=PAR&depart_date=2024-12", "month"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.kind, func(t *testing.T) {
+			flightSearcher := &mockFlightSearcher{}
+			h := NewHandler(flightSearcher)
+
+			r := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, r)
+			if w.Code != 200 {
+				t.Fatalf("status: %d", w.Code)
+			}
+			if flightSearcher.matrixKind != tc.kind || flightSearcher.matrixWith.Currency != "rub" {
+				t.Errorf("expected %s matrix with default currency, got %s %+v", tc.kind, flightSearcher.matrixKind, flightSearcher.matrixWith)
+			}
+
+			var response struct {
+				Success bool            `json:"success"`
+				Matrix  app.PriceMatrix `json:"matrix"`
+				Count   int             `json:"count"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("json: %v", err)
+			}
+			if !response.Success || response.Count != 2 || len(response.Matrix.Flights) != 2 {
+				t.Errorf("unexpected response: %+v", response)
+			}
+		})
+	}
+}
+
+func TestFlightMatrix_Errors(t *testing.T) {
+	h := NewHandler(&mockFlightSearcher{})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/flights/week-matrix?origin=MOW", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+
+	h = NewHandler(&mockFlightSearcher{shouldError: true})
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/flights/month-matrix?origin=MOW&destination=PAR&depart_date=2024-12", nil))
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("expected 502, got %d", w.Code)
+	}
+}
+
+// Тесты endpoint /flights/latest
+func TestFlightLatest_DefaultsAndPaging(t *testing.T) {
+	flightSearcher := &mockFlightSearcher{}
+	h := NewHandler(flightSearcher)
+
+	r := httptest.NewRequest(http.MethodGet, "/flights/latest?origin=MOW&page=3&limit=2&one_way=true&trip_class=1", nil)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+	if w.Code != 200 {
+		t.Fatalf("status: %d", w.Code)
+	}
+
+	want := app.LatestParams{
+		Origin:           "MOW",
+		Currency:         "rub",
+		PeriodType:       "year",
+		OneWay:           true,
+		Page:             3,
+		Limit:            2,
+		ShowToAffiliates: true,
+		Sorting:          "price",
+		TripClass:        1,
+	}
+	if flightSearcher.latestWith != want {
+		t.Errorf("expected params %+v, got %+v", want, flightSearcher.latestWith)
+	}
+
+	var response struct {
+		Success bool         `json:"success"`
+		Flights []app.Flight `json:"flights"`
+		Count   int          `json:"count"`
+		Page    int          `json:"page"`
+		Limit   int          `json:"limit"`
+		HasMore bool         `json:"has_more"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	if !response.Success || response.Count != 2 || response.Page != 3 || response.Limit != 2 || !response.HasMore {
+		t.Errorf("unexpected response: %+v", response)
+	}
+}
+
+func TestFlightLatest_Errors(t *testing.T) {
+	cases := []struct {
+		name     string
+		query    string
+		searcher *mockFlightSearcher
+		status   int
+	}{
+		{"missing origin", "destination=AER", &mockFlightSearcher{}, http.StatusBadRequest},
+		{"month without beginning", "origin=MOW&period_type=month", &mockFlightSearcher{}, http.StatusBadRequest},
+		{"bad sorting", "origin=MOW&sorting=cheapest", &mockFlightSearcher{}, http.StatusBadRequest},
+		{"bad trip class", "origin=MOW&trip_class=5", &mockFlightSearcher{}, http.StatusBadRequest},
+		{"limit too large", "origin=MOW&limit=5000", &mockFlightSearcher{}, http.StatusBadRequest},
+		{"upstream error", "origin=MOW", &mockFlightSearcher{shouldError: true}, http.StatusBadGateway},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(tc.searcher)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/flights/latest?"+tc.query, nil))
+			if w.Code != tc.status {
+				t.Errorf("expected %d, got %d", tc.status, w.Code)
+			}
+		})
+	}
+}
+
+// Тесты поиска «куда угодно» без направления
+func TestFlightSearch_Anywhere(t *testing.T) {
+	for _, query := range []string{"", "&destination=-", "&destination=anywhere", "&destination=Anywhere"} {
+		flightSearcher := &mockFlightSearcher{}
+		h := NewHandler(flightSearcher)
+
+		r := httptest.NewRequest(http.MethodGet, "/flights/search?origin=MOW&depart_date=2024-12"+query, nil)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+		if w.Code != 200 {
+			t.Fatalf("%q: status %d", query, w.Code)
+		}
+		if flightSearcher.calledWith.Destination != app.AnyDestination {
+			t.Errorf("%q: expected destination %q, got %q", query, app.AnyDestination, flightSearcher.calledWith.Destination)
+		}
+	}
+}
+
+func TestFlightMessage_AnywhereUsesCityNames(t *testing.T) {
+	flightSearcher := &mockFlightSearcher{mockFlights: []app.Flight{
+		{Origin: "MOW", OriginName: "Москва", Destination: "IST", DestinationName: "Стамбул", Price: 8500},
+		{Origin: "MOW", OriginName: "Москва", Destination: "AER", DestinationName: "Сочи", Price: 9900},
+	}}
+	h := NewHandler(flightSearcher)
+
+	r := httptest.NewRequest(http.MethodGet, "/flights/message?origin=MOW&depart_date=2024-12", nil)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+	if w.Code != 200 {
+		t.Fatalf("status: %d", w.Code)
+	}
+	if flightSearcher.messageFor != "Москва → -" {
+		t.Errorf("expected message for Москва → -, got %q", flightSearcher.messageFor)
+	}
+}
+
+// Тесты endpoint /directions/popular
+func TestPopularDirections_ReturnsFaresWithLinks(t *testing.T) {
+	flightSearcher := &mockFlightSearcher{mockFlights: []app.Flight{
+		{Origin: "MOW", OriginName: "Москва", Destination: "AER", DestinationName: "Сочи", Price: 3673},
+		{Origin: "MOW", OriginName: "Москва", Destination: "IST", DestinationName: "Стамбул", Price: 8500},
+		{Origin: "MOW", OriginName: "Москва", Destination: "BCN", DestinationName: "Барселона", Price: 21000},
+	}}
+	h := NewHandler(flightSearcher)
+
+	r := httptest.NewRequest(http.MethodGet, "/directions/popular?origin=MOW&limit=2", nil)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+	if w.Code != 200 {
+		t.Fatalf("status: %d", w.Code)
+	}
+	if flightSearcher.calledWith.Origin != "MOW" || flightSearcher.calledWith.Currency != "rub" {
+		t.Errorf("unexpected params: %+v", flightSearcher.calledWith)
+	}
+	if flightSearcher.messageFor != "Москва → -" {
+		t.Errorf("expected anywhere message for Москва, got %q", flightSearcher.messageFor)
+	}
+
+	var response struct {
+		Success    bool `json:"success"`
+		Directions []struct {
+			Destination     string `json:"destination"`
+			DestinationName string `json:"destination_name"`
+			Price           int    `json:"price"`
+			Link            string `json:"link"`
+		} `json:"directions"`
+		Message string `json:"message"`
+		Count   int    `json:"count"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	if !response.Success || response.Count != 2 || len(response.Directions) != 2 || response.Message == "" {
+		t.Fatalf("unexpected response: %+v", response)
+	}
+	if d := response.Directions[0]; d.Destination != "AER" || d.DestinationName != "Сочи" || d.Price != 3673 || d.Link == "" {
+		t.Errorf("unexpected first direction: %+v", d)
+	}
+}
+
+func TestPopularDirections_Errors(t *testing.T) {
+	h := NewHandler(&mockFlightSearcher{})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/directions/popular", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+
+	h = NewHandler(&mockFlightSearcher{shouldError: true})
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/directions/popular?origin=MOW", nil))
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("expected 502, got %d", w.Code)
+	}
+}
+
+// Тесты endpoint /flights/airline
+func TestFlightAirline_ReturnsRoutesWithLinks(t *testing.T) {
+	flightSearcher := &mockFlightSearcher{mockFlights: []app.Flight{
+		{Origin: "MOW", Destination: "KZN", Airline: "DP", Price: 2500},
+		{Origin: "MOW", Destination: "AER", Airline: "DP", Price: 4200},
+	}}
+	h := NewHandler(flightSearcher)
+
+	r := httptest.NewRequest(http.MethodGet, "/flights/airline?airline=dp&origin=mow&limit=5", nil)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+	if w.Code != 200 {
+		t.Fatalf("status: %d", w.Code)
+	}
+
+	want := app.AirlineParams{Airline: "DP", Origin: "MOW", Currency: "rub", Limit: 5}
+	if flightSearcher.airlineWith != want {
+		t.Errorf("expected params %+v, got %+v", want, flightSearcher.airlineWith)
+	}
+
+	var response struct {
+		Success bool   `json:"success"`
+		Airline string `json:"airline"`
+		Routes  []struct {
+			Destination string `json:"destination"`
+			Price       int    `json:"price"`
+			Link        string `json:"link"`
+		} `json:"routes"`
+		Count int `json:"count"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	if !response.Success || response.Airline != "DP" || response.Count != 2 || response.Routes[0].Destination != "KZN" || response.Routes[0].Link == "" {
+		t.Errorf("unexpected response: %+v", response)
+	}
+}
+
+// Число маршрутов ограничивается: каждый маршрут — отдельный запрос цен к API
+func TestFlightAirline_ClampsLimit(t *testing.T) {
+	for query, want := range map[string]int{
+		"limit=1000": app.MaxAirlineRoutes,
+		"limit=0":    1,
+		"limit=-5":   1,
+	} {
+		flightSearcher := &mockFlightSearcher{}
+		h := NewHandler(flightSearcher)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/flights/airline?airline=DP&"+query, nil))
+		if w.Code != 200 {
+			t.Fatalf("%s: status %d", query, w.Code)
+		}
+		if flightSearcher.airlineWith.Limit != want {
+			t.Errorf("%s: expected limit %d, got %d", query, want, flightSearcher.airlineWith.Limit)
+		}
+	}
+}
+
+func TestFlightAirline_Errors(t *testing.T) {
+	h := NewHandler(&mockFlightSearcher{})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/flights/airline?origin=MOW", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+
+	h = NewHandler(&mockFlightSearcher{shouldError: true})
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/flights/airline?airline=DP", nil))
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("expected 502, got %d", w.Code)
+	}
+}
+
+// Тесты endpoint /deals
+func TestDeals_FiltersByOrigin(t *testing.T) {
+	h := NewHandler(&mockFlightSearcher{})
+
+	r := httptest.NewRequest(http.MethodGet, "/deals?origin=MOW&limit=1", nil)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+	if w.Code != 200 {
+		t.Fatalf("status: %d", w.Code)
+	}
+
+	var response struct {
+		Success bool               `json:"success"`
+		Offers  []app.SpecialOffer `json:"offers"`
+		Message string             `json:"message"`
+		Count   int                `json:"count"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	if !response.Success || response.Count != 1 || response.Offers[0].Destination != "AER" || response.Message != "offers: 1" {
+		t.Errorf("unexpected response: %+v", response)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/deals", nil))
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || response.Count != 3 {
+		t.Errorf("expected all offers without filter, got %d (%v)", response.Count, err)
+	}
+}
+
+func TestDeals_UpstreamError(t *testing.T) {
+	h := NewHandler(&mockFlightSearcher{shouldError: true})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/deals", nil))
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("expected 502, got %d", w.Code)
+	}
+}
+
+// Тесты выбора v3 prices_for_dates
+func TestFlightSearch_PricesAPIV3Options(t *testing.T) {
+	flightSearcher := &mockFlightSearcher{}
+	h := NewHandler(flightSearcher)
+
+	r := httptest.NewRequest(http.MethodGet, "/flights/search?origin=MOW&destination=LED&depart_date=2024-12&api=v3&one_way=true&sorting=route&unique=true&page=2&direct=true&sort=duration", nil)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+	if w.Code != 200 {
+		t.Fatalf("status: %d", w.Code)
+	}
+
+	p := flightSearcher.calledWith
+	if p.API != app.PricesAPIV3 || !p.OneWay || p.Sorting != "route" || !p.Unique || p.Page != 2 || p.Sort != app.SortByDuration || !flightSearcher.direct {
+		t.Errorf("unexpected params: %+v (direct=%v)", p, flightSearcher.direct)
+	}
+
+	// По умолчанию используется v1
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/flights/message?origin=MOW&destination=LED&depart_date=2024-12", nil))
+	if flightSearcher.calledWith.API != app.PricesAPIV1 {
+		t.Errorf("expected v1 by default, got %q", flightSearcher.calledWith.API)
+	}
+}
+
+func TestFlightSearch_InvalidPricesOptions(t *testing.T) {
+	for _, query := range []string{"api=v2", "api=v3&sorting=distance", "page=-1", "sort=cheapest"} {
+		h := NewHandler(&mockFlightSearcher{})
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/flights/search?origin=MOW&destination=LED&depart_date=2024-12&"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestFlightSearch_Filter(t *testing.T) {
+	flightSearcher := &mockFlightSearcher{}
+	h := NewHandler(flightSearcher)
+
+	r := httptest.NewRequest(http.MethodGet, "/flights/message?origin=MOW&destination=AER&depart_date=2024-12&exclude_airlines=dp,%20s7&max_price=20000&max_duration=360&max_transfers=0&depart_hours=18-24", nil)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+	if w.Code != 200 {
+		t.Fatalf("status: %d", w.Code)
+	}
+
+	f := flightSearcher.calledWith.Filter
+	if len(f.ExcludeAirlines) != 2 || f.ExcludeAirlines[0] != "DP" || f.ExcludeAirlines[1] != "S7" {
+		t.Errorf("unexpected excluded airlines: %v", f.ExcludeAirlines)
+	}
+	if f.MaxPrice != 20000 || f.MaxDuration != 360 || f.MaxTransfers == nil || *f.MaxTransfers != 0 {
+		t.Errorf("unexpected filter: %+v", f)
+	}
+	if f.DepartHours == nil || f.DepartHours.From != 18 || f.DepartHours.To != 24 || f.ReturnHours != nil {
+		t.Errorf("unexpected hour windows: %+v", f)
+	}
+}
+
+func TestFlightSearch_InvalidFilter(t *testing.T) {
+	for _, query := range []string{"max_price=cheap", "min_price=30000&max_price=20000", "max_transfers=-1", "depart_hours=18", "return_hours=5-30"} {
+		h := NewHandler(&mockFlightSearcher{})
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/flights/search?origin=MOW&destination=AER&depart_date=2024-12&"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestFlightSearch_FlexDays(t *testing.T) {
+	flightSearcher := &mockFlightSearcher{}
+	h := NewHandler(flightSearcher)
+
+	r := httptest.NewRequest(http.MethodGet, "/flights/search?origin=MOW&destination=PAR&depart_date=2031-12-15&flex_days=1", nil)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+	if w.Code != 200 {
+		t.Fatalf("status: %d, body: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Flights []app.Flight          `json:"flights"`
+		Days    map[string]app.Flight `json:"days"`
+		Best    *app.Flight           `json:"best"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	// Мок отдает одни и те же билеты на каждую дату: после дедупликации остаются два
+	if len(resp.Flights) != 2 || resp.Best == nil || resp.Best.Price != 15000 {
+		t.Errorf("unexpected flex response: %+v", resp)
+	}
+	if len(resp.Days) != 2 || resp.Days["2024-12-15"].Price != 15000 {
+		t.Errorf("unexpected days: %+v", resp.Days)
+	}
+
+	for _, query := range []string{"depart_date=2031-12&flex_days=2", "depart_date=2031-12-15&flex_days=30"} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/flights/search?origin=MOW&destination=PAR&"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestFlightMessage_MultiRoute(t *testing.T) {
+	flightSearcher := &mockFlightSearcher{perRoute: true}
+	h := NewHandler(flightSearcher)
+
+	r := httptest.NewRequest(http.MethodGet, "/flights/message?origin=MOW&destination=IST,%20ayt&expand_airports=true&depart_date=2024-12&limit=3&origin_city=Москва&dest_city=Стамбул%20или%20Анталья", nil)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+	if w.Code != 200 {
+		t.Fatalf("status: %d, body: %s", w.Code, w.Body.String())
+	}
+	if len(flightSearcher.routes) != 6 {
+		t.Errorf("expected 6 routes from 3 airports to 2 cities, got %v", flightSearcher.routes)
+	}
+
+	var resp struct {
+		Flights []app.Flight      `json:"flights"`
+		Routes  []string          `json:"routes"`
+		Errors  map[string]string `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	// Объединенная выдача по цене, у каждого билета свой маршрут
+	if len(resp.Flights) != 3 {
+		t.Fatalf("expected 3 flights, got %+v", resp.Flights)
+	}
+	for i, want := range []string{"DME-IST", "SVO-AYT", "SVO-IST"} {
+		if got := resp.Flights[i].Origin + "-" + resp.Flights[i].Destination; got != want {
+			t.Errorf("flight %d: expected route %s, got %s", i, want, got)
+		}
+	}
+	if len(resp.Routes) != 6 || len(resp.Errors) != 2 || resp.Errors["VKO-IST"] == "" {
+		t.Errorf("unexpected routes %v or errors %v", resp.Routes, resp.Errors)
+	}
+}
+
+func TestFlightSearch_MultiRouteErrors(t *testing.T) {
+	for _, query := range []string{
+		"origin=MOW,LED&destination=IST&depart_date=2031-12-15&flex_days=1",
+		"origin=A1,A2,A3,A4,A5&destination=B1,B2,B3,B4&depart_date=2024-12",
+	} {
+		h := NewHandler(&mockFlightSearcher{})
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/flights/search?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
+// Тесты endpoint /flights/grouped
+func TestFlightGrouped_ReturnsPeriodsAndMessage(t *testing.T) {
+	flightSearcher := &mockFlightSearcher{}
+	h := NewHandler(flightSearcher)
+
+	r := httptest.NewRequest(http.MethodGet, "/flights/grouped?origin=MOW&destination=PAR&direct=true&origin_city=Москва&dest_city=Париж", nil)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+	if w.Code != 200 {
+		t.Fatalf("status: %d", w.Code)
+	}
+
+	want := app.GroupedParams{Origin: "MOW", Destination: "PAR", GroupBy: "month", Direct: true, Currency: "rub"}
+	if flightSearcher.groupedWith != want {
+		t.Errorf("expected params %+v, got %+v", want, flightSearcher.groupedWith)
+	}
+
+	var response struct {
+		Success bool              `json:"success"`
+		Grouped app.GroupedPrices `json:"grouped"`
+		Message string            `json:"message"`
+		Count   int               `json:"count"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	if !response.Success || response.Count != 2 || len(response.Grouped.Periods) != 2 || response.Message != "Москва → Париж: 2" {
+		t.Errorf("unexpected response: %+v", response)
+	}
+}
+
+func TestFlightGrouped_Errors(t *testing.T) {
+	cases := []struct {
+		name     string
+		query    string
+		searcher *mockFlightSearcher
+		status   int
+	}{
+		{"missing destination", "origin=MOW", &mockFlightSearcher{}, http.StatusBadRequest},
+		{"bad group_by", "origin=MOW&destination=PAR&group_by=week", &mockFlightSearcher{}, http.StatusBadRequest},
+		{"upstream error", "origin=MOW&destination=PAR", &mockFlightSearcher{shouldError: true}, http.StatusBadGateway},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(tc.searcher)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/flights/grouped?"+tc.query, nil))
+			if w.Code != tc.status {
+				t.Errorf("expected %d, got %d", tc.status, w.Code)
+			}
+		})
+	}
+}
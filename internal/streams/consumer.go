@@ -2,105 +2,183 @@ package streams
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"time"
+
+	app "aviasales-bot/search-service/internal/application"
 )
 
+// MessageIDField поле события, в котором Redis клиент возвращает ID сообщения stream
+const MessageIDField = "id"
+
+// DeliveryCountField поле события с количеством доставок сообщения (заполняется для pending entries)
+const DeliveryCountField = "delivery_count"
+
+// ErrNoEvents возвращается, когда в stream нет новых сообщений
+var ErrNoEvents = errors.New("no events available")
+
+// ErrDeadLettered возвращается, когда невалидное сообщение перенесено в DLQ и подтверждено
+var ErrDeadLettered = errors.New("message moved to dead-letter stream")
+
 // SearchRequest представляет запрос на поиск авиабилетов из Redis Stream
 type SearchRequest struct {
+	MessageID     string              `json:"-"`
+	DeliveryCount int64               `json:"-"` // сколько раз сообщение выдавалось консьюмерам (0 — неизвестно)
 	RequestID     string              `json:"request_id"`
 	CorrelationID string              `json:"correlation_id"`
 	ChatID        string              `json:"chat_id"`
 	Params        SearchRequestParams `json:"params"`
+	CreatedAt     time.Time           `json:"created_at"` // когда пользователь отправил запрос
+	Deadline      time.Time           `json:"deadline"`   // после этого момента результат уже не нужен
+}
+
+// Expired сообщает, истек ли deadline запроса к моменту now; запрос без deadline не истекает
+func (r *SearchRequest) Expired(now time.Time) bool {
+	return !r.Deadline.IsZero() && !now.Before(r.Deadline)
 }
 
 // SearchRequestParams параметры поиска
 type SearchRequestParams struct {
-	Origin      string `json:"origin"`
-	Destination string `json:"destination"`
-	DepartDate  string `json:"depart_date"`
-	ReturnDate  string `json:"return_date"`
-	Currency    string `json:"currency"`
-	Passengers  int    `json:"passengers"`
-	Limit       int    `json:"limit"`
+	Origin      string      `json:"origin"`      // для cheap — список кодов через запятую
+	Destination string      `json:"destination"` // пусто, "-" или "anywhere" — все направления (кроме матриц); для cheap — список через запятую
+	DepartDate  string      `json:"depart_date"`
+	ReturnDate  string      `json:"return_date"`
+	Currency    string      `json:"currency"`
+	Passengers  int         `json:"passengers"`
+	Limit       int         `json:"limit"`
+	Direct      bool        `json:"direct,omitempty"`    // только прямые рейсы
+	Kind        string      `json:"kind,omitempty"`      // вид поиска: SearchKind*, по умолчанию самые дешевые билеты
+	API         string      `json:"api,omitempty"`       // версия API цен для cheap: v1 (по умолчанию) или v3
+	OneWay      bool        `json:"one_way,omitempty"`   // билеты в одну сторону (v3)
+	GroupBy     string      `json:"group_by,omitempty"`  // период для grouped_prices: month (по умолчанию) или departure_at
+	Sort        string      `json:"sort,omitempty"`      // порядок результатов cheap: price (по умолчанию), departure, duration, transfers
+	Filter      *app.Filter `json:"filter,omitempty"`    // ограничения на билеты cheap: авиакомпании, цена, длительность, часы вылета
+	FlexDays    int         `json:"flex_days,omitempty"` // поиск cheap на ±N дней от depart_date (YYYY-MM-DD)
+
+	ExpandAirports bool `json:"expand_airports,omitempty"` // искать cheap из каждого аэропорта городов по отдельности
+}
+
+// Виды поиска в SearchRequestParams.Kind
+const (
+	SearchKindCheap       = "cheap"          // самые дешевые билеты (по умолчанию)
+	SearchKindWeekMatrix  = "week_matrix"    // цены на ±3 дня от дат вылета и возвращения
+	SearchKindMonthMatrix = "month_matrix"   // цены на каждый день месяца вылета
+	SearchKindGrouped     = "grouped_prices" // самый дешевый билет на каждый месяц или день вылета
+)
+
+// validSearchKind проверяет, поддерживается ли вид поиска
+func validSearchKind(kind string) bool {
+	switch kind {
+	case "", SearchKindCheap, SearchKindWeekMatrix, SearchKindMonthMatrix, SearchKindGrouped:
+		return true
+	}
+	return false
+}
+
+// isMatrixKind сообщает, что вид поиска возвращает матрицу цен
+func isMatrixKind(kind string) bool {
+	return kind == SearchKindWeekMatrix || kind == SearchKindMonthMatrix
+}
+
+// needsDestination сообщает, что виду поиска обязательно нужно направление
+func needsDestination(kind string) bool {
+	return isMatrixKind(kind) || kind == SearchKindGrouped
 }
 
 // RedisClient интерфейс для работы с Redis
 type RedisClient interface {
 	XReadGroup(ctx context.Context, group, consumer, stream string, count int64) ([]map[string]interface{}, error)
 	XAck(ctx context.Context, stream, group, messageID string) error
+	// XClaimJustID переприсваивает сообщения консьюмеру (XCLAIM ... JUSTID): простой сбрасывается,
+	// а счетчик доставок не растет
+	XClaimJustID(ctx context.Context, stream, group, consumer string, ids []string) error
 }
 
 // SearchRequestConsumer консьюмер для обработки запросов поиска
 type SearchRequestConsumer struct {
-	redis  RedisClient
-	group  string
-	stream string
+	redis    RedisClient
+	group    string
+	stream   string
+	consumer string
+	dlq      *DeadLetterQueue
+}
+
+// ConsumerOption настраивает SearchRequestConsumer
+type ConsumerOption func(*SearchRequestConsumer)
+
+// WithDeadLetterQueue переносит невалидные сообщения в DLQ вместо бесконечной повторной доставки
+func WithDeadLetterQueue(q *DeadLetterQueue) ConsumerOption {
+	return func(c *SearchRequestConsumer) { c.dlq = q }
+}
+
+// WithConsumerName задает имя консьюмера в группе. Имя должно быть уникальным для экземпляра
+// сервиса: pending entries и их доставки в Redis учитываются по имени консьюмера.
+func WithConsumerName(name string) ConsumerOption {
+	return func(c *SearchRequestConsumer) { c.consumer = name }
 }
 
 // NewSearchRequestConsumer создает новый консьюмер
-func NewSearchRequestConsumer(redis RedisClient, group string) *SearchRequestConsumer {
-	return &SearchRequestConsumer{
-		redis:  redis,
-		group:  group,
-		stream: "search.requests",
+func NewSearchRequestConsumer(redis RedisClient, group string, opts ...ConsumerOption) *SearchRequestConsumer {
+	c := &SearchRequestConsumer{
+		redis:    redis,
+		group:    group,
+		stream:   "search.requests",
+		consumer: "search-service",
+	}
+	for _, o := range opts {
+		o(c)
 	}
+	return c
 }
 
 // Consume читает и парсит запрос из Redis Stream
 func (c *SearchRequestConsumer) Consume(ctx context.Context) (*SearchRequest, error) {
+	requests, err := c.ConsumeBatch(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+	return requests[0], nil
+}
+
+// ConsumeBatch читает до count запросов за один XREADGROUP.
+// Невалидные сообщения переносятся в DLQ (если подключен); их ошибки объединяются
+// и возвращаются вместе с валидными запросами.
+func (c *SearchRequestConsumer) ConsumeBatch(ctx context.Context, count int64) ([]*SearchRequest, error) {
 	// Читаем из stream с таймаутом
-	events, err := c.redis.XReadGroup(ctx, c.group, "search-service", c.stream, 1)
+	events, err := c.redis.XReadGroup(ctx, c.group, c.consumer, c.stream, count)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read from stream: %w", err)
 	}
 
 	if len(events) == 0 {
-		return nil, fmt.Errorf("no events available")
-	}
-
-	event := events[0]
-
-	// Парсим JSON из поля "params"
-	paramsJSON, exists := event["params"]
-	if !exists {
-		return nil, fmt.Errorf("missing params field")
+		return nil, ErrNoEvents
 	}
 
-	paramsBytes, err := json.Marshal(paramsJSON)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal params: %w", err)
-	}
-
-	var params SearchRequestParams
-	if err := json.Unmarshal(paramsBytes, &params); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal params: %w", err)
+	var requests []*SearchRequest
+	var errs []error
+	for _, event := range events {
+		request, err := DecodeSearchRequest(event)
+		if err != nil {
+			errs = append(errs, c.reject(ctx, event, err))
+			continue
+		}
+		requests = append(requests, request)
 	}
 
-	// Создаем SearchRequest
-	request := &SearchRequest{
-		RequestID:     getString(event, "request_id"),
-		CorrelationID: getString(event, "correlation_id"),
-		ChatID:        getString(event, "chat_id"),
-		Params:        params,
-	}
+	return requests, errors.Join(errs...)
+}
 
-	// Валидируем обязательные поля
-	if request.RequestID == "" {
-		return nil, fmt.Errorf("missing request_id")
-	}
-	if request.ChatID == "" {
-		return nil, fmt.Errorf("missing chat_id")
-	}
-	if request.Params.Origin == "" {
-		return nil, fmt.Errorf("missing origin")
+// reject переносит невалидное сообщение в DLQ, если он подключен
+func (c *SearchRequestConsumer) reject(ctx context.Context, event map[string]interface{}, err error) error {
+	if c.dlq == nil {
+		return err
 	}
-	if request.Params.Destination == "" {
-		return nil, fmt.Errorf("missing destination")
+	if _, dlqErr := c.dlq.Move(ctx, event, err.Error()); dlqErr != nil {
+		return fmt.Errorf("%v (dead-letter failed: %w)", err, dlqErr)
 	}
-
-	return request, nil
+	return fmt.Errorf("%w: %v", ErrDeadLettered, err)
 }
 
 // ConsumeWithTimeout читает запрос с таймаутом
@@ -111,6 +189,38 @@ func (c *SearchRequestConsumer) ConsumeWithTimeout(ctx context.Context, timeout
 	return c.Consume(ctx)
 }
 
+// Touch сбрасывает простой прочитанных, но еще не подтвержденных запросов, чтобы reclaimer
+// (этого или другого экземпляра) не счел их брошенными, пока они ждут воркера
+func (c *SearchRequestConsumer) Touch(ctx context.Context, requests []*SearchRequest) error {
+	ids := make([]string, 0, len(requests))
+	for _, request := range requests {
+		if request.MessageID != "" {
+			ids = append(ids, request.MessageID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if err := c.redis.XClaimJustID(ctx, c.stream, c.group, c.consumer, ids); err != nil {
+		return fmt.Errorf("failed to touch pending entries: %w", err)
+	}
+	return nil
+}
+
+// Ack подтверждает обработку запроса в consumer group
+func (c *SearchRequestConsumer) Ack(ctx context.Context, request *SearchRequest) error {
+	if request.MessageID == "" {
+		return fmt.Errorf("missing message id for request %s", request.RequestID)
+	}
+
+	if err := c.redis.XAck(ctx, c.stream, c.group, request.MessageID); err != nil {
+		return fmt.Errorf("failed to ack message: %w", err)
+	}
+
+	return nil
+}
+
 // getString извлекает строковое значение из map
 func getString(m map[string]interface{}, key string) string {
 	if val, exists := m[key]; exists {
@@ -120,3 +230,40 @@ func getString(m map[string]interface{}, key string) string {
 	}
 	return ""
 }
+
+// getInt64 извлекает целое значение из map (число или строка)
+func getInt64(m map[string]interface{}, key string) int64 {
+	switch v := m[key].(type) {
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	case float64:
+		return int64(v)
+	case string:
+		n, _ := strconv.ParseInt(v, 10, 64)
+		return n
+	}
+	return 0
+}
+
+// getTime извлекает время из map: unix-время в секундах (число или строка) или RFC3339.
+// Отсутствующее или пустое поле возвращает нулевое время.
+func getTime(m map[string]interface{}, key string) (time.Time, error) {
+	switch v := m[key].(type) {
+	case nil:
+		return time.Time{}, nil
+	case int64, int, float64:
+		return time.Unix(getInt64(m, key), 0), nil
+	case string:
+		if v == "" {
+			return time.Time{}, nil
+		}
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.Unix(n, 0), nil
+		}
+		return time.Parse(time.RFC3339, v)
+	default:
+		return time.Time{}, fmt.Errorf("unsupported type %T", v)
+	}
+}
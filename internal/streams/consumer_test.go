@@ -100,3 +100,200 @@ func TestSearchRequestConsumer_InvalidJSON(t *testing.T) {
 		t.Error("Expected error for invalid JSON")
 	}
 }
+
+func TestDecodeSearchRequest_Deadline(t *testing.T) {
+	event := map[string]interface{}{
+		"request_id": "req-1",
+		"chat_id":    "12345",
+		"created_at": "1733000000",
+		"deadline":   "2024-12-01T00:15:00Z",
+		"params":     `{"origin":"MOW","destination":"PAR"}`,
+	}
+
+	request, err := DecodeSearchRequest(event)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if !request.CreatedAt.Equal(time.Unix(1733000000, 0)) {
+		t.Errorf("unexpected created_at: %v", request.CreatedAt)
+	}
+	deadline := time.Date(2024, 12, 1, 0, 15, 0, 0, time.UTC)
+	if !request.Deadline.Equal(deadline) {
+		t.Errorf("unexpected deadline: %v", request.Deadline)
+	}
+	if request.Expired(deadline.Add(-time.Second)) || !request.Expired(deadline) {
+		t.Error("expected request to expire exactly at deadline")
+	}
+
+	event["deadline"] = "tomorrow"
+	if _, err := DecodeSearchRequest(event); err == nil {
+		t.Error("expected error for invalid deadline")
+	}
+
+	delete(event, "deadline")
+	delete(event, "created_at")
+	request, err = DecodeSearchRequest(event)
+	if err != nil {
+		t.Fatalf("parse without deadline: %v", err)
+	}
+	if request.Expired(time.Now()) {
+		t.Error("expected request without deadline never to expire")
+	}
+}
+
+func TestDecodeSearchRequest_AnywhereDestination(t *testing.T) {
+	event := testRequestEvent("1-0", "req-1")
+	delete(event["params"].(map[string]interface{}), "destination")
+
+	if _, err := DecodeSearchRequest(event); err != nil {
+		t.Fatalf("expected cheap search without destination to be valid: %v", err)
+	}
+
+	event["params"].(map[string]interface{})["kind"] = SearchKindMonthMatrix
+	if _, err := DecodeSearchRequest(event); err == nil {
+		t.Error("expected matrix search without destination to be rejected")
+	}
+}
+
+func TestDecodeSearchRequest_UnknownPricesAPI(t *testing.T) {
+	event := testRequestEvent("1-0", "req-1")
+	event["params"].(map[string]interface{})["api"] = "v2"
+
+	if _, err := DecodeSearchRequest(event); err == nil {
+		t.Error("expected error for unsupported prices api")
+	}
+}
+
+func TestDecodeSearchRequest_Sort(t *testing.T) {
+	event := testRequestEvent("1-0", "req-1")
+	event["params"].(map[string]interface{})["sort"] = "transfers"
+
+	request, err := DecodeSearchRequest(event)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if request.Params.Sort != "transfers" {
+		t.Errorf("expected sort transfers, got %q", request.Params.Sort)
+	}
+	if toSearchParams(request.Params).Sort != "transfers" {
+		t.Errorf("sort is not passed to search params: %+v", toSearchParams(request.Params))
+	}
+
+	event["params"].(map[string]interface{})["sort"] = "cheapest"
+	if _, err := DecodeSearchRequest(event); err == nil {
+		t.Error("expected error for unsupported sort")
+	}
+}
+
+func TestDecodeSearchRequest_Filter(t *testing.T) {
+	event := testRequestEvent("1-0", "req-1")
+	event["params"].(map[string]interface{})["filter"] = map[string]interface{}{
+		"exclude_airlines": []string{"DP"},
+		"max_price":        20000,
+		"max_transfers":    0,
+		"depart_hours":     map[string]int{"from": 18, "to": 24},
+	}
+
+	request, err := DecodeSearchRequest(event)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	f := toSearchParams(request.Params).Filter
+	if len(f.ExcludeAirlines) != 1 || f.MaxPrice != 20000 || f.MaxTransfers == nil || *f.MaxTransfers != 0 || f.DepartHours == nil || f.DepartHours.From != 18 {
+		t.Errorf("unexpected filter: %+v", f)
+	}
+
+	event["params"].(map[string]interface{})["filter"] = map[string]interface{}{"min_price": 30000, "max_price": 20000}
+	if _, err := DecodeSearchRequest(event); err == nil {
+		t.Error("expected error for invalid filter")
+	}
+}
+
+func TestDecodeSearchRequest_FlexDays(t *testing.T) {
+	event := testRequestEvent("1-0", "req-1")
+	event["params"].(map[string]interface{})["flex_days"] = 3
+
+	request, err := DecodeSearchRequest(event)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if toSearchParams(request.Params).FlexDays != 3 {
+		t.Errorf("flex days are not passed to search params: %+v", request.Params)
+	}
+
+	event["params"].(map[string]interface{})["depart_date"] = "2024-12"
+	if _, err := DecodeSearchRequest(event); err == nil {
+		t.Error("expected error for flex days with month depart_date")
+	}
+
+	event = testRequestEvent("1-0", "req-1")
+	event["params"].(map[string]interface{})["flex_days"] = 3
+	event["params"].(map[string]interface{})["kind"] = SearchKindWeekMatrix
+	if _, err := DecodeSearchRequest(event); err == nil {
+		t.Error("expected error for flex days with matrix search")
+	}
+}
+
+func TestDecodeSearchRequest_MultiRoute(t *testing.T) {
+	event := testRequestEvent("1-0", "req-1")
+	event["params"].(map[string]interface{})["origin"] = "MOW,LED"
+	event["params"].(map[string]interface{})["expand_airports"] = true
+
+	request, err := DecodeSearchRequest(event)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !toSearchParams(request.Params).ExpandAirports {
+		t.Error("expand_airports is not passed to search params")
+	}
+
+	for name, params := range map[string]map[string]interface{}{
+		"matrix":      {"kind": SearchKindWeekMatrix},
+		"flex":        {"flex_days": 1, "depart_date": "2031-12-15"},
+		"many routes": {"origin": "A1,A2,A3,A4,A5", "destination": "B1,B2,B3,B4"},
+	} {
+		event := testRequestEvent("1-0", "req-1")
+		event["params"].(map[string]interface{})["origin"] = "MOW,LED"
+		for k, v := range params {
+			event["params"].(map[string]interface{})[k] = v
+		}
+		if _, err := DecodeSearchRequest(event); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestDecodeSearchRequest_GroupedPrices(t *testing.T) {
+	event := testRequestEvent("1-0", "req-1")
+	params := event["params"].(map[string]interface{})
+	params["kind"] = SearchKindGrouped
+	params["group_by"] = "departure_at"
+
+	request, err := DecodeSearchRequest(event)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if request.Params.GroupBy != "departure_at" {
+		t.Errorf("expected group_by departure_at, got %q", request.Params.GroupBy)
+	}
+
+	params["group_by"] = "week"
+	if _, err := DecodeSearchRequest(event); err == nil {
+		t.Error("expected error for unsupported group_by")
+	}
+
+	params["group_by"] = "month"
+	delete(params, "destination")
+	if _, err := DecodeSearchRequest(event); err == nil {
+		t.Error("expected grouped prices without destination to be rejected")
+	}
+}
+
+func TestDecodeSearchRequest_UnknownKind(t *testing.T) {
+	event := testRequestEvent("1-0", "req-1")
+	event["params"].(map[string]interface{})["kind"] = "everything"
+
+	if _, err := DecodeSearchRequest(event); err == nil {
+		t.Error("expected error for unsupported search kind")
+	}
+}
@@ -0,0 +1,200 @@
+package streams
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	app "aviasales-bot/search-service/internal/application"
+)
+
+// DealsSource источник спецпредложений для дайджеста
+type DealsSource interface {
+	SpecialOffers(ctx context.Context) ([]app.SpecialOffer, error)
+	FormatOffersMessage(offers []app.SpecialOffer) string
+}
+
+// DealsDigest событие deals.digest со спецпредложениями для ежедневной рассылки бота
+type DealsDigest struct {
+	Count     int                `json:"count"`
+	Offers    []app.SpecialOffer `json:"offers"`
+	Message   string             `json:"message"` // готовый к отправке текст (HTML Telegram)
+	Timestamp time.Time          `json:"timestamp"`
+}
+
+// RedisDigest интерфейс Redis для дайджеста: публикация в stream и ключ, которым
+// один из экземпляров сервиса закрепляет за собой публикацию периода
+type RedisDigest interface {
+	RedisProducer
+	SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error)
+	DelIfEqual(ctx context.Context, key string, value interface{}) (bool, error)
+}
+
+// digestRetryDelay через сколько повторяется неудавшаяся публикация в том же периоде
+const digestRetryDelay = 5 * time.Minute
+
+// DealsDigestPublisher периодически публикует спецпредложения в stream deals.digest
+type DealsDigestPublisher struct {
+	redis    RedisDigest
+	source   DealsSource
+	stream   string
+	interval time.Duration
+	retry    time.Duration
+	limit    int
+	logger   Logger
+	now      func() time.Time
+}
+
+// DigestOption настраивает DealsDigestPublisher
+type DigestOption func(*DealsDigestPublisher)
+
+// WithDigestInterval задает период публикации дайджеста (по умолчанию сутки).
+// Периоды отсчитываются от полуночи UTC, а не от запуска сервиса.
+func WithDigestInterval(d time.Duration) DigestOption {
+	return func(p *DealsDigestPublisher) { p.interval = d }
+}
+
+// WithDigestLimit ограничивает число предложений в дайджесте (0 — без ограничения)
+func WithDigestLimit(n int) DigestOption {
+	return func(p *DealsDigestPublisher) { p.limit = n }
+}
+
+// WithDigestLogger подключает логгер
+func WithDigestLogger(l Logger) DigestOption {
+	return func(p *DealsDigestPublisher) { p.logger = l }
+}
+
+// NewDealsDigestPublisher создает публикатор дайджеста спецпредложений
+func NewDealsDigestPublisher(redis RedisDigest, source DealsSource, opts ...DigestOption) *DealsDigestPublisher {
+	p := &DealsDigestPublisher{
+		redis:    redis,
+		source:   source,
+		stream:   "deals.digest",
+		interval: 24 * time.Hour,
+		retry:    digestRetryDelay,
+		limit:    10,
+		now:      time.Now,
+	}
+	for _, o := range opts {
+		o(p)
+	}
+	if p.interval <= 0 {
+		p.interval = 24 * time.Hour
+	}
+	return p
+}
+
+// Publish публикует текущие спецпредложения одним событием: поля count, offers (JSON),
+// message и timestamp (unix секунды), как в v1 схеме search.results
+func (p *DealsDigestPublisher) Publish(ctx context.Context) (string, error) {
+	offers, err := p.source.SpecialOffers(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to load special offers: %w", err)
+	}
+	if p.limit > 0 && len(offers) > p.limit {
+		offers = offers[:p.limit]
+	}
+
+	offersJSON, err := json.Marshal(offers)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal offers: %w", err)
+	}
+
+	messageID, err := p.redis.XAdd(ctx, p.stream, map[string]interface{}{
+		SchemaVersionField: SchemaV1,
+		"count":            len(offers),
+		"offers":           string(offersJSON),
+		"message":          p.source.FormatOffersMessage(offers),
+		"timestamp":        time.Now().Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to publish to stream: %w", err)
+	}
+	return messageID, nil
+}
+
+// Run публикует дайджест раз в период до отмены контекста: сразу при запуске, если дайджест
+// текущего периода еще не вышел, и затем в начале каждого следующего. Из нескольких экземпляров
+// сервиса период публикует тот, кто первым занял ключ deals:digest:<начало периода> (SET NX),
+// поэтому перезапуски не откладывают дайджест и не дублируют его.
+func (p *DealsDigestPublisher) Run(ctx context.Context) error {
+	for {
+		now := p.now()
+		period := now.Truncate(p.interval)
+		next := period.Add(p.interval)
+		if !p.publishPeriod(ctx, period) {
+			if retry := now.Add(p.retry); retry.Before(next) {
+				next = retry
+			}
+		}
+
+		timer := time.NewTimer(next.Sub(p.now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// publishPeriod публикует дайджест периода, если его еще не опубликовал этот или другой экземпляр.
+// Возвращает false, если публикацию нужно повторить.
+func (p *DealsDigestPublisher) publishPeriod(ctx context.Context, period time.Time) bool {
+	key := "deals:digest:" + period.UTC().Format(time.RFC3339Nano)
+	token := newLeaseToken()
+	claimed, err := p.redis.SetNX(ctx, key, token, p.interval)
+	if err != nil {
+		p.logError("deals_digest_failed", map[string]interface{}{"period": key, "error": err.Error()})
+		return ctx.Err() != nil
+	}
+	if !claimed {
+		return true
+	}
+
+	messageID, err := p.Publish(ctx)
+	if err != nil {
+		// освобождаем период, чтобы повтор (свой или другого экземпляра) мог его опубликовать
+		_, _ = p.redis.DelIfEqual(context.WithoutCancel(ctx), key, token)
+		if ctx.Err() != nil {
+			return true
+		}
+		p.logError("deals_digest_failed", map[string]interface{}{"period": key, "error": err.Error()})
+		return false
+	}
+	if p.logger != nil {
+		p.logger.Info("deals_digest_published", map[string]interface{}{"message_id": messageID, "period": key})
+	}
+	return true
+}
+
+func (p *DealsDigestPublisher) logError(event string, data map[string]interface{}) {
+	if p.logger != nil {
+		p.logger.Error(event, data)
+	}
+}
+
+// DecodeDealsDigest декодирует событие deals.digest
+func DecodeDealsDigest(fields map[string]interface{}) (*DealsDigest, error) {
+	if version := SchemaVersion(fields); version != SchemaV1 {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedSchema, version)
+	}
+
+	digest := &DealsDigest{
+		Count:   int(getInt64(fields, "count")),
+		Message: getString(fields, "message"),
+		Offers:  []app.SpecialOffer{},
+	}
+	if raw := getString(fields, "offers"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &digest.Offers); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal offers: %w", err)
+		}
+	}
+
+	var err error
+	if digest.Timestamp, err = getTime(fields, "timestamp"); err != nil {
+		return nil, fmt.Errorf("invalid timestamp: %w", err)
+	}
+	return digest, nil
+}
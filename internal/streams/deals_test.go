@@ -0,0 +1,167 @@
+package streams
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	app "aviasales-bot/search-service/internal/application"
+)
+
+type fakeDeals struct {
+	offers []app.SpecialOffer
+	err    error
+}
+
+func (f *fakeDeals) SpecialOffers(context.Context) ([]app.SpecialOffer, error) {
+	return f.offers, f.err
+}
+
+func (f *fakeDeals) FormatOffersMessage(offers []app.SpecialOffer) string {
+	return fmt.Sprintf("offers: %d", len(offers))
+}
+
+func TestDealsDigestPublisher_Publish(t *testing.T) {
+	mockRedis := &mockRedisClient{streams: make(map[string][]map[string]interface{})}
+	source := &fakeDeals{offers: []app.SpecialOffer{
+		{Airline: "Победа", Origin: "MOW", Destination: "AER", Price: 2999, SaleEnd: time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)},
+		{Airline: "S7", Origin: "OVB", Destination: "MOW", Price: 4500},
+		{Airline: "Аэрофлот", Origin: "MOW", Destination: "LED", Price: 5200},
+	}}
+	publisher := NewDealsDigestPublisher(mockRedis, source, WithDigestLimit(2))
+
+	if _, err := publisher.Publish(context.Background()); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	events := mockRedis.GetStreams()["deals.digest"]
+	if len(events) != 1 {
+		t.Fatalf("expected 1 digest event, got %d", len(events))
+	}
+	digest, err := DecodeDealsDigest(events[0])
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if digest.Count != 2 || len(digest.Offers) != 2 || digest.Message != "offers: 2" || digest.Timestamp.IsZero() {
+		t.Errorf("unexpected digest: %+v", digest)
+	}
+	if digest.Offers[0].Destination != "AER" || !digest.Offers[0].SaleEnd.Equal(source.offers[0].SaleEnd) {
+		t.Errorf("unexpected first offer: %+v", digest.Offers[0])
+	}
+}
+
+func TestDealsDigestPublisher_SourceError(t *testing.T) {
+	mockRedis := &mockRedisClient{streams: make(map[string][]map[string]interface{})}
+	publisher := NewDealsDigestPublisher(mockRedis, &fakeDeals{err: errors.New("feed unavailable")})
+
+	if _, err := publisher.Publish(context.Background()); err == nil {
+		t.Fatal("expected error")
+	}
+	if len(mockRedis.GetStreams()["deals.digest"]) != 0 {
+		t.Error("expected nothing to be published")
+	}
+}
+
+func TestDealsDigestPublisher_RunPublishesPeriodically(t *testing.T) {
+	mockRedis := &mockRedisClient{streams: make(map[string][]map[string]interface{}), processed: make(map[string]bool)}
+	publisher := NewDealsDigestPublisher(mockRedis, &fakeDeals{}, WithDigestInterval(10*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = publisher.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.After(time.Second)
+	for {
+		events, _ := mockRedis.XRange(context.Background(), "deals.digest", "-", "+", 0)
+		if len(events) >= 2 {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatal("expected digest to be published at least twice")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	cancel()
+	<-done
+}
+
+// flakyDeals отдает ошибку на первый запрос и предложения на последующие
+type flakyDeals struct {
+	fakeDeals
+	mu    sync.Mutex
+	calls int
+}
+
+func (f *flakyDeals) SpecialOffers(context.Context) ([]app.SpecialOffer, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.calls++; f.calls == 1 {
+		return nil, errors.New("feed unavailable")
+	}
+	return f.offers, nil
+}
+
+// runDigest запускает Run и возвращает функцию остановки
+func runDigest(publisher *DealsDigestPublisher) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = publisher.Run(ctx)
+		close(done)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func TestDealsDigestPublisher_RunPublishesOnStartOncePerPeriod(t *testing.T) {
+	mockRedis := &mockRedisClient{streams: make(map[string][]map[string]interface{}), processed: make(map[string]bool)}
+
+	// две реплики: дайджест выходит сразу после запуска и только один раз за период
+	first := runDigest(NewDealsDigestPublisher(mockRedis, &fakeDeals{}, WithDigestInterval(time.Hour)))
+	second := runDigest(NewDealsDigestPublisher(mockRedis, &fakeDeals{}, WithDigestInterval(time.Hour)))
+	time.Sleep(50 * time.Millisecond)
+	first()
+	second()
+
+	// перезапуск в том же периоде не публикует дайджест повторно
+	runDigest(NewDealsDigestPublisher(mockRedis, &fakeDeals{}, WithDigestInterval(time.Hour)))()
+
+	if events := mockRedis.GetStreams()["deals.digest"]; len(events) != 1 {
+		t.Fatalf("expected exactly one digest for the period, got %d", len(events))
+	}
+}
+
+func TestDealsDigestPublisher_RunRetriesFailedPeriod(t *testing.T) {
+	mockRedis := &mockRedisClient{streams: make(map[string][]map[string]interface{}), processed: make(map[string]bool)}
+	publisher := NewDealsDigestPublisher(mockRedis, &flakyDeals{}, WithDigestInterval(time.Hour))
+	publisher.retry = 10 * time.Millisecond
+
+	stop := runDigest(publisher)
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if events, _ := mockRedis.XRange(context.Background(), "deals.digest", "-", "+", 0); len(events) > 0 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	stop()
+
+	if events := mockRedis.GetStreams()["deals.digest"]; len(events) != 1 {
+		t.Fatalf("expected failed period to be retried and published once, got %d", len(events))
+	}
+}
+
+func TestDecodeDealsDigest_UnsupportedSchema(t *testing.T) {
+	if _, err := DecodeDealsDigest(map[string]interface{}{SchemaVersionField: "2"}); !errors.Is(err, ErrUnsupportedSchema) {
+		t.Errorf("expected ErrUnsupportedSchema, got %v", err)
+	}
+}
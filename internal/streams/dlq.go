@@ -0,0 +1,152 @@
+package streams
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DeadLetterStream stream для запросов, которые невозможно обработать
+const DeadLetterStream = "search.requests.dlq"
+
+// RedisDeadLetter интерфейс Redis для работы с dead-letter stream
+type RedisDeadLetter interface {
+	XAdd(ctx context.Context, stream string, fields map[string]interface{}) (string, error)
+	XAck(ctx context.Context, stream, group, messageID string) error
+	XRange(ctx context.Context, stream, start, end string, count int64) ([]map[string]interface{}, error)
+	XDel(ctx context.Context, stream string, ids ...string) error
+}
+
+// DeadLetter запрос, перенесенный в DLQ
+type DeadLetter struct {
+	ID            string                 `json:"id"`
+	OriginalID    string                 `json:"original_id"`
+	Reason        string                 `json:"reason"`
+	DeliveryCount int64                  `json:"delivery_count"`
+	Fields        map[string]interface{} `json:"fields"`
+	FailedAt      time.Time              `json:"failed_at"`
+}
+
+// DeadLetterQueue переносит сообщения search.requests в DLQ и возвращает их обратно
+type DeadLetterQueue struct {
+	redis  RedisDeadLetter
+	group  string
+	source string
+	stream string
+}
+
+// NewDeadLetterQueue создает DLQ для search.requests; group — consumer group, в которой подтверждаются перенесенные сообщения
+func NewDeadLetterQueue(redis RedisDeadLetter, group string) *DeadLetterQueue {
+	return &DeadLetterQueue{
+		redis:  redis,
+		group:  group,
+		source: "search.requests",
+		stream: DeadLetterStream,
+	}
+}
+
+// Move копирует событие в DLQ с причиной и количеством доставок, затем подтверждает исходное сообщение
+func (q *DeadLetterQueue) Move(ctx context.Context, event map[string]interface{}, reason string) (string, error) {
+	originalID := getString(event, MessageIDField)
+
+	original := make(map[string]interface{}, len(event))
+	for k, v := range event {
+		if k == MessageIDField || k == DeliveryCountField {
+			continue
+		}
+		original[k] = v
+	}
+	originalJSON, err := json.Marshal(original)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal original fields: %w", err)
+	}
+
+	id, err := q.redis.XAdd(ctx, q.stream, map[string]interface{}{
+		"original_id":    originalID,
+		"reason":         reason,
+		"delivery_count": getInt64(event, DeliveryCountField),
+		"fields":         string(originalJSON),
+		"failed_at":      time.Now().Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to publish to dlq: %w", err)
+	}
+
+	if originalID != "" {
+		if err := q.redis.XAck(ctx, q.source, q.group, originalID); err != nil {
+			return id, fmt.Errorf("failed to ack dead-lettered message: %w", err)
+		}
+	}
+
+	return id, nil
+}
+
+// List возвращает до count самых старых сообщений DLQ
+func (q *DeadLetterQueue) List(ctx context.Context, count int64) ([]DeadLetter, error) {
+	events, err := q.redis.XRange(ctx, q.stream, "-", "+", count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dlq: %w", err)
+	}
+
+	letters := make([]DeadLetter, 0, len(events))
+	for _, event := range events {
+		letter, err := parseDeadLetter(event)
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, *letter)
+	}
+
+	return letters, nil
+}
+
+// Redrive публикует исходные поля сообщения обратно в search.requests и удаляет его из DLQ
+func (q *DeadLetterQueue) Redrive(ctx context.Context, id string) (string, error) {
+	events, err := q.redis.XRange(ctx, q.stream, id, id, 1)
+	if err != nil {
+		return "", fmt.Errorf("failed to read dlq: %w", err)
+	}
+	if len(events) == 0 {
+		return "", fmt.Errorf("dlq message %s not found", id)
+	}
+
+	letter, err := parseDeadLetter(events[0])
+	if err != nil {
+		return "", err
+	}
+
+	newID, err := q.redis.XAdd(ctx, q.source, letter.Fields)
+	if err != nil {
+		return "", fmt.Errorf("failed to redrive message: %w", err)
+	}
+
+	if err := q.redis.XDel(ctx, q.stream, id); err != nil {
+		return newID, fmt.Errorf("failed to delete redriven message: %w", err)
+	}
+
+	return newID, nil
+}
+
+// parseDeadLetter парсит сообщение DLQ
+func parseDeadLetter(event map[string]interface{}) (*DeadLetter, error) {
+	letter := &DeadLetter{
+		ID:            getString(event, MessageIDField),
+		OriginalID:    getString(event, "original_id"),
+		Reason:        getString(event, "reason"),
+		DeliveryCount: getInt64(event, "delivery_count"),
+		Fields:        map[string]interface{}{},
+	}
+
+	if failedAt := getInt64(event, "failed_at"); failedAt > 0 {
+		letter.FailedAt = time.Unix(failedAt, 0).UTC()
+	}
+
+	if fields := getString(event, "fields"); fields != "" {
+		if err := json.Unmarshal([]byte(fields), &letter.Fields); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal dlq fields of %s: %w", letter.ID, err)
+		}
+	}
+
+	return letter, nil
+}
@@ -0,0 +1,135 @@
+package streams
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSearchRequestConsumer_InvalidMessageMovedToDLQ(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		streams:   make(map[string][]map[string]interface{}),
+		processed: make(map[string]bool),
+	}
+
+	dlq := NewDeadLetterQueue(mockRedis, "test-group")
+	consumer := NewSearchRequestConsumer(mockRedis, "test-group", WithDeadLetterQueue(dlq))
+
+	mockRedis.AddToStream("search.requests", map[string]interface{}{
+		MessageIDField: "1-0",
+		"request_id":   "test-request-123",
+		"params":       "invalid-json",
+	})
+
+	_, err := consumer.Consume(context.Background())
+	if !errors.Is(err, ErrDeadLettered) {
+		t.Fatalf("expected ErrDeadLettered, got %v", err)
+	}
+
+	if len(mockRedis.acked) != 1 || mockRedis.acked[0] != "1-0" {
+		t.Errorf("expected invalid message to be acked, got %v", mockRedis.acked)
+	}
+
+	letters, err := dlq.List(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(letters) != 1 {
+		t.Fatalf("expected 1 dead letter, got %d", len(letters))
+	}
+
+	letter := letters[0]
+	if letter.OriginalID != "1-0" {
+		t.Errorf("expected original id 1-0, got %s", letter.OriginalID)
+	}
+	if letter.Reason == "" {
+		t.Error("expected failure reason")
+	}
+	if letter.Fields["request_id"] != "test-request-123" || letter.Fields["params"] != "invalid-json" {
+		t.Errorf("expected original fields, got %v", letter.Fields)
+	}
+	if _, ok := letter.Fields[MessageIDField]; ok {
+		t.Error("message id should not be copied into original fields")
+	}
+}
+
+func TestPendingReclaimer_MaxDeliveriesMovedToDLQ(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		streams:   make(map[string][]map[string]interface{}),
+		processed: make(map[string]bool),
+	}
+
+	poison := testRequestEvent("1-0", "req-poison")
+	poison[DeliveryCountField] = int64(5)
+	mockRedis.AddPending(poison)
+	mockRedis.AddPending(testRequestEvent("2-0", "req-ok"))
+
+	dlq := NewDeadLetterQueue(mockRedis, "test-group")
+	reclaimer := NewPendingReclaimer(mockRedis, "test-group", time.Minute, WithReclaimDeadLetter(dlq, 5))
+
+	requests, err := reclaimer.Reclaim(context.Background())
+	if err != nil {
+		t.Fatalf("reclaim: %v", err)
+	}
+	if len(requests) != 1 || requests[0].RequestID != "req-ok" {
+		t.Fatalf("expected only req-ok to be reclaimed, got %+v", requests)
+	}
+
+	letters, err := dlq.List(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(letters) != 1 || letters[0].DeliveryCount != 6 || letters[0].Fields["request_id"] != "req-poison" {
+		t.Fatalf("expected poison request in dlq, got %+v", letters)
+	}
+	if len(mockRedis.acked) != 1 || mockRedis.acked[0] != "1-0" {
+		t.Errorf("expected poison message to be acked, got %v", mockRedis.acked)
+	}
+}
+
+func TestDeadLetterQueue_Redrive(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		streams:   make(map[string][]map[string]interface{}),
+		processed: make(map[string]bool),
+	}
+	dlq := NewDeadLetterQueue(mockRedis, "test-group")
+
+	dlqID, err := dlq.Move(context.Background(), testRequestEvent("1-0", "req-1"), "search backend unavailable")
+	if err != nil {
+		t.Fatalf("move: %v", err)
+	}
+
+	newID, err := dlq.Redrive(context.Background(), dlqID)
+	if err != nil {
+		t.Fatalf("redrive: %v", err)
+	}
+	if newID == "" {
+		t.Error("expected new message id")
+	}
+
+	if left := mockRedis.GetStreams()[DeadLetterStream]; len(left) != 0 {
+		t.Errorf("expected dlq to be empty after redrive, got %v", left)
+	}
+
+	consumer := NewSearchRequestConsumer(mockRedis, "test-group")
+	request, err := consumer.Consume(context.Background())
+	if err != nil {
+		t.Fatalf("consume redriven request: %v", err)
+	}
+	if request.RequestID != "req-1" || request.Params.Origin != "MOW" {
+		t.Errorf("unexpected redriven request: %+v", request)
+	}
+}
+
+func TestDeadLetterQueue_RedriveNotFound(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		streams:   make(map[string][]map[string]interface{}),
+		processed: make(map[string]bool),
+	}
+	dlq := NewDeadLetterQueue(mockRedis, "test-group")
+
+	if _, err := dlq.Redrive(context.Background(), "42-0"); err == nil {
+		t.Error("expected error for missing dlq message")
+	}
+}
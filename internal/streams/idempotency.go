@@ -2,47 +2,96 @@ package streams
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 )
 
-// IdempotencyTracker отслеживает обработанные запросы для предотвращения дублирования
+// ErrInProgress возвращается, если запрос дольше допустимого обрабатывается другим воркером
+var ErrInProgress = errors.New("request is being processed by another worker")
+
+// ErrLeaseLost возвращается, если lease истек во время обработки и ключ уже захватил другой воркер
+var ErrLeaseLost = errors.New("idempotency lease was taken over by another worker")
+
+const (
+	doneValue   = "1"
+	leasePrefix = "processing:"
+)
+
+// IdempotencyTracker отслеживает обработанные запросы для предотвращения дублирования.
+// Ключ processed:<request_id> проходит два состояния: lease "processing:<token>" с коротким TTL,
+// который атомарно захватывает один воркер (SET NX), и финальное с TTL 24 часа —
+// "1" или JSON StoredResult с опубликованным результатом.
 type IdempotencyTracker struct {
-	redis RedisIdempotency
-	ttl   time.Duration
+	redis        RedisIdempotency
+	ttl          time.Duration
+	leaseTTL     time.Duration
+	waitTimeout  time.Duration
+	pollInterval time.Duration
 }
 
 // RedisIdempotency интерфейс для работы с Redis для идемпотентности
 type RedisIdempotency interface {
 	SetWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error
 	Get(ctx context.Context, key string) (interface{}, error)
+	// SetNX атомарно устанавливает ключ, только если его нет; возвращает true при успехе
+	SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error)
+	// DelIfEqual удаляет ключ, только если его значение равно value
+	DelIfEqual(ctx context.Context, key string, value interface{}) (bool, error)
+	// CompareAndSet атомарно записывает value с ttl, если ключа нет или его значение равно expected
+	CompareAndSet(ctx context.Context, key string, expected, value interface{}, ttl time.Duration) (bool, error)
+}
+
+// IdempotencyOption настраивает IdempotencyTracker
+type IdempotencyOption func(*IdempotencyTracker)
+
+// WithLeaseTTL задает время жизни lease; воркер, упавший во время обработки, теряет его по истечении TTL
+func WithLeaseTTL(d time.Duration) IdempotencyOption {
+	return func(t *IdempotencyTracker) { t.leaseTTL = d }
+}
+
+// WithWaitTimeout задает, сколько дубликат ждет завершения обработки первым воркером
+func WithWaitTimeout(d time.Duration) IdempotencyOption {
+	return func(t *IdempotencyTracker) { t.waitTimeout = d }
+}
+
+// WithWaitPollInterval задает период проверки состояния lease при ожидании
+func WithWaitPollInterval(d time.Duration) IdempotencyOption {
+	return func(t *IdempotencyTracker) { t.pollInterval = d }
 }
 
 // NewIdempotencyTracker создает новый трекер идемпотентности
-func NewIdempotencyTracker(redis RedisIdempotency) *IdempotencyTracker {
-	return &IdempotencyTracker{
-		redis: redis,
-		ttl:   24 * time.Hour, // TTL 24 часа
+func NewIdempotencyTracker(redis RedisIdempotency, opts ...IdempotencyOption) *IdempotencyTracker {
+	t := &IdempotencyTracker{
+		redis:        redis,
+		ttl:          24 * time.Hour, // TTL 24 часа
+		leaseTTL:     2 * time.Minute,
+		waitTimeout:  30 * time.Second,
+		pollInterval: 100 * time.Millisecond,
+	}
+	for _, o := range opts {
+		o(t)
 	}
+	return t
 }
 
 // IsProcessed проверяет, был ли запрос уже обработан
 func (t *IdempotencyTracker) IsProcessed(ctx context.Context, requestID string) (bool, error) {
-	key := fmt.Sprintf("processed:%s", requestID)
-
-	value, err := t.redis.Get(ctx, key)
+	value, err := t.redis.Get(ctx, idempotencyKey(requestID))
 	if err != nil {
 		return false, fmt.Errorf("failed to check processed status: %w", err)
 	}
 
-	return value != nil, nil
+	return value != nil && !isLease(value), nil
 }
 
 // MarkProcessed отмечает запрос как обработанный
 func (t *IdempotencyTracker) MarkProcessed(ctx context.Context, requestID string) error {
-	key := fmt.Sprintf("processed:%s", requestID)
-
-	err := t.redis.SetWithTTL(ctx, key, "1", t.ttl)
+	err := t.redis.SetWithTTL(ctx, idempotencyKey(requestID), doneValue, t.ttl)
 	if err != nil {
 		return fmt.Errorf("failed to mark as processed: %w", err)
 	}
@@ -50,27 +99,150 @@ func (t *IdempotencyTracker) MarkProcessed(ctx context.Context, requestID string
 	return nil
 }
 
-// ProcessWithIdempotency выполняет обработку с проверкой идемпотентности
+// StoredResult финальное состояние запроса, сохраняемое под ключом идемпотентности
+type StoredResult struct {
+	Result          *SearchResult `json:"result,omitempty"`
+	MessageID       string        `json:"message_id,omitempty"`        // ID результата в search.results
+	SourceMessageID string        `json:"source_message_id,omitempty"` // ID запроса в search.requests
+}
+
+// ProcessWithIdempotency выполняет обработку не более одного раза для requestID.
+// Обработку выполняет только воркер, захвативший lease; конкурентные дубликаты ждут
+// ее завершения и возвращают nil, а по истечении waitTimeout — ErrInProgress.
+// Если processor вернул ошибку, lease освобождается и запрос можно обработать повторно.
 func (t *IdempotencyTracker) ProcessWithIdempotency(ctx context.Context, requestID string, processor func() error) error {
-	// Проверяем, был ли уже обработан
-	processed, err := t.IsProcessed(ctx, requestID)
+	_, _, err := t.ProcessWithResult(ctx, requestID, func() (*StoredResult, error) {
+		return nil, processor()
+	})
+	return err
+}
+
+// ProcessWithResult работает как ProcessWithIdempotency, но сохраняет результат processor
+// в финальном состоянии ключа. Для дубликата возвращает сохраненный результат и duplicate=true
+// (результат может быть nil, если запрос отмечен без него, например через MarkProcessed).
+// Финальное состояние записывается, только пока ключ хранит свой lease (или уже истек без
+// замены); если lease перехватил другой воркер, его состояние не перезаписывается, а
+// возвращается ErrLeaseLost.
+func (t *IdempotencyTracker) ProcessWithResult(ctx context.Context, requestID string, processor func() (*StoredResult, error)) (*StoredResult, bool, error) {
+	lease, existing, err := t.claim(ctx, requestID)
 	if err != nil {
-		return fmt.Errorf("failed to check idempotency: %w", err)
+		return nil, false, err
 	}
-
-	if processed {
-		return nil // Уже обработан, пропускаем
+	if lease == "" {
+		stored, err := decodeStoredResult(existing)
+		return stored, true, err
 	}
 
 	// Выполняем обработку
-	if err := processor(); err != nil {
-		return fmt.Errorf("processing failed: %w", err)
+	stored, err := processor()
+	if err != nil {
+		if _, relErr := t.redis.DelIfEqual(ctx, idempotencyKey(requestID), lease); relErr != nil {
+			return nil, false, fmt.Errorf("processing failed: %w (lease release failed: %v)", err, relErr)
+		}
+		return nil, false, fmt.Errorf("processing failed: %w", err)
 	}
 
-	// Отмечаем как обработанный
-	if err := t.MarkProcessed(ctx, requestID); err != nil {
-		return fmt.Errorf("failed to mark as processed: %w", err)
+	// Отмечаем как обработанный вместе с результатом
+	var value interface{} = doneValue
+	if stored != nil {
+		data, err := json.Marshal(stored)
+		if err != nil {
+			return stored, false, fmt.Errorf("failed to marshal stored result: %w", err)
+		}
+		value = string(data)
+	}
+	ok, err := t.redis.CompareAndSet(ctx, idempotencyKey(requestID), lease, value, t.ttl)
+	if err != nil {
+		return stored, false, fmt.Errorf("failed to mark as processed: %w", err)
+	}
+	if !ok {
+		return stored, false, ErrLeaseLost
 	}
 
-	return nil
+	return stored, false, nil
+}
+
+// GetResult возвращает сохраненный результат обработанного запроса или nil
+func (t *IdempotencyTracker) GetResult(ctx context.Context, requestID string) (*StoredResult, error) {
+	value, err := t.redis.Get(ctx, idempotencyKey(requestID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stored result: %w", err)
+	}
+	if value == nil || isLease(value) {
+		return nil, nil
+	}
+	return decodeStoredResult(value)
+}
+
+// claim захватывает lease для requestID. Если запрос уже обработан, lease пустой,
+// а existing содержит финальное значение ключа.
+func (t *IdempotencyTracker) claim(ctx context.Context, requestID string) (lease string, existing interface{}, err error) {
+	key := idempotencyKey(requestID)
+	lease = leasePrefix + newLeaseToken()
+	deadline := time.Now().Add(t.waitTimeout)
+
+	for {
+		ok, err := t.redis.SetNX(ctx, key, lease, t.leaseTTL)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to check idempotency: %w", err)
+		}
+		if ok {
+			return lease, nil, nil
+		}
+
+		value, err := t.redis.Get(ctx, key)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to check idempotency: %w", err)
+		}
+		if value != nil && !isLease(value) {
+			return "", value, nil
+		}
+		if value == nil {
+			continue // lease освободили или он истек между SET NX и GET
+		}
+
+		// Запрос обрабатывается другим воркером — ждем финального состояния
+		if time.Now().After(deadline) {
+			return "", nil, ErrInProgress
+		}
+		timer := time.NewTimer(t.pollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return "", nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// decodeStoredResult разбирает финальное значение ключа; "1" означает обработку без результата
+func decodeStoredResult(value interface{}) (*StoredResult, error) {
+	s, ok := value.(string)
+	if !ok || s == doneValue || s == "" {
+		return nil, nil
+	}
+
+	var stored StoredResult
+	if err := json.Unmarshal([]byte(s), &stored); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal stored result: %w", err)
+	}
+	return &stored, nil
+}
+
+func idempotencyKey(requestID string) string {
+	return fmt.Sprintf("processed:%s", requestID)
+}
+
+func isLease(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && strings.HasPrefix(s, leasePrefix)
+}
+
+// newLeaseToken генерирует уникальный идентификатор владельца lease
+func newLeaseToken() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
 }
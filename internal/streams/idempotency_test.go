@@ -2,7 +2,11 @@ package streams
 
 import (
 	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestIdempotencyTracker_IsProcessed(t *testing.T) {
@@ -119,3 +123,186 @@ func TestIdempotencyTracker_ConcurrentAccess(t *testing.T) {
 		t.Error("Expected request to be processed")
 	}
 }
+
+func TestIdempotencyTracker_ProcessWithIdempotency_ConcurrentDuplicates(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		processed: make(map[string]bool),
+	}
+
+	tracker := NewIdempotencyTracker(mockRedis, WithWaitPollInterval(time.Millisecond))
+
+	var calls int32
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- tracker.ProcessWithIdempotency(context.Background(), "test-request-123", func() error {
+				atomic.AddInt32(&calls, 1)
+				time.Sleep(10 * time.Millisecond)
+				return nil
+			})
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("expected duplicates to wait for the first result, got %v", err)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("expected processor to run exactly once, got %d", calls)
+	}
+
+	processed, err := tracker.IsProcessed(context.Background(), "test-request-123")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !processed {
+		t.Error("Expected request to be processed")
+	}
+}
+
+func TestIdempotencyTracker_ProcessWithIdempotency_ReleasesLeaseOnError(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		processed: make(map[string]bool),
+	}
+
+	tracker := NewIdempotencyTracker(mockRedis)
+
+	err := tracker.ProcessWithIdempotency(context.Background(), "test-request-123", func() error {
+		return errors.New("publish failed")
+	})
+	if err == nil {
+		t.Fatal("Expected processing error")
+	}
+
+	processed, err := tracker.IsProcessed(context.Background(), "test-request-123")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if processed {
+		t.Error("Expected failed request not to be marked as processed")
+	}
+
+	called := false
+	err = tracker.ProcessWithIdempotency(context.Background(), "test-request-123", func() error {
+		called = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected retry to succeed, got %v", err)
+	}
+	if !called {
+		t.Error("Expected retry to run processor after lease release")
+	}
+}
+
+func TestIdempotencyTracker_ProcessWithIdempotency_ExpiredLeaseOfCrashedWorker(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		processed: make(map[string]bool),
+	}
+
+	tracker := NewIdempotencyTracker(mockRedis, WithWaitTimeout(20*time.Millisecond), WithWaitPollInterval(time.Millisecond))
+
+	// упавший воркер захватил lease и не освободил его
+	ok, _ := mockRedis.SetNX(context.Background(), "processed:test-request-123", "processing:crashed", time.Minute)
+	if !ok {
+		t.Fatal("expected lease to be claimed")
+	}
+
+	err := tracker.ProcessWithIdempotency(context.Background(), "test-request-123", func() error {
+		t.Error("processor must not run while lease is held")
+		return nil
+	})
+	if !errors.Is(err, ErrInProgress) {
+		t.Fatalf("Expected ErrInProgress, got %v", err)
+	}
+
+	// Симулируем истечение TTL lease
+	mockRedis.ExpireKey("processed:test-request-123")
+
+	called := false
+	err = tracker.ProcessWithIdempotency(context.Background(), "test-request-123", func() error {
+		called = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !called {
+		t.Error("Expected processor to run after lease expiration")
+	}
+}
+
+func TestIdempotencyTracker_ProcessWithResult_LeaseTakenOverDuringProcessing(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		processed: make(map[string]bool),
+	}
+	tracker := NewIdempotencyTracker(mockRedis)
+
+	// обработка пережила lease, и ключ захватил второй воркер
+	_, _, err := tracker.ProcessWithResult(context.Background(), "test-request-123", func() (*StoredResult, error) {
+		mockRedis.ExpireKey("processed:test-request-123")
+		mockRedis.SetNX(context.Background(), "processed:test-request-123", "processing:other", time.Minute)
+		return &StoredResult{MessageID: "1-0"}, nil
+	})
+	if !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("Expected ErrLeaseLost, got %v", err)
+	}
+
+	value, _ := mockRedis.Get(context.Background(), "processed:test-request-123")
+	if value != "processing:other" {
+		t.Errorf("Expected lease of the second worker to stay, got %v", value)
+	}
+}
+
+func TestIdempotencyTracker_ProcessWithResult_ReturnsStoredResultForDuplicate(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		processed: make(map[string]bool),
+	}
+
+	tracker := NewIdempotencyTracker(mockRedis)
+
+	result := &SearchResult{
+		RequestID:     "test-request-123",
+		CorrelationID: "test-correlation-456",
+		ChatID:        "12345",
+		Timestamp:     time.Now(),
+	}
+
+	stored, duplicate, err := tracker.ProcessWithResult(context.Background(), "test-request-123", func() (*StoredResult, error) {
+		return &StoredResult{Result: result, MessageID: "100-0"}, nil
+	})
+	if err != nil || duplicate || stored.Result != result {
+		t.Fatalf("unexpected first processing: %+v %v %v", stored, duplicate, err)
+	}
+
+	stored, duplicate, err = tracker.ProcessWithResult(context.Background(), "test-request-123", func() (*StoredResult, error) {
+		t.Error("processor must not run for duplicate")
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !duplicate {
+		t.Error("Expected duplicate flag")
+	}
+	if stored == nil || stored.MessageID != "100-0" {
+		t.Fatalf("Expected stored result, got %+v", stored)
+	}
+	if stored.Result.CorrelationID != result.CorrelationID || !stored.Result.Timestamp.Equal(result.Timestamp) {
+		t.Errorf("Expected identical result, got %+v", stored.Result)
+	}
+
+	fromGet, err := tracker.GetResult(context.Background(), "test-request-123")
+	if err != nil || fromGet == nil || fromGet.MessageID != "100-0" {
+		t.Errorf("Expected GetResult to return stored result, got %+v %v", fromGet, err)
+	}
+}
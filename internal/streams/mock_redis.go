@@ -3,16 +3,26 @@ package streams
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 )
 
-// mockRedisClient для тестирования
+// mockRedisClient для тестирования; безопасен для конкурентного использования
 type mockRedisClient struct {
+	mu        sync.Mutex
 	streams   map[string][]map[string]interface{}
 	processed map[string]bool
+	values    map[string]interface{}
+	acked     []string
+	touched   []string
+	pending   []map[string]interface{}
+	seq       int64
 }
 
 func (m *mockRedisClient) AddToStream(streamName string, event map[string]interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if m.streams[streamName] == nil {
 		m.streams[streamName] = make([]map[string]interface{}, 0)
 	}
@@ -20,53 +30,215 @@ func (m *mockRedisClient) AddToStream(streamName string, event map[string]interf
 }
 
 func (m *mockRedisClient) XReadGroup(ctx context.Context, group, consumer, stream string, count int64) ([]map[string]interface{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	streams, exists := m.streams[stream]
 	if !exists || len(streams) == 0 {
 		return nil, nil // No data available
 	}
 
-	// Возвращаем первое событие и удаляем его
-	event := streams[0]
-	m.streams[stream] = streams[1:]
+	// Возвращаем до count первых событий и удаляем их
+	n := int(count)
+	if n <= 0 || n > len(streams) {
+		n = len(streams)
+	}
+	events := streams[:n]
+	m.streams[stream] = streams[n:]
+
+	return events, nil
+}
+
+// AddPending добавляет сообщение, прочитанное, но не подтвержденное другим консьюмером
+func (m *mockRedisClient) AddPending(event map[string]interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pending = append(m.pending, event)
+}
+
+// XAutoClaim отдает pending сообщения порциями по count и увеличивает счетчик доставок
+func (m *mockRedisClient) XAutoClaim(ctx context.Context, stream, group, consumer string, minIdle time.Duration, start string, count int64) ([]map[string]interface{}, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := int(count)
+	if n <= 0 || n > len(m.pending) {
+		n = len(m.pending)
+	}
+
+	claimed := m.pending[:n]
+	m.pending = m.pending[n:]
+	for _, event := range claimed {
+		event[DeliveryCountField] = getInt64(event, DeliveryCountField) + 1
+	}
 
-	return []map[string]interface{}{event}, nil
+	next := "0-0"
+	if len(m.pending) > 0 {
+		next = getString(m.pending[0], MessageIDField)
+	}
+	return claimed, next, nil
 }
 
 func (m *mockRedisClient) XAck(ctx context.Context, stream, group, messageID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.acked = append(m.acked, messageID)
 	return nil
 }
 
+// XClaimJustID запоминает ID сообщений, простой которых сбросил пул
+func (m *mockRedisClient) XClaimJustID(ctx context.Context, stream, group, consumer string, ids []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.touched = append(m.touched, ids...)
+	return nil
+}
+
+// Touched возвращает ID сообщений, переданных в XClaimJustID
+func (m *mockRedisClient) Touched() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]string(nil), m.touched...)
+}
+
 func (m *mockRedisClient) SetWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
-	m.processed[key] = true
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.set(key, value)
 	return nil
 }
 
 func (m *mockRedisClient) Get(ctx context.Context, key string) (interface{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if processed, exists := m.processed[key]; exists && processed {
+		if value, ok := m.values[key]; ok {
+			return value, nil
+		}
 		return "1", nil
 	}
 	return nil, nil
 }
 
+func (m *mockRedisClient) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.processed[key] {
+		return false, nil
+	}
+	m.set(key, value)
+	return true, nil
+}
+
+func (m *mockRedisClient) DelIfEqual(ctx context.Context, key string, value interface{}) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.processed[key] || m.values[key] != value {
+		return false, nil
+	}
+	delete(m.processed, key)
+	delete(m.values, key)
+	return true, nil
+}
+
+func (m *mockRedisClient) CompareAndSet(ctx context.Context, key string, expected, value interface{}, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.processed[key] && m.values[key] != expected {
+		return false, nil
+	}
+	m.set(key, value)
+	return true, nil
+}
+
+func (m *mockRedisClient) set(key string, value interface{}) {
+	if m.values == nil {
+		m.values = make(map[string]interface{})
+	}
+	m.processed[key] = true
+	m.values[key] = value
+}
+
 func (m *mockRedisClient) GetStreams() map[string][]map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	return m.streams
 }
 
 func (m *mockRedisClient) XAdd(ctx context.Context, stream string, fields map[string]interface{}) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if m.streams[stream] == nil {
 		m.streams[stream] = make([]map[string]interface{}, 0)
 	}
 
 	// Генерируем mock message ID
-	messageID := fmt.Sprintf("%d-0", time.Now().UnixNano())
+	m.seq++
+	messageID := fmt.Sprintf("%d-%d", time.Now().UnixNano(), m.seq)
 
-	// Добавляем timestamp
-	fields["timestamp"] = time.Now().Unix()
+	// Добавляем timestamp, если продюсер его не передал
+	if _, ok := fields["timestamp"]; !ok {
+		fields["timestamp"] = time.Now().Unix()
+	}
+	fields[MessageIDField] = messageID
 
 	m.streams[stream] = append(m.streams[stream], fields)
 	return messageID, nil
 }
 
+// XRange возвращает сообщения stream: все для "-"/"+" или одно по ID
+func (m *mockRedisClient) XRange(ctx context.Context, stream, start, end string, count int64) ([]map[string]interface{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var events []map[string]interface{}
+	for _, event := range m.streams[stream] {
+		if start != "-" && getString(event, MessageIDField) != start {
+			continue
+		}
+		events = append(events, event)
+		if count > 0 && int64(len(events)) >= count {
+			break
+		}
+	}
+	return events, nil
+}
+
+func (m *mockRedisClient) XDel(ctx context.Context, stream string, ids ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var kept []map[string]interface{}
+	for _, event := range m.streams[stream] {
+		deleted := false
+		for _, id := range ids {
+			if getString(event, MessageIDField) == id {
+				deleted = true
+			}
+		}
+		if !deleted {
+			kept = append(kept, event)
+		}
+	}
+	m.streams[stream] = kept
+	return nil
+}
+
 func (m *mockRedisClient) ExpireKey(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	delete(m.processed, key)
+	delete(m.values, key)
 }
@@ -0,0 +1,352 @@
+package streams
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// WorkerPool обрабатывает search.requests пулом из N воркеров.
+// Запросы читаются пачками; прочитанных, но не завершенных запросов не больше
+// maxInFlight+readAhead. Очередь к воркерам обходит чаты по кругу и не дает одному чату
+// больше chatLimit воркеров, чтобы один чат не занимал весь пул. Пока запросы ждут
+// воркера, пул сбрасывает их простой в pending, и reclaimer не забирает их себе.
+type WorkerPool struct {
+	worker       *SearchWorker
+	size         int
+	batch        int64
+	maxInFlight  int
+	readAhead    int
+	readAheadSet bool
+	chatLimit    int
+	pendingIdle  time.Duration
+}
+
+// PoolOption настраивает WorkerPool
+type PoolOption func(*WorkerPool)
+
+// WithPoolSize задает количество воркеров
+func WithPoolSize(n int) PoolOption { return func(p *WorkerPool) { p.size = n } }
+
+// WithBatchSize задает COUNT для одного XREADGROUP
+func WithBatchSize(n int64) PoolOption { return func(p *WorkerPool) { p.batch = n } }
+
+// WithMaxInFlight задает базовый лимит прочитанных из stream и еще не завершенных запросов
+// (0 — 2×size). Вместе с read-ahead пул держит не больше maxInFlight+readAhead запросов;
+// значение меньше size оставляет часть воркеров без работы.
+func WithMaxInFlight(n int) PoolOption { return func(p *WorkerPool) { p.maxInFlight = n } }
+
+// WithReadAhead разрешает читать еще n запросов сверх maxInFlight (0 — не читать наперед,
+// по умолчанию 4×size). Пока чат, заваливший stream запросами, упирается в chatLimit,
+// прочитанные наперед запросы других чатов получают свободных воркеров раньше его очереди.
+func WithReadAhead(n int) PoolOption {
+	return func(p *WorkerPool) { p.readAhead, p.readAheadSet = n, true }
+}
+
+// WithChatConcurrency ограничивает число запросов одного чата, обрабатываемых одновременно
+func WithChatConcurrency(n int) PoolOption { return func(p *WorkerPool) { p.chatLimit = n } }
+
+// WithPendingIdle сообщает пулу простой, после которого reclaimer забирает pending entries
+// (SEARCH_PENDING_IDLE). Пул сбрасывает простой своих запросов втрое чаще, чтобы запросы,
+// ждущие воркера за очередью другого чата, не перехватывались и не копили доставки.
+func WithPendingIdle(d time.Duration) PoolOption { return func(p *WorkerPool) { p.pendingIdle = d } }
+
+// NewWorkerPool создает пул поверх SearchWorker: чтение пачками, обработка через SearchWorker.Handle.
+// Недопустимые значения опций логируются как search_pool_invalid_option и заменяются значениями по умолчанию.
+func NewWorkerPool(worker *SearchWorker, opts ...PoolOption) *WorkerPool {
+	p := &WorkerPool{
+		worker: worker,
+		size:   4,
+		batch:  10,
+	}
+	for _, o := range opts {
+		o(p)
+	}
+	if p.size <= 0 {
+		p.invalid("size", p.size, 1)
+		p.size = 1
+	}
+	if p.batch <= 0 {
+		p.invalid("batch", int(p.batch), 1)
+		p.batch = 1
+	}
+	if p.maxInFlight < 0 {
+		p.invalid("max_in_flight", p.maxInFlight, 2*p.size)
+		p.maxInFlight = 0
+	}
+	if p.maxInFlight == 0 {
+		p.maxInFlight = 2 * p.size
+	}
+	if p.readAhead < 0 {
+		p.invalid("read_ahead", p.readAhead, 4*p.size)
+		p.readAheadSet = false
+	}
+	if !p.readAheadSet {
+		p.readAhead = 4 * p.size
+	}
+	if p.chatLimit < 0 {
+		p.invalid("chat_concurrency", p.chatLimit, max(1, p.size/2))
+		p.chatLimit = 0
+	}
+	if p.chatLimit == 0 {
+		p.chatLimit = max(1, p.size/2)
+	}
+	return p
+}
+
+// invalid логирует недопустимое значение опции и значение, которое будет использовано вместо него
+func (p *WorkerPool) invalid(option string, value, fallback int) {
+	p.worker.logError("search_pool_invalid_option", map[string]interface{}{
+		"option":   option,
+		"value":    value,
+		"fallback": fallback,
+	})
+}
+
+// capacity наибольшее число прочитанных, но не завершенных запросов
+func (p *WorkerPool) capacity() int {
+	return p.maxInFlight + p.readAhead
+}
+
+// Run читает и обрабатывает запросы до отмены контекста. После отмены чтение
+// прекращается, а уже прочитанные запросы дорабатываются и подтверждаются.
+func (p *WorkerPool) Run(ctx context.Context) error {
+	queue := newFairQueue(p.chatLimit)
+	stop := context.AfterFunc(ctx, queue.wake)
+	defer stop()
+
+	// простой сбрасывается и во время дообработки после отмены ctx
+	touchCtx, stopTouch := context.WithCancel(context.WithoutCancel(ctx))
+	touched := make(chan struct{})
+	go func() {
+		defer close(touched)
+		p.touch(touchCtx, queue)
+	}()
+	defer func() {
+		stopTouch()
+		<-touched
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < p.size; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				request, ok := queue.pop()
+				if !ok {
+					return
+				}
+				_ = p.worker.Handle(context.WithoutCancel(ctx), request)
+				queue.done(request)
+			}
+		}()
+	}
+
+	p.read(ctx, queue)
+
+	queue.close()
+	wg.Wait()
+	return nil
+}
+
+// read читает пачки запросов, пока есть свободные слоты (capacity),
+// и раскладывает их по очередям чатов
+func (p *WorkerPool) read(ctx context.Context, queue *fairQueue) {
+	for {
+		free := queue.waitCapacity(ctx, p.capacity())
+		if ctx.Err() != nil {
+			return
+		}
+
+		count := p.batch
+		if int64(free) < count {
+			count = int64(free)
+		}
+
+		requests, err := p.worker.consumer.ConsumeBatch(ctx, count)
+		if ctx.Err() != nil && len(requests) == 0 {
+			return
+		}
+		if err != nil {
+			if errors.Is(err, ErrNoEvents) {
+				p.worker.wait(ctx)
+				continue
+			}
+			p.worker.logError("search_worker_consume_failed", map[string]interface{}{"error": err.Error()})
+			if len(requests) == 0 && !errors.Is(err, ErrDeadLettered) {
+				p.worker.wait(ctx)
+				continue
+			}
+		}
+
+		for _, request := range requests {
+			queue.push(request)
+		}
+	}
+}
+
+// touch каждую треть pendingIdle сбрасывает простой всех запросов, которые держит пул,
+// до отмены ctx; без pendingIdle ничего не делает
+func (p *WorkerPool) touch(ctx context.Context, queue *fairQueue) {
+	if p.pendingIdle <= 0 {
+		return
+	}
+	t := time.NewTicker(p.pendingIdle / 3)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		held := queue.held()
+		if len(held) == 0 {
+			continue
+		}
+		if err := p.worker.consumer.Touch(ctx, held); err != nil && ctx.Err() == nil {
+			p.worker.logError("search_pool_touch_failed", map[string]interface{}{
+				"count": len(held),
+				"error": err.Error(),
+			})
+		}
+	}
+}
+
+// fairQueue очередь запросов с round-robin обходом чатов
+type fairQueue struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	chats     map[string][]*SearchRequest
+	order     []string                    // чаты с ожидающими запросами в порядке обслуживания
+	active    map[string]int              // запросы чата в обработке
+	requests  map[*SearchRequest]struct{} // запросы в очереди и в обработке
+	chatLimit int                         // максимум запросов чата в обработке, 0 — без ограничения
+	inFlight  int                         // запросы в очереди и в обработке
+	closed    bool
+}
+
+func newFairQueue(chatLimit int) *fairQueue {
+	q := &fairQueue{
+		chats:     make(map[string][]*SearchRequest),
+		active:    make(map[string]int),
+		requests:  make(map[*SearchRequest]struct{}),
+		chatLimit: chatLimit,
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// push добавляет запрос в очередь его чата
+func (q *fairQueue) push(request *SearchRequest) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.chats[request.ChatID]) == 0 {
+		q.order = append(q.order, request.ChatID)
+	}
+	q.chats[request.ChatID] = append(q.chats[request.ChatID], request)
+	q.requests[request] = struct{}{}
+	q.inFlight++
+	q.cond.Broadcast()
+}
+
+// pop выдает следующий запрос очередного чата; чат с оставшимися запросами уходит в конец круга.
+// Чаты, у которых в обработке уже chatLimit запросов, пропускаются без потери места в круге.
+// Возвращает false, когда очередь закрыта и пуста.
+func (q *fairQueue) pop() (*SearchRequest, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for {
+		if i := q.next(); i >= 0 {
+			chat := q.order[i]
+			q.order = append(q.order[:i], q.order[i+1:]...)
+
+			pending := q.chats[chat]
+			request := pending[0]
+			if len(pending) > 1 {
+				q.chats[chat] = pending[1:]
+				q.order = append(q.order, chat)
+			} else {
+				delete(q.chats, chat)
+			}
+			q.active[chat]++
+			return request, true
+		}
+		if q.closed && len(q.order) == 0 {
+			return nil, false
+		}
+		q.cond.Wait()
+	}
+}
+
+// next возвращает индекс в order первого чата, который может взять еще один запрос, или -1
+func (q *fairQueue) next() int {
+	for i, chat := range q.order {
+		if q.chatLimit <= 0 || q.active[chat] < q.chatLimit {
+			return i
+		}
+	}
+	return -1
+}
+
+// done освобождает слот чата после завершения обработки запроса
+func (q *fairQueue) done(request *SearchRequest) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.inFlight--
+	delete(q.requests, request)
+	q.active[request.ChatID]--
+	if q.active[request.ChatID] <= 0 {
+		delete(q.active, request.ChatID)
+	}
+	q.cond.Broadcast()
+}
+
+// held возвращает запросы в очереди и в обработке
+func (q *fairQueue) held() []*SearchRequest {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	held := make([]*SearchRequest, 0, len(q.requests))
+	for request := range q.requests {
+		held = append(held, request)
+	}
+	return held
+}
+
+// waitCapacity ждет свободного слота и возвращает их количество (0 при отмене ctx)
+func (q *fairQueue) waitCapacity(ctx context.Context, max int) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for q.inFlight >= max {
+		if ctx.Err() != nil {
+			return 0
+		}
+		q.cond.Wait()
+	}
+	return max - q.inFlight
+}
+
+// close сообщает воркерам, что новых запросов не будет
+func (q *fairQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.cond.Broadcast()
+}
+
+// wake будит ожидающих, например при отмене контекста
+func (q *fairQueue) wake() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.cond.Broadcast()
+}
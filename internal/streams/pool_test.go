@@ -0,0 +1,314 @@
+package streams
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	app "aviasales-bot/search-service/internal/application"
+)
+
+// slowSearcher потокобезопасный Searcher, фиксирующий параллельность поиска;
+// остальные методы берет из fakeSearcher
+type slowSearcher struct {
+	fakeSearcher
+	delay    time.Duration
+	active   int32
+	maxSeen  int32
+	searches int32
+}
+
+func (s *slowSearcher) SearchCheap(_ context.Context, p app.SearchParams) ([]app.Flight, error) {
+	n := atomic.AddInt32(&s.active, 1)
+	defer atomic.AddInt32(&s.active, -1)
+	for {
+		seen := atomic.LoadInt32(&s.maxSeen)
+		if n <= seen || atomic.CompareAndSwapInt32(&s.maxSeen, seen, n) {
+			break
+		}
+	}
+	atomic.AddInt32(&s.searches, 1)
+	time.Sleep(s.delay)
+	return []app.Flight{{Origin: p.Origin, Destination: p.Destination, Price: 10000}}, nil
+}
+
+func poolRequestEvent(i int, chatID string) map[string]interface{} {
+	event := testRequestEvent(fmt.Sprintf("%d-0", i+1), fmt.Sprintf("req-%d", i+1))
+	event["chat_id"] = chatID
+	return event
+}
+
+func TestFairQueue_RoundRobinAcrossChats(t *testing.T) {
+	q := newFairQueue(0)
+	for i := 0; i < 3; i++ {
+		q.push(&SearchRequest{RequestID: fmt.Sprintf("spam-%d", i), ChatID: "spammer"})
+	}
+	q.push(&SearchRequest{RequestID: "calm-0", ChatID: "calm"})
+	q.push(&SearchRequest{RequestID: "other-0", ChatID: "other"})
+
+	var order []string
+	for i := 0; i < 5; i++ {
+		request, ok := q.pop()
+		if !ok {
+			t.Fatal("expected request")
+		}
+		order = append(order, request.RequestID)
+	}
+
+	expected := []string{"spam-0", "calm-0", "other-0", "spam-1", "spam-2"}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("expected order %v, got %v", expected, order)
+		}
+	}
+
+	q.close()
+	if _, ok := q.pop(); ok {
+		t.Error("expected closed empty queue to stop workers")
+	}
+}
+
+// Тест: чат, достигший лимита одновременных запросов, пропускается, пока его запрос не завершится
+func TestFairQueue_ChatLimit(t *testing.T) {
+	q := newFairQueue(1)
+	q.push(&SearchRequest{RequestID: "spam-0", ChatID: "spammer"})
+	q.push(&SearchRequest{RequestID: "spam-1", ChatID: "spammer"})
+	q.push(&SearchRequest{RequestID: "calm-0", ChatID: "calm"})
+
+	first, _ := q.pop()
+	second, _ := q.pop()
+	if first.RequestID != "spam-0" || second.RequestID != "calm-0" {
+		t.Fatalf("expected spam-0, calm-0, got %s, %s", first.RequestID, second.RequestID)
+	}
+
+	popped := make(chan *SearchRequest, 1)
+	go func() {
+		request, _ := q.pop()
+		popped <- request
+	}()
+	select {
+	case request := <-popped:
+		t.Fatalf("expected spammer to wait for its running request, got %s", request.RequestID)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	q.done(first)
+	select {
+	case request := <-popped:
+		if request.RequestID != "spam-1" {
+			t.Errorf("expected spam-1, got %s", request.RequestID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected spam-1 after spam-0 is done")
+	}
+}
+
+// Тест: запрос другого чата за потоком запросов одного чата, превышающим maxInFlight,
+// читается наперед и обрабатывается раньше большей части этого потока
+func TestWorkerPool_FloodingChatDoesNotStarveOthers(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		streams:   make(map[string][]map[string]interface{}),
+		processed: make(map[string]bool),
+	}
+	searcher := &slowSearcher{delay: 5 * time.Millisecond}
+	worker, monitor := newTestWorker(mockRedis, searcher)
+
+	const spam = 30
+	for i := 0; i < spam; i++ {
+		mockRedis.AddToStream("search.requests", poolRequestEvent(i, "spammer"))
+	}
+	calm := poolRequestEvent(spam, "calm")
+	mockRedis.AddToStream("search.requests", calm)
+
+	pool := NewWorkerPool(worker, WithPoolSize(2), WithBatchSize(4), WithMaxInFlight(4), WithReadAhead(8), WithChatConcurrency(1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = pool.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for monitor.GetMetrics().ProcessedCount < spam+1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	mockRedis.mu.Lock()
+	acked := append([]string(nil), mockRedis.acked...)
+	mockRedis.mu.Unlock()
+	if len(acked) != spam+1 {
+		t.Fatalf("expected %d acks, got %d", spam+1, len(acked))
+	}
+
+	position := -1
+	for i, id := range acked {
+		if id == calm[MessageIDField] {
+			position = i
+		}
+	}
+	// при чтении запроса calm в очереди остается почти весь read-ahead запросов spammer
+	if after := len(acked) - 1 - position; after < 5 {
+		t.Errorf("expected calm request well before the end of the flood, processed %d of %d with %d after it", position+1, len(acked), after)
+	}
+}
+
+func TestWorkerPool_ProcessesBatchConcurrently(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		streams:   make(map[string][]map[string]interface{}),
+		processed: make(map[string]bool),
+	}
+	searcher := &slowSearcher{delay: 20 * time.Millisecond}
+	worker, monitor := newTestWorker(mockRedis, searcher)
+
+	for i := 0; i < 12; i++ {
+		mockRedis.AddToStream("search.requests", poolRequestEvent(i, fmt.Sprintf("chat-%d", i%3)))
+	}
+
+	pool := NewWorkerPool(worker, WithPoolSize(4), WithBatchSize(5), WithMaxInFlight(8))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = pool.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for monitor.GetMetrics().ProcessedCount < 12 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if got := monitor.GetMetrics().ProcessedCount; got != 12 {
+		t.Fatalf("expected 12 processed requests, got %d", got)
+	}
+	if maxSeen := atomic.LoadInt32(&searcher.maxSeen); maxSeen < 2 || maxSeen > 4 {
+		t.Errorf("expected between 2 and 4 concurrent searches, got %d", maxSeen)
+	}
+	if len(mockRedis.acked) != 12 {
+		t.Errorf("expected 12 acks, got %d", len(mockRedis.acked))
+	}
+}
+
+func TestWorkerPool_ShutdownDrainsInFlight(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		streams:   make(map[string][]map[string]interface{}),
+		processed: make(map[string]bool),
+	}
+	searcher := &slowSearcher{delay: 50 * time.Millisecond}
+	worker, _ := newTestWorker(mockRedis, searcher)
+
+	for i := 0; i < 4; i++ {
+		mockRedis.AddToStream("search.requests", poolRequestEvent(i, "chat-1"))
+	}
+
+	pool := NewWorkerPool(worker, WithPoolSize(2), WithBatchSize(4), WithMaxInFlight(4))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = pool.Run(ctx)
+	}()
+
+	// отменяем, пока первые поиски еще выполняются
+	for atomic.LoadInt32(&searcher.searches) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	wg.Wait()
+
+	searches := atomic.LoadInt32(&searcher.searches)
+	mockRedis.mu.Lock()
+	acked := len(mockRedis.acked)
+	mockRedis.mu.Unlock()
+
+	if int32(acked) != searches {
+		t.Errorf("expected every started search to be acked before shutdown, searches=%d acked=%d", searches, acked)
+	}
+	if acked != 4 {
+		t.Errorf("expected all read requests to be drained, got %d acks", acked)
+	}
+}
+
+// recordingLogger запоминает события ошибок
+type recordingLogger struct {
+	mu     sync.Mutex
+	errors []string
+}
+
+func (l *recordingLogger) Info(string, map[string]interface{}) {}
+
+func (l *recordingLogger) Error(event string, _ map[string]interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errors = append(l.errors, event)
+}
+
+func TestNewWorkerPool_Options(t *testing.T) {
+	mockRedis := &mockRedisClient{processed: make(map[string]bool)}
+	worker, _ := newTestWorker(mockRedis, &fakeSearcher{})
+	logger := &recordingLogger{}
+	worker.logger = logger
+
+	pool := NewWorkerPool(worker, WithPoolSize(4), WithMaxInFlight(1), WithReadAhead(0))
+	if pool.capacity() != 1 {
+		t.Errorf("expected explicit max in flight below pool size and zero read-ahead to be kept, got capacity %d", pool.capacity())
+	}
+	if len(logger.errors) != 0 {
+		t.Errorf("expected valid options not to be logged, got %v", logger.errors)
+	}
+
+	pool = NewWorkerPool(worker, WithPoolSize(4), WithMaxInFlight(-1), WithReadAhead(-2))
+	if pool.maxInFlight != 8 || pool.readAhead != 16 {
+		t.Errorf("expected defaults for invalid options, got max in flight %d, read-ahead %d", pool.maxInFlight, pool.readAhead)
+	}
+	if fmt.Sprint(logger.errors) != "[search_pool_invalid_option search_pool_invalid_option]" {
+		t.Errorf("expected invalid options to be logged, got %v", logger.errors)
+	}
+}
+
+func TestWorkerPool_TouchesHeldRequests(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		streams:   make(map[string][]map[string]interface{}),
+		processed: make(map[string]bool),
+	}
+	searcher := &slowSearcher{delay: 100 * time.Millisecond}
+	worker, _ := newTestWorker(mockRedis, searcher)
+
+	for i := 0; i < 3; i++ {
+		mockRedis.AddToStream("search.requests", poolRequestEvent(i, "chat-1"))
+	}
+
+	pool := NewWorkerPool(worker, WithPoolSize(1), WithBatchSize(3), WithPendingIdle(30*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = pool.Run(ctx)
+		close(done)
+	}()
+
+	// пока первый поиск идет, два других ждут воркера и должны оставаться своими в pending
+	time.Sleep(60 * time.Millisecond)
+	touched := make(map[string]bool)
+	for _, id := range mockRedis.Touched() {
+		touched[id] = true
+	}
+	cancel()
+	<-done
+
+	for _, id := range []string{"1-0", "2-0", "3-0"} {
+		if !touched[id] {
+			t.Errorf("expected held request %s to be touched, got %v", id, mockRedis.Touched())
+		}
+	}
+}
@@ -2,22 +2,36 @@ package streams
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"time"
 )
 
 // FlightResult представляет результат поиска одного рейса
 type FlightResult struct {
-	Origin      string `json:"origin"`
-	Destination string `json:"destination"`
-	DepartDate  string `json:"depart_date"`
-	ReturnDate  string `json:"return_date"`
-	Price       int    `json:"price"`
-	Currency    string `json:"currency"`
-	Link        string `json:"link"`
+	Origin       string `json:"origin"`
+	Destination  string `json:"destination"`
+	DepartDate   string `json:"depart_date"`
+	ReturnDate   string `json:"return_date"`
+	DepartAt     string `json:"depart_at,omitempty"` // время вылета в RFC3339
+	ReturnAt     string `json:"return_at,omitempty"` // время обратного вылета в RFC3339
+	Price        int    `json:"price"`
+	Currency     string `json:"currency"`
+	Airline      string `json:"airline,omitempty"`
+	FlightNumber int    `json:"flight_number,omitempty"`
+	Duration     int    `json:"duration,omitempty"` // длительность перелета в минутах
+	Transfers    int    `json:"transfers"`
+	Gate         string `json:"gate,omitempty"`
+	ExpiresAt    string `json:"expires_at,omitempty"` // до какого момента цена актуальна, RFC3339
+	Link         string `json:"link"`
 }
 
+// Статусы результата поиска в поле status
+const (
+	ResultStatusOK      = "ok"
+	ResultStatusError   = "error"
+	ResultStatusExpired = "expired" // deadline запроса истек, поиск не выполнялся или был прерван
+)
+
 // SearchResult представляет результат поиска авиабилетов
 type SearchResult struct {
 	RequestID     string         `json:"request_id"`
@@ -25,10 +39,33 @@ type SearchResult struct {
 	ChatID        string         `json:"chat_id"`
 	Count         int            `json:"count"`
 	Results       []FlightResult `json:"results"`
+	Status        string         `json:"status,omitempty"`
+	Message       string         `json:"message,omitempty"` // готовый к отправке текст (HTML Telegram)
 	Error         string         `json:"error,omitempty"`
+	Flex          *FlexSummary   `json:"flex,omitempty"` // сводка поиска с гибкими датами
 	Timestamp     time.Time      `json:"timestamp"`
 }
 
+// FlexSummary сводка поиска с гибкими датами: лучшая цена на каждый день и сбои отдельных дат.
+// По Errors бот отличает частичный сбой от окна дат без билетов.
+type FlexSummary struct {
+	Days   map[string]FlightResult `json:"days"`             // самый дешевый билет на каждый день вылета, ключ — YYYY-MM-DD
+	Best   *FlightResult           `json:"best,omitempty"`   // самый дешевый билет во всем окне дат
+	Errors map[string]string       `json:"errors,omitempty"` // даты, поиск на которые не удался, и причина
+}
+
+// status возвращает статус результата; для результатов без явного статуса он выводится из Error
+func (r *SearchResult) status() string {
+	switch {
+	case r.Status != "":
+		return r.Status
+	case r.Error != "":
+		return ResultStatusError
+	default:
+		return ResultStatusOK
+	}
+}
+
 // RedisProducer интерфейс для публикации в Redis Stream
 type RedisProducer interface {
 	XAdd(ctx context.Context, stream string, fields map[string]interface{}) (string, error)
@@ -38,14 +75,33 @@ type RedisProducer interface {
 type SearchResultProducer struct {
 	redis  RedisProducer
 	stream string
+	codec  ResultCodec
+}
+
+// ProducerOption настраивает SearchResultProducer
+type ProducerOption func(*SearchResultProducer)
+
+// WithResultSchemaVersion задает версию схемы публикуемых результатов (по умолчанию v1).
+// Неизвестная версия игнорируется.
+func WithResultSchemaVersion(version int) ProducerOption {
+	return func(p *SearchResultProducer) {
+		if codec, err := ResultCodecFor(version); err == nil {
+			p.codec = codec
+		}
+	}
 }
 
 // NewSearchResultProducer создает новый продюсер
-func NewSearchResultProducer(redis RedisProducer) *SearchResultProducer {
-	return &SearchResultProducer{
+func NewSearchResultProducer(redis RedisProducer, opts ...ProducerOption) *SearchResultProducer {
+	p := &SearchResultProducer{
 		redis:  redis,
 		stream: "search.results",
+		codec:  resultCodecV1{},
+	}
+	for _, o := range opts {
+		o(p)
 	}
+	return p
 }
 
 // Publish публикует результат поиска в Redis Stream
@@ -55,26 +111,10 @@ func (p *SearchResultProducer) Publish(ctx context.Context, result *SearchResult
 		result.Timestamp = time.Now()
 	}
 
-	// Конвертируем в map для Redis
-	fields := map[string]interface{}{
-		"request_id":     result.RequestID,
-		"correlation_id": result.CorrelationID,
-		"chat_id":        result.ChatID,
-		"count":          result.Count,
-		"timestamp":      result.Timestamp.Unix(),
-	}
-
-	// Добавляем результаты или ошибку
-	if result.Error != "" {
-		fields["error"] = result.Error
-		fields["results"] = "[]"
-	} else {
-		// Сериализуем результаты в JSON
-		resultsJSON, err := json.Marshal(result.Results)
-		if err != nil {
-			return "", fmt.Errorf("failed to marshal results: %w", err)
-		}
-		fields["results"] = string(resultsJSON)
+	// Конвертируем в поля stream по версии схемы
+	fields, err := p.codec.Encode(result)
+	if err != nil {
+		return "", err
 	}
 
 	// Публикуем в Redis Stream
@@ -0,0 +1,173 @@
+package streams
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RedisPendingClient интерфейс для перехвата зависших pending entries
+type RedisPendingClient interface {
+	// XAutoClaim передает консьюмеру сообщения, простаивающие дольше minIdle, начиная с start.
+	// Возвращает события (с MessageIDField и DeliveryCountField) и курсор следующего вызова ("0-0" — конец).
+	XAutoClaim(ctx context.Context, stream, group, consumer string, minIdle time.Duration, start string, count int64) ([]map[string]interface{}, string, error)
+}
+
+// PendingReclaimer забирает запросы, которые упавший воркер прочитал, но не подтвердил
+type PendingReclaimer struct {
+	redis    RedisPendingClient
+	group    string
+	stream   string
+	consumer string
+	minIdle  time.Duration
+	interval time.Duration
+	batch    int64
+	logger   Logger
+
+	dlq           *DeadLetterQueue
+	maxDeliveries int64
+}
+
+// ReclaimerOption настраивает PendingReclaimer
+type ReclaimerOption func(*PendingReclaimer)
+
+// WithReclaimInterval задает период проверки pending entries
+func WithReclaimInterval(d time.Duration) ReclaimerOption {
+	return func(r *PendingReclaimer) { r.interval = d }
+}
+
+// WithReclaimBatch задает COUNT для одного вызова XAUTOCLAIM
+func WithReclaimBatch(n int64) ReclaimerOption {
+	return func(r *PendingReclaimer) { r.batch = n }
+}
+
+// WithReclaimDeadLetter переносит в DLQ невалидные сообщения и сообщения,
+// доставленные больше maxDeliveries раз (0 — без ограничения)
+func WithReclaimDeadLetter(q *DeadLetterQueue, maxDeliveries int64) ReclaimerOption {
+	return func(r *PendingReclaimer) {
+		r.dlq = q
+		r.maxDeliveries = maxDeliveries
+	}
+}
+
+// WithReclaimConsumerName задает имя консьюмера, которому передаются перехваченные сообщения
+// (то же, что у SearchRequestConsumer этого экземпляра)
+func WithReclaimConsumerName(name string) ReclaimerOption {
+	return func(r *PendingReclaimer) { r.consumer = name }
+}
+
+// WithReclaimerLogger подключает логгер
+func WithReclaimerLogger(l Logger) ReclaimerOption {
+	return func(r *PendingReclaimer) { r.logger = l }
+}
+
+// NewPendingReclaimer создает reclaimer для search.requests.
+// minIdle — сколько сообщение должно простаивать без ack, чтобы считаться брошенным.
+func NewPendingReclaimer(redis RedisPendingClient, group string, minIdle time.Duration, opts ...ReclaimerOption) *PendingReclaimer {
+	r := &PendingReclaimer{
+		redis:    redis,
+		group:    group,
+		stream:   "search.requests",
+		consumer: "search-service",
+		minIdle:  minIdle,
+		interval: 30 * time.Second,
+		batch:    10,
+	}
+	for _, o := range opts {
+		o(r)
+	}
+	return r
+}
+
+// Reclaim проходит по всему pending списку и возвращает перехваченные запросы.
+// Невалидные и исчерпавшие лимит доставок сообщения переносятся в DLQ, если он подключен,
+// иначе логируются и остаются в pending.
+func (r *PendingReclaimer) Reclaim(ctx context.Context) ([]*SearchRequest, error) {
+	var requests []*SearchRequest
+
+	start := "0-0"
+	for {
+		events, next, err := r.redis.XAutoClaim(ctx, r.stream, r.group, r.consumer, r.minIdle, start, r.batch)
+		if err != nil {
+			return requests, fmt.Errorf("failed to autoclaim pending entries: %w", err)
+		}
+
+		for _, event := range events {
+			request, err := DecodeSearchRequest(event)
+			if err == nil && r.maxDeliveries > 0 && request.DeliveryCount > r.maxDeliveries {
+				err = fmt.Errorf("exceeded max deliveries: %d > %d", request.DeliveryCount, r.maxDeliveries)
+			}
+			if err != nil {
+				r.reject(ctx, event, err)
+				continue
+			}
+			requests = append(requests, request)
+		}
+
+		if next == "" || next == "0-0" {
+			return requests, nil
+		}
+		start = next
+	}
+}
+
+// Run периодически перехватывает зависшие запросы и передает их в handle
+// (обычно SearchWorker.Handle) до отмены контекста.
+func (r *PendingReclaimer) Run(ctx context.Context, handle func(context.Context, *SearchRequest) error) error {
+	t := time.NewTicker(r.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+
+		requests, err := r.Reclaim(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.logError("pending_reclaim_failed", map[string]interface{}{"error": err.Error()})
+		}
+
+		for _, request := range requests {
+			if r.logger != nil {
+				r.logger.Info("pending_reclaimed", map[string]interface{}{
+					"request_id":     request.RequestID,
+					"message_id":     request.MessageID,
+					"delivery_count": request.DeliveryCount,
+				})
+			}
+			_ = handle(context.WithoutCancel(ctx), request)
+		}
+	}
+}
+
+// reject переносит сообщение в DLQ или оставляет его в pending, если DLQ не подключен
+func (r *PendingReclaimer) reject(ctx context.Context, event map[string]interface{}, reason error) {
+	data := map[string]interface{}{
+		"message_id":     getString(event, MessageIDField),
+		"delivery_count": getInt64(event, DeliveryCountField),
+		"error":          reason.Error(),
+	}
+
+	if r.dlq == nil {
+		r.logError("pending_reclaim_rejected", data)
+		return
+	}
+
+	dlqID, err := r.dlq.Move(ctx, event, reason.Error())
+	if err != nil {
+		data["dlq_error"] = err.Error()
+		r.logError("pending_reclaim_rejected", data)
+		return
+	}
+
+	data["dlq_id"] = dlqID
+	r.logError("pending_dead_lettered", data)
+}
+
+func (r *PendingReclaimer) logError(event string, data map[string]interface{}) {
+	if r.logger != nil {
+		r.logger.Error(event, data)
+	}
+}
@@ -0,0 +1,88 @@
+package streams
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPendingReclaimer_Reclaim_PagesThroughPendingList(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		streams:   make(map[string][]map[string]interface{}),
+		processed: make(map[string]bool),
+	}
+
+	for _, id := range []string{"1-0", "2-0", "3-0"} {
+		event := testRequestEvent(id, "req-"+id)
+		event[DeliveryCountField] = int64(1)
+		mockRedis.AddPending(event)
+	}
+
+	reclaimer := NewPendingReclaimer(mockRedis, "test-group", time.Minute, WithReclaimBatch(2))
+
+	requests, err := reclaimer.Reclaim(context.Background())
+	if err != nil {
+		t.Fatalf("reclaim: %v", err)
+	}
+
+	if len(requests) != 3 {
+		t.Fatalf("expected 3 reclaimed requests, got %d", len(requests))
+	}
+	for _, request := range requests {
+		if request.DeliveryCount != 2 {
+			t.Errorf("expected delivery count 2 for %s, got %d", request.MessageID, request.DeliveryCount)
+		}
+	}
+	if requests[2].MessageID != "3-0" {
+		t.Errorf("expected last message 3-0, got %s", requests[2].MessageID)
+	}
+}
+
+func TestPendingReclaimer_Reclaim_SkipsInvalidEntries(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		streams:   make(map[string][]map[string]interface{}),
+		processed: make(map[string]bool),
+	}
+
+	mockRedis.AddPending(map[string]interface{}{MessageIDField: "1-0", "request_id": "broken"})
+	mockRedis.AddPending(testRequestEvent("2-0", "req-2"))
+
+	reclaimer := NewPendingReclaimer(mockRedis, "test-group", time.Minute)
+
+	requests, err := reclaimer.Reclaim(context.Background())
+	if err != nil {
+		t.Fatalf("reclaim: %v", err)
+	}
+	if len(requests) != 1 || requests[0].RequestID != "req-2" {
+		t.Fatalf("expected only valid request to be reclaimed, got %+v", requests)
+	}
+}
+
+func TestPendingReclaimer_Run_HandsRequestsToWorker(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		streams:   make(map[string][]map[string]interface{}),
+		processed: make(map[string]bool),
+	}
+	mockRedis.AddPending(testRequestEvent("1-0", "req-1"))
+
+	reclaimer := NewPendingReclaimer(mockRedis, "test-group", time.Minute, WithReclaimInterval(10*time.Millisecond))
+
+	var mu sync.Mutex
+	var handled []string
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_ = reclaimer.Run(ctx, func(_ context.Context, request *SearchRequest) error {
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, request.RequestID)
+		return nil
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(handled) != 1 || handled[0] != "req-1" {
+		t.Errorf("expected req-1 to be handled once, got %v", handled)
+	}
+}
@@ -0,0 +1,436 @@
+package streams
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	app "aviasales-bot/search-service/internal/application"
+)
+
+// SchemaVersionField поле события с версией схемы; событие без него считается v1
+const SchemaVersionField = "schema_version"
+
+// Версии схемы событий search.requests и search.results.
+//
+// v1 — исходный формат: все поля верхнего уровня, params и results — JSON строки
+// (params допускается и вложенной map), count и timestamp — числа, время — unix секунды.
+//
+// v2 — конверт из полей маршрутизации (request_id, correlation_id, chat_id, а для
+// результатов еще status) и поля payload с JSON телом события; время в RFC3339.
+const (
+	SchemaV1 = 1
+	SchemaV2 = 2
+)
+
+// ErrUnsupportedSchema возвращается для событий неизвестной версии схемы
+var ErrUnsupportedSchema = errors.New("unsupported schema version")
+
+// RequestCodec кодирует SearchRequest в поля search.requests одной версии схемы и обратно
+type RequestCodec interface {
+	Version() int
+	Encode(request *SearchRequest) (map[string]interface{}, error)
+	Decode(fields map[string]interface{}) (*SearchRequest, error)
+}
+
+// ResultCodec кодирует SearchResult в поля search.results одной версии схемы и обратно
+type ResultCodec interface {
+	Version() int
+	Encode(result *SearchResult) (map[string]interface{}, error)
+	Decode(fields map[string]interface{}) (*SearchResult, error)
+}
+
+// RequestCodecFor возвращает кодек запросов для версии схемы
+func RequestCodecFor(version int) (RequestCodec, error) {
+	switch version {
+	case SchemaV1:
+		return requestCodecV1{}, nil
+	case SchemaV2:
+		return requestCodecV2{}, nil
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedSchema, version)
+	}
+}
+
+// ResultCodecFor возвращает кодек результатов для версии схемы
+func ResultCodecFor(version int) (ResultCodec, error) {
+	switch version {
+	case SchemaV1:
+		return resultCodecV1{}, nil
+	case SchemaV2:
+		return resultCodecV2{}, nil
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedSchema, version)
+	}
+}
+
+// SchemaVersion возвращает версию схемы события (v1, если поле отсутствует)
+func SchemaVersion(fields map[string]interface{}) int {
+	if v := getInt64(fields, SchemaVersionField); v > 0 {
+		return int(v)
+	}
+	return SchemaV1
+}
+
+// DecodeSearchRequest декодирует и валидирует событие search.requests любой поддерживаемой версии
+func DecodeSearchRequest(fields map[string]interface{}) (*SearchRequest, error) {
+	codec, err := RequestCodecFor(SchemaVersion(fields))
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := codec.Decode(fields)
+	if err != nil {
+		return nil, err
+	}
+	request.MessageID = getString(fields, MessageIDField)
+	request.DeliveryCount = getInt64(fields, DeliveryCountField)
+
+	// Валидируем обязательные поля
+	if request.RequestID == "" {
+		return nil, fmt.Errorf("missing request_id")
+	}
+	if request.ChatID == "" {
+		return nil, fmt.Errorf("missing chat_id")
+	}
+	if request.Params.Origin == "" {
+		return nil, fmt.Errorf("missing origin")
+	}
+	if !validSearchKind(request.Params.Kind) {
+		return nil, fmt.Errorf("unsupported search kind %q", request.Params.Kind)
+	}
+	if groupBy := request.Params.GroupBy; groupBy != "" && groupBy != "month" && groupBy != "departure_at" {
+		return nil, fmt.Errorf("unsupported group_by %q", groupBy)
+	}
+	if api := request.Params.API; api != "" && api != app.PricesAPIV1 && api != app.PricesAPIV3 {
+		return nil, fmt.Errorf("unsupported prices api %q", api)
+	}
+	if !app.ValidSort(request.Params.Sort) {
+		return nil, fmt.Errorf("unsupported sort %q", request.Params.Sort)
+	}
+	if f := request.Params.Filter; f != nil {
+		if err := f.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid filter: %w", err)
+		}
+	}
+	p := toSearchParams(request.Params)
+	cheap := request.Params.Kind == "" || request.Params.Kind == SearchKindCheap
+	if request.Params.FlexDays != 0 {
+		if !cheap {
+			return nil, fmt.Errorf("flex_days is supported only for %s search", SearchKindCheap)
+		}
+		if err := app.ValidateFlex(p); err != nil {
+			return nil, err
+		}
+		if app.IsMultiRoute(p) {
+			return nil, fmt.Errorf("flex_days cannot be combined with several origins, destinations or expand_airports")
+		}
+	}
+	if app.IsMultiRoute(p) {
+		if !cheap {
+			return nil, fmt.Errorf("several origins or destinations are supported only for %s search", SearchKindCheap)
+		}
+		if err := app.ValidateRoutes(p); err != nil {
+			return nil, err
+		}
+	}
+	// Без направления ищутся самые дешевые билеты во все города; матрицам и grouped_prices направление нужно
+	if app.IsAnywhere(request.Params.Destination) && needsDestination(request.Params.Kind) {
+		return nil, fmt.Errorf("missing destination")
+	}
+
+	return request, nil
+}
+
+// DecodeSearchResult декодирует событие search.results любой поддерживаемой версии
+func DecodeSearchResult(fields map[string]interface{}) (*SearchResult, error) {
+	codec, err := ResultCodecFor(SchemaVersion(fields))
+	if err != nil {
+		return nil, err
+	}
+	return codec.Decode(fields)
+}
+
+// requestCodecV1 исходный формат search.requests
+type requestCodecV1 struct{}
+
+func (requestCodecV1) Version() int { return SchemaV1 }
+
+func (requestCodecV1) Encode(request *SearchRequest) (map[string]interface{}, error) {
+	params, err := json.Marshal(request.Params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal params: %w", err)
+	}
+
+	fields := map[string]interface{}{
+		SchemaVersionField: SchemaV1,
+		"request_id":       request.RequestID,
+		"correlation_id":   request.CorrelationID,
+		"chat_id":          request.ChatID,
+		"params":           string(params),
+	}
+	if !request.CreatedAt.IsZero() {
+		fields["created_at"] = request.CreatedAt.Unix()
+	}
+	if !request.Deadline.IsZero() {
+		fields["deadline"] = request.Deadline.Unix()
+	}
+	return fields, nil
+}
+
+func (requestCodecV1) Decode(fields map[string]interface{}) (*SearchRequest, error) {
+	// Парсим JSON из поля "params"
+	paramsJSON, exists := fields["params"]
+	if !exists {
+		return nil, fmt.Errorf("missing params field")
+	}
+
+	// Redis возвращает значения полей строками, поэтому params может прийти как JSON строка
+	var paramsBytes []byte
+	if str, ok := paramsJSON.(string); ok {
+		paramsBytes = []byte(str)
+	} else {
+		var err error
+		paramsBytes, err = json.Marshal(paramsJSON)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal params: %w", err)
+		}
+	}
+
+	var params SearchRequestParams
+	if err := json.Unmarshal(paramsBytes, &params); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal params: %w", err)
+	}
+
+	request := &SearchRequest{
+		RequestID:     getString(fields, "request_id"),
+		CorrelationID: getString(fields, "correlation_id"),
+		ChatID:        getString(fields, "chat_id"),
+		Params:        params,
+	}
+
+	// created_at и deadline необязательны: unix-время в секундах или RFC3339
+	var err error
+	if request.CreatedAt, err = getTime(fields, "created_at"); err != nil {
+		return nil, fmt.Errorf("invalid created_at: %w", err)
+	}
+	if request.Deadline, err = getTime(fields, "deadline"); err != nil {
+		return nil, fmt.Errorf("invalid deadline: %w", err)
+	}
+
+	return request, nil
+}
+
+// requestPayloadV2 тело запроса v2 в поле payload
+type requestPayloadV2 struct {
+	Params    SearchRequestParams `json:"params"`
+	CreatedAt *time.Time          `json:"created_at,omitempty"`
+	Deadline  *time.Time          `json:"deadline,omitempty"`
+}
+
+// requestCodecV2 конверт с полями маршрутизации и JSON payload
+type requestCodecV2 struct{}
+
+func (requestCodecV2) Version() int { return SchemaV2 }
+
+func (requestCodecV2) Encode(request *SearchRequest) (map[string]interface{}, error) {
+	payload, err := json.Marshal(requestPayloadV2{
+		Params:    request.Params,
+		CreatedAt: optionalTime(request.CreatedAt),
+		Deadline:  optionalTime(request.Deadline),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	return map[string]interface{}{
+		SchemaVersionField: SchemaV2,
+		"request_id":       request.RequestID,
+		"correlation_id":   request.CorrelationID,
+		"chat_id":          request.ChatID,
+		"payload":          string(payload),
+	}, nil
+}
+
+func (requestCodecV2) Decode(fields map[string]interface{}) (*SearchRequest, error) {
+	var payload requestPayloadV2
+	if err := decodePayload(fields, &payload); err != nil {
+		return nil, err
+	}
+
+	request := &SearchRequest{
+		RequestID:     getString(fields, "request_id"),
+		CorrelationID: getString(fields, "correlation_id"),
+		ChatID:        getString(fields, "chat_id"),
+		Params:        payload.Params,
+	}
+	if payload.CreatedAt != nil {
+		request.CreatedAt = *payload.CreatedAt
+	}
+	if payload.Deadline != nil {
+		request.Deadline = *payload.Deadline
+	}
+	return request, nil
+}
+
+// resultCodecV1 исходный формат search.results
+type resultCodecV1 struct{}
+
+func (resultCodecV1) Version() int { return SchemaV1 }
+
+func (resultCodecV1) Encode(result *SearchResult) (map[string]interface{}, error) {
+	fields := map[string]interface{}{
+		SchemaVersionField: SchemaV1,
+		"request_id":       result.RequestID,
+		"correlation_id":   result.CorrelationID,
+		"chat_id":          result.ChatID,
+		"count":            result.Count,
+		"status":           result.status(),
+		"timestamp":        result.Timestamp.Unix(),
+	}
+
+	// Добавляем результаты или ошибку
+	if result.Error != "" {
+		fields["error"] = result.Error
+		fields["results"] = "[]"
+	} else {
+		// Сериализуем результаты в JSON
+		resultsJSON, err := json.Marshal(result.Results)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal results: %w", err)
+		}
+		fields["results"] = string(resultsJSON)
+	}
+	if result.Message != "" {
+		fields["message"] = result.Message
+	}
+	if result.Flex != nil {
+		flexJSON, err := json.Marshal(result.Flex)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal flex: %w", err)
+		}
+		fields["flex"] = string(flexJSON)
+	}
+	return fields, nil
+}
+
+func (resultCodecV1) Decode(fields map[string]interface{}) (*SearchResult, error) {
+	result := &SearchResult{
+		RequestID:     getString(fields, "request_id"),
+		CorrelationID: getString(fields, "correlation_id"),
+		ChatID:        getString(fields, "chat_id"),
+		Count:         int(getInt64(fields, "count")),
+		Status:        getString(fields, "status"),
+		Message:       getString(fields, "message"),
+		Error:         getString(fields, "error"),
+		Results:       []FlightResult{},
+	}
+
+	if raw := getString(fields, "results"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &result.Results); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal results: %w", err)
+		}
+	}
+
+	if raw := getString(fields, "flex"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &result.Flex); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal flex: %w", err)
+		}
+	}
+
+	var err error
+	if result.Timestamp, err = getTime(fields, "timestamp"); err != nil {
+		return nil, fmt.Errorf("invalid timestamp: %w", err)
+	}
+	result.Status = result.status()
+	return result, nil
+}
+
+// resultPayloadV2 тело результата v2 в поле payload
+type resultPayloadV2 struct {
+	Count     int            `json:"count"`
+	Results   []FlightResult `json:"results"`
+	Message   string         `json:"message,omitempty"`
+	Error     string         `json:"error,omitempty"`
+	Flex      *FlexSummary   `json:"flex,omitempty"`
+	Timestamp time.Time      `json:"timestamp"`
+}
+
+// resultCodecV2 конверт с полями маршрутизации и JSON payload
+type resultCodecV2 struct{}
+
+func (resultCodecV2) Version() int { return SchemaV2 }
+
+func (resultCodecV2) Encode(result *SearchResult) (map[string]interface{}, error) {
+	results := result.Results
+	if results == nil {
+		results = []FlightResult{}
+	}
+	payload, err := json.Marshal(resultPayloadV2{
+		Count:     result.Count,
+		Results:   results,
+		Message:   result.Message,
+		Error:     result.Error,
+		Flex:      result.Flex,
+		Timestamp: result.Timestamp.UTC(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	return map[string]interface{}{
+		SchemaVersionField: SchemaV2,
+		"request_id":       result.RequestID,
+		"correlation_id":   result.CorrelationID,
+		"chat_id":          result.ChatID,
+		"status":           result.status(),
+		"payload":          string(payload),
+	}, nil
+}
+
+func (resultCodecV2) Decode(fields map[string]interface{}) (*SearchResult, error) {
+	var payload resultPayloadV2
+	if err := decodePayload(fields, &payload); err != nil {
+		return nil, err
+	}
+
+	result := &SearchResult{
+		RequestID:     getString(fields, "request_id"),
+		CorrelationID: getString(fields, "correlation_id"),
+		ChatID:        getString(fields, "chat_id"),
+		Count:         payload.Count,
+		Results:       payload.Results,
+		Status:        getString(fields, "status"),
+		Message:       payload.Message,
+		Error:         payload.Error,
+		Flex:          payload.Flex,
+		Timestamp:     payload.Timestamp,
+	}
+	if result.Results == nil {
+		result.Results = []FlightResult{}
+	}
+	result.Status = result.status()
+	return result, nil
+}
+
+// decodePayload разбирает JSON из поля payload события v2
+func decodePayload(fields map[string]interface{}, v interface{}) error {
+	raw := getString(fields, "payload")
+	if raw == "" {
+		return fmt.Errorf("missing payload field")
+	}
+	if err := json.Unmarshal([]byte(raw), v); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", err)
+	}
+	return nil
+}
+
+// optionalTime возвращает nil для нулевого времени, чтобы поле не попадало в JSON
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
@@ -0,0 +1,264 @@
+package streams
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var updateGolden = flag.Bool("update", false, "перезаписать golden файлы testdata/*.golden.json")
+
+func goldenRequest() *SearchRequest {
+	return &SearchRequest{
+		RequestID:     "req-1",
+		CorrelationID: "corr-1",
+		ChatID:        "12345",
+		Params: SearchRequestParams{
+			Origin:      "MOW",
+			Destination: "PAR",
+			DepartDate:  "2024-12-15",
+			ReturnDate:  "2024-12-22",
+			Currency:    "rub",
+			Passengers:  2,
+			Limit:       5,
+		},
+		CreatedAt: time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC),
+		Deadline:  time.Date(2024, 12, 1, 0, 5, 0, 0, time.UTC),
+	}
+}
+
+func goldenResult() *SearchResult {
+	return &SearchResult{
+		RequestID:     "req-1",
+		CorrelationID: "corr-1",
+		ChatID:        "12345",
+		Count:         1,
+		Results: []FlightResult{{
+			Origin:       "MOW",
+			Destination:  "PAR",
+			DepartDate:   "2024-12-15",
+			ReturnDate:   "2024-12-22",
+			DepartAt:     "2024-12-15T10:30:00Z",
+			ReturnAt:     "2024-12-22T15:45:00Z",
+			Price:        15000,
+			Currency:     "rub",
+			Airline:      "SU",
+			FlightNumber: 2454,
+			Duration:     260,
+			Transfers:    1,
+			Gate:         "Aeroflot",
+			ExpiresAt:    "2024-12-02T00:00:00Z",
+			Link:         "https://www.aviasales.com/search/MOW1512PAR22122?marker=test&passengers=2",
+		}},
+		Status:    ResultStatusOK,
+		Message:   "✈️ <b>MOW → PAR</b>\n\n🎫 <b>15 000 ₽</b>",
+		Timestamp: time.Date(2024, 12, 1, 0, 1, 0, 0, time.UTC),
+	}
+}
+
+// goldenFlexResult результат поиска с гибкими датами: сводка по дням и сбой одной даты
+func goldenFlexResult() *SearchResult {
+	result := goldenResult()
+	best := result.Results[0]
+	other := best
+	other.DepartDate, other.DepartAt, other.Price = "2024-12-16", "2024-12-16T10:30:00Z", 17500
+	result.Flex = &FlexSummary{
+		Days:   map[string]FlightResult{"2024-12-15": best, "2024-12-16": other},
+		Best:   &best,
+		Errors: map[string]string{"2024-12-14": "API returned status 502"},
+	}
+	return result
+}
+
+// redisFields приводит поля события к виду, в котором их отдает Redis: все значения — строки
+func redisFields(fields map[string]interface{}) map[string]string {
+	out := make(map[string]string, len(fields))
+	for k, v := range fields {
+		out[k] = fmt.Sprint(v)
+	}
+	return out
+}
+
+// assertGolden сравнивает поля события с testdata/<name>.golden.json и возвращает их для декодирования
+func assertGolden(t *testing.T, name string, fields map[string]interface{}) map[string]interface{} {
+	t.Helper()
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false) // сообщения содержат HTML разметку Telegram
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(redisFields(fields)); err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	got := buf.Bytes()
+
+	path := filepath.Join("testdata", name+".golden.json")
+	if *updateGolden {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("update golden: %v", err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden: %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s does not match golden file:\n got: %s\nwant: %s", name, got, want)
+	}
+
+	return loadFields(t, path)
+}
+
+func loadFields(t *testing.T, path string) map[string]interface{} {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("unmarshal %s: %v", path, err)
+	}
+	return fields
+}
+
+func TestRequestCodec_Golden(t *testing.T) {
+	for _, version := range []int{SchemaV1, SchemaV2} {
+		t.Run(fmt.Sprintf("v%d", version), func(t *testing.T) {
+			codec, err := RequestCodecFor(version)
+			if err != nil {
+				t.Fatalf("codec: %v", err)
+			}
+
+			fields, err := codec.Encode(goldenRequest())
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			stored := assertGolden(t, fmt.Sprintf("search_request_v%d", version), fields)
+
+			decoded, err := DecodeSearchRequest(stored)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			decoded.CreatedAt = decoded.CreatedAt.UTC()
+			decoded.Deadline = decoded.Deadline.UTC()
+			if !reflect.DeepEqual(decoded, goldenRequest()) {
+				t.Errorf("round trip mismatch:\n got: %+v\nwant: %+v", decoded, goldenRequest())
+			}
+		})
+	}
+}
+
+func TestResultCodec_Golden(t *testing.T) {
+	for _, version := range []int{SchemaV1, SchemaV2} {
+		t.Run(fmt.Sprintf("v%d", version), func(t *testing.T) {
+			codec, err := ResultCodecFor(version)
+			if err != nil {
+				t.Fatalf("codec: %v", err)
+			}
+
+			fields, err := codec.Encode(goldenResult())
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			stored := assertGolden(t, fmt.Sprintf("search_result_v%d", version), fields)
+
+			decoded, err := DecodeSearchResult(stored)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			decoded.Timestamp = decoded.Timestamp.UTC()
+			if !reflect.DeepEqual(decoded, goldenResult()) {
+				t.Errorf("round trip mismatch:\n got: %+v\nwant: %+v", decoded, goldenResult())
+			}
+		})
+	}
+}
+
+func TestResultCodec_FlexGolden(t *testing.T) {
+	for _, version := range []int{SchemaV1, SchemaV2} {
+		t.Run(fmt.Sprintf("v%d", version), func(t *testing.T) {
+			codec, err := ResultCodecFor(version)
+			if err != nil {
+				t.Fatalf("codec: %v", err)
+			}
+
+			fields, err := codec.Encode(goldenFlexResult())
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			stored := assertGolden(t, fmt.Sprintf("search_result_v%d_flex", version), fields)
+
+			decoded, err := DecodeSearchResult(stored)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			decoded.Timestamp = decoded.Timestamp.UTC()
+			if !reflect.DeepEqual(decoded, goldenFlexResult()) {
+				t.Errorf("round trip mismatch:\n got: %+v\nwant: %+v", decoded.Flex, goldenFlexResult().Flex)
+			}
+		})
+	}
+}
+
+func TestDecodeSearchRequest_LegacyV1(t *testing.T) {
+	// события без schema_version с params во вложенной map, как их публиковали до версионирования
+	request, err := DecodeSearchRequest(loadFields(t, filepath.Join("testdata", "search_request_v1_legacy.json")))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if request.RequestID != "req-1" || request.Params.Origin != "MOW" || request.Params.Passengers != 2 {
+		t.Errorf("unexpected request: %+v", request)
+	}
+	if !request.Deadline.IsZero() {
+		t.Errorf("expected no deadline, got %v", request.Deadline)
+	}
+}
+
+func TestDecodeSearchRequest_UnsupportedVersion(t *testing.T) {
+	fields := map[string]interface{}{
+		SchemaVersionField: "99",
+		"request_id":       "req-1",
+		"chat_id":          "12345",
+		"payload":          `{}`,
+	}
+
+	if _, err := DecodeSearchRequest(fields); !errors.Is(err, ErrUnsupportedSchema) {
+		t.Errorf("expected ErrUnsupportedSchema, got %v", err)
+	}
+}
+
+func TestSearchResultProducer_SchemaVersion(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		streams:   make(map[string][]map[string]interface{}),
+		processed: make(map[string]bool),
+	}
+	producer := NewSearchResultProducer(mockRedis, WithResultSchemaVersion(SchemaV2))
+
+	if _, err := producer.Publish(context.Background(), goldenResult()); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	published := mockRedis.GetStreams()["search.results"]
+	if len(published) != 1 || SchemaVersion(published[0]) != SchemaV2 {
+		t.Fatalf("expected v2 result, got %v", published)
+	}
+	decoded, err := DecodeSearchResult(published[0])
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if decoded.Count != 1 || decoded.Results[0].Price != 15000 {
+		t.Errorf("unexpected decoded result: %+v", decoded)
+	}
+}
@@ -0,0 +1,448 @@
+package streams
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	app "aviasales-bot/search-service/internal/application"
+)
+
+// Searcher минимальный интерфейс поиска, необходимый воркеру
+type Searcher interface {
+	SearchCheap(ctx context.Context, p app.SearchParams) ([]app.Flight, error)
+	SearchDirect(ctx context.Context, p app.SearchParams) ([]app.Flight, error)
+	SearchWeekMatrix(ctx context.Context, p app.MatrixParams) (*app.PriceMatrix, error)
+	SearchMonthMatrix(ctx context.Context, p app.MatrixParams) (*app.PriceMatrix, error)
+	SearchGroupedPrices(ctx context.Context, p app.GroupedParams) (*app.GroupedPrices, error)
+	FormatGroupedMessage(originCity, destCity string, g *app.GroupedPrices) string
+	GeneratePartnerLink(flight app.Flight, passengers int) string
+	FormatFlightMessage(originCity, destCity string, flights []app.Flight, passengers int, currency string) string
+	CityAirports(ctx context.Context, code string) ([]string, error)
+}
+
+// Logger минимальный логгер воркера
+type Logger interface {
+	Info(event string, data map[string]interface{})
+	Error(event string, data map[string]interface{})
+}
+
+// SearchWorker читает search.requests, выполняет поиск и публикует результат в search.results
+type SearchWorker struct {
+	consumer      *SearchRequestConsumer
+	producer      *SearchResultProducer
+	tracker       *IdempotencyTracker
+	monitor       *ConsumerHealthMonitor
+	searcher      Searcher
+	logger        Logger
+	pollInterval  time.Duration
+	searchTimeout time.Duration
+}
+
+// WorkerOption настраивает SearchWorker
+type WorkerOption func(*SearchWorker)
+
+// WithWorkerLogger подключает логгер к воркеру
+func WithWorkerLogger(l Logger) WorkerOption { return func(w *SearchWorker) { w.logger = l } }
+
+// WithPollInterval задает паузу между чтениями пустого stream
+func WithPollInterval(d time.Duration) WorkerOption {
+	return func(w *SearchWorker) { w.pollInterval = d }
+}
+
+// WithSearchTimeout ограничивает время одного поиска; 0 — без ограничения.
+// Без него зависший запрос к API занимает воркер и не дает завершить остановку.
+func WithSearchTimeout(d time.Duration) WorkerOption {
+	return func(w *SearchWorker) { w.searchTimeout = d }
+}
+
+// NewSearchWorker создает воркер из готовых компонентов stream
+func NewSearchWorker(consumer *SearchRequestConsumer, producer *SearchResultProducer, tracker *IdempotencyTracker, monitor *ConsumerHealthMonitor, searcher Searcher, opts ...WorkerOption) *SearchWorker {
+	w := &SearchWorker{
+		consumer:     consumer,
+		producer:     producer,
+		tracker:      tracker,
+		monitor:      monitor,
+		searcher:     searcher,
+		pollInterval: time.Second,
+	}
+	for _, o := range opts {
+		o(w)
+	}
+	return w
+}
+
+// Run обрабатывает запросы до отмены контекста.
+// Запрос, взятый в работу, дорабатывается и подтверждается даже после отмены.
+func (w *SearchWorker) Run(ctx context.Context) error {
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		request, err := w.consumer.Consume(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if errors.Is(err, ErrDeadLettered) {
+				w.logError("search_worker_dead_lettered", map[string]interface{}{"error": err.Error()})
+				continue
+			}
+			if !errors.Is(err, ErrNoEvents) {
+				w.logError("search_worker_consume_failed", map[string]interface{}{"error": err.Error()})
+			}
+			w.wait(ctx)
+			continue
+		}
+
+		_ = w.Handle(context.WithoutCancel(ctx), request)
+	}
+}
+
+// Handle выполняет поиск по запросу, публикует результат и подтверждает сообщение.
+// Ошибка поиска публикуется пользователю как результат; неподтвержденными остаются
+// только сообщения, результат которых не удалось опубликовать.
+// Для повторного request_id публикуется сохраненный ранее результат без нового поиска.
+func (w *SearchWorker) Handle(ctx context.Context, request *SearchRequest) error {
+	start := time.Now()
+	searchFailed := false
+
+	stored, duplicate, err := w.tracker.ProcessWithResult(ctx, request.RequestID, func() (*StoredResult, error) {
+		result := w.search(ctx, request)
+		searchFailed = result.Status == ResultStatusError
+
+		messageID, err := w.producer.Publish(ctx, result)
+		if err != nil {
+			return nil, err
+		}
+		return &StoredResult{Result: result, MessageID: messageID, SourceMessageID: request.MessageID}, nil
+	})
+	if err == nil && duplicate {
+		err = w.replay(ctx, request, stored)
+	}
+
+	w.monitor.RecordProcessing(request.RequestID, err == nil && !searchFailed, time.Since(start))
+
+	if err != nil {
+		w.logError("search_worker_process_failed", map[string]interface{}{
+			"request_id": request.RequestID,
+			"error":      err.Error(),
+		})
+		return err
+	}
+
+	if err := w.consumer.Ack(ctx, request); err != nil {
+		w.logError("search_worker_ack_failed", map[string]interface{}{
+			"request_id": request.RequestID,
+			"error":      err.Error(),
+		})
+		return err
+	}
+
+	if w.logger != nil {
+		w.logger.Info("search_worker_processed", map[string]interface{}{
+			"request_id":  request.RequestID,
+			"chat_id":     request.ChatID,
+			"success":     !searchFailed,
+			"expired":     stored != nil && stored.Result != nil && stored.Result.Status == ResultStatusExpired,
+			"duplicate":   duplicate,
+			"duration_ms": time.Since(start).Milliseconds(),
+		})
+	}
+
+	return nil
+}
+
+// search выполняет поиск и собирает результат; ошибка поиска попадает в SearchResult.Error.
+// Запрос с истекшим deadline не выполняется, а оставшееся до deadline время ограничивает поиск;
+// в обоих случаях публикуется результат со статусом expired. Поиск, не уложившийся
+// в searchTimeout, публикуется как ошибка.
+func (w *SearchWorker) search(ctx context.Context, request *SearchRequest) *SearchResult {
+	result := &SearchResult{
+		RequestID:     request.RequestID,
+		CorrelationID: request.CorrelationID,
+		ChatID:        request.ChatID,
+		Results:       []FlightResult{},
+		Status:        ResultStatusOK,
+		Timestamp:     time.Now(),
+	}
+
+	if request.Expired(result.Timestamp) {
+		return expire(result, request)
+	}
+	if !request.Deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, request.Deadline)
+		defer cancel()
+	}
+	if w.searchTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.searchTimeout)
+		defer cancel()
+	}
+
+	flights, message, flex, err := w.find(ctx, request.Params)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && request.Expired(time.Now()) {
+			return expire(result, request)
+		}
+		w.logError("search_worker_search_failed", map[string]interface{}{
+			"request_id": request.RequestID,
+			"error":      err.Error(),
+		})
+		result.Status = ResultStatusError
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Results = w.toFlightResults(request.Params, flights)
+	result.Count = len(result.Results)
+	if flex != nil {
+		result.Flex = w.toFlexSummary(request.Params, flex)
+	}
+	result.Message = message
+	if message == "" {
+		result.Message = w.searcher.FormatFlightMessage(originCity(request.Params, flights), request.Params.Destination, cheapestFirst(flights), passengers(request.Params), request.Params.Currency)
+	}
+	return result
+}
+
+// find выполняет поиск нужного вида; матрицы цен и лучшие цены по периодам возвращаются списком билетов.
+// Для видов со своим форматом сообщения возвращается и готовое сообщение, для гибких дат — сводка по дням.
+func (w *SearchWorker) find(ctx context.Context, params SearchRequestParams) ([]app.Flight, string, *app.FlexResult, error) {
+	switch {
+	case isMatrixKind(params.Kind):
+		search := w.searcher.SearchWeekMatrix
+		if params.Kind == SearchKindMonthMatrix {
+			search = w.searcher.SearchMonthMatrix
+		}
+		p := toSearchParams(params)
+		matrix, err := search(ctx, app.MatrixParams{
+			Origin:      p.Origin,
+			Destination: p.Destination,
+			DepartDate:  p.DepartDate,
+			ReturnDate:  p.ReturnDate,
+			Currency:    p.Currency,
+		})
+		if err != nil {
+			return nil, "", nil, err
+		}
+		return matrix.Flights, "", nil, nil
+	case params.Kind == SearchKindGrouped:
+		p := toSearchParams(params)
+		grouped, err := w.searcher.SearchGroupedPrices(ctx, app.GroupedParams{
+			Origin:      p.Origin,
+			Destination: p.Destination,
+			GroupBy:     params.GroupBy,
+			DepartureAt: p.DepartDate,
+			ReturnAt:    p.ReturnDate,
+			Direct:      params.Direct,
+			Currency:    p.Currency,
+		})
+		if err != nil {
+			return nil, "", nil, err
+		}
+		flights := make([]app.Flight, 0, len(grouped.Periods))
+		for _, period := range sortedPeriods(grouped) {
+			flights = append(flights, grouped.Periods[period])
+		}
+		return flights, w.searcher.FormatGroupedMessage(params.Origin, params.Destination, grouped), nil, nil
+	case params.FlexDays > 0:
+		flex, err := app.NewFlexSearcher(w.searcher).Search(ctx, toSearchParams(params), params.Direct)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		// Ошибки отдельных дат не проваливают поиск: они попадают в лог и в сводку результата
+		for date, reason := range flex.Errors {
+			w.logError("search_flex_date_failed", map[string]interface{}{
+				"origin":      params.Origin,
+				"destination": params.Destination,
+				"depart_date": date,
+				"error":       reason,
+			})
+		}
+		return flex.Flights, "", flex, nil
+	case app.IsMultiRoute(toSearchParams(params)):
+		multi, err := app.NewMultiSearcher(w.searcher, app.WithAirportResolver(w.searcher)).Search(ctx, toSearchParams(params), params.Direct)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		for route, reason := range multi.Errors {
+			w.logError("search_route_failed", map[string]interface{}{
+				"route": route,
+				"error": reason,
+			})
+		}
+		return multi.Flights, "", nil, nil
+	default:
+		search := w.searcher.SearchCheap
+		if params.Direct {
+			search = w.searcher.SearchDirect
+		}
+		flights, err := search(ctx, toSearchParams(params))
+		return flights, "", nil, err
+	}
+}
+
+// sortedPeriods возвращает периоды grouped_prices по возрастанию
+func sortedPeriods(g *app.GroupedPrices) []string {
+	periods := make([]string, 0, len(g.Periods))
+	for period := range g.Periods {
+		periods = append(periods, period)
+	}
+	sort.Strings(periods)
+	return periods
+}
+
+// cheapestFirst возвращает копию рейсов, отсортированную по цене, для сообщения пользователю
+func cheapestFirst(flights []app.Flight) []app.Flight {
+	sorted := append([]app.Flight(nil), flights...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Price < sorted[j].Price })
+	return sorted
+}
+
+// expire помечает результат как просроченный
+func expire(result *SearchResult, request *SearchRequest) *SearchResult {
+	result.Status = ResultStatusExpired
+	result.Error = fmt.Sprintf("request deadline exceeded at %s", request.Deadline.UTC().Format(time.RFC3339))
+	return result
+}
+
+// replay повторно публикует сохраненный результат для дубликата request_id.
+// Повторная доставка того же сообщения (воркер упал между публикацией и ack) не публикуется.
+func (w *SearchWorker) replay(ctx context.Context, request *SearchRequest, stored *StoredResult) error {
+	if stored == nil || stored.Result == nil {
+		return nil
+	}
+	if stored.SourceMessageID != "" && stored.SourceMessageID == request.MessageID {
+		return nil
+	}
+
+	if _, err := w.producer.Publish(ctx, stored.Result); err != nil {
+		return fmt.Errorf("failed to replay stored result: %w", err)
+	}
+	return nil
+}
+
+// toFlightResults конвертирует найденные рейсы в формат stream с партнерскими ссылками
+func (w *SearchWorker) toFlightResults(params SearchRequestParams, flights []app.Flight) []FlightResult {
+	currency := params.Currency
+	if currency == "" {
+		currency = "rub"
+	}
+
+	results := make([]FlightResult, 0, len(flights))
+	for _, flight := range flights {
+		result := FlightResult{
+			Origin:       flight.Origin,
+			Destination:  flight.Destination,
+			Price:        flight.Price,
+			Currency:     currency,
+			Airline:      flight.Airline,
+			FlightNumber: flight.FlightNumber,
+			Duration:     flight.Duration,
+			Transfers:    flight.Transfers,
+			Gate:         flight.Gate,
+			Link:         w.searcher.GeneratePartnerLink(flight, passengers(params)),
+		}
+		if !flight.DepartDate.IsZero() {
+			result.DepartDate = flight.DepartDate.Format("2006-01-02")
+			result.DepartAt = flight.DepartDate.Format(time.RFC3339)
+		}
+		if !flight.ReturnDate.IsZero() {
+			result.ReturnDate = flight.ReturnDate.Format("2006-01-02")
+			result.ReturnAt = flight.ReturnDate.Format(time.RFC3339)
+		}
+		if !flight.ExpiresAt.IsZero() {
+			result.ExpiresAt = flight.ExpiresAt.Format(time.RFC3339)
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// toFlexSummary конвертирует результат поиска с гибкими датами в сводку по дням
+func (w *SearchWorker) toFlexSummary(params SearchRequestParams, flex *app.FlexResult) *FlexSummary {
+	summary := &FlexSummary{Days: make(map[string]FlightResult, len(flex.Days)), Errors: flex.Errors}
+	for day, flight := range flex.Days {
+		summary.Days[day] = w.toFlightResults(params, []app.Flight{flight})[0]
+	}
+	if flex.Best != nil {
+		best := w.toFlightResults(params, []app.Flight{*flex.Best})[0]
+		summary.Best = &best
+	}
+	return summary
+}
+
+// originCity возвращает название города отправления из найденных рейсов или IATA код запроса
+func originCity(params SearchRequestParams, flights []app.Flight) string {
+	if len(flights) > 0 && flights[0].OriginName != "" {
+		return flights[0].OriginName
+	}
+	return params.Origin
+}
+
+// passengers возвращает число пассажиров запроса (минимум 1)
+func passengers(params SearchRequestParams) int {
+	if params.Passengers <= 0 {
+		return 1
+	}
+	return params.Passengers
+}
+
+// toSearchParams конвертирует параметры из stream в параметры поиска
+func toSearchParams(p SearchRequestParams) app.SearchParams {
+	currency := p.Currency
+	if currency == "" {
+		currency = "rub"
+	}
+	limit := p.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+	destination := p.Destination
+	if app.IsAnywhere(destination) {
+		destination = app.AnyDestination
+	}
+
+	var filter app.Filter
+	if p.Filter != nil {
+		filter = *p.Filter
+	}
+
+	return app.SearchParams{
+		Origin:      p.Origin,
+		Destination: destination,
+		DepartDate:  p.DepartDate,
+		ReturnDate:  p.ReturnDate,
+		Currency:    currency,
+		Limit:       limit,
+		Sort:        p.Sort,
+		Filter:      filter,
+		FlexDays:    p.FlexDays,
+
+		ExpandAirports: p.ExpandAirports,
+		API:            p.API,
+		OneWay:         p.OneWay,
+	}
+}
+
+// wait делает паузу перед следующим чтением или выходит при отмене контекста
+func (w *SearchWorker) wait(ctx context.Context) {
+	t := time.NewTimer(w.pollInterval)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
+
+func (w *SearchWorker) logError(event string, data map[string]interface{}) {
+	if w.logger != nil {
+		w.logger.Error(event, data)
+	}
+}
@@ -0,0 +1,785 @@
+package streams
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	app "aviasales-bot/search-service/internal/application"
+)
+
+// fakeSearcher реализует Searcher для тестов воркера
+type fakeSearcher struct {
+	calledWith app.SearchParams
+	direct     bool   // последний поиск шел через SearchDirect
+	matrix     string // вид последней запрошенной матрицы цен
+	matrixWith app.MatrixParams
+	grouped    app.GroupedParams
+	flights    []app.Flight
+	err        error
+	block      bool // ждать отмены контекста вместо ответа
+}
+
+func (f *fakeSearcher) SearchCheap(ctx context.Context, p app.SearchParams) ([]app.Flight, error) {
+	f.calledWith = p
+	if f.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return f.flights, f.err
+}
+
+func (f *fakeSearcher) SearchDirect(ctx context.Context, p app.SearchParams) ([]app.Flight, error) {
+	f.direct = true
+	return f.SearchCheap(ctx, p)
+}
+
+func (f *fakeSearcher) CityAirports(_ context.Context, code string) ([]string, error) {
+	if code == "MOW" {
+		return []string{"DME", "SVO"}, nil
+	}
+	return []string{code}, nil
+}
+
+func (f *fakeSearcher) SearchWeekMatrix(_ context.Context, p app.MatrixParams) (*app.PriceMatrix, error) {
+	f.matrix, f.matrixWith = SearchKindWeekMatrix, p
+	return &app.PriceMatrix{Origin: p.Origin, Destination: p.Destination, Flights: f.flights}, f.err
+}
+
+func (f *fakeSearcher) SearchMonthMatrix(_ context.Context, p app.MatrixParams) (*app.PriceMatrix, error) {
+	f.matrix, f.matrixWith = SearchKindMonthMatrix, p
+	return &app.PriceMatrix{Origin: p.Origin, Destination: p.Destination, Flights: f.flights}, f.err
+}
+
+func (f *fakeSearcher) SearchGroupedPrices(_ context.Context, p app.GroupedParams) (*app.GroupedPrices, error) {
+	f.grouped = p
+	periods := make(map[string]app.Flight, len(f.flights))
+	for _, flight := range f.flights {
+		periods[flight.DepartDate.Format("2006-01")] = flight
+	}
+	return &app.GroupedPrices{Origin: p.Origin, Destination: p.Destination, GroupBy: p.GroupBy, Periods: periods}, f.err
+}
+
+func (f *fakeSearcher) FormatGroupedMessage(originCity, destCity string, g *app.GroupedPrices) string {
+	return fmt.Sprintf("%s → %s по периодам: %d", originCity, destCity, len(g.Periods))
+}
+
+func (f *fakeSearcher) GeneratePartnerLink(flight app.Flight, passengers int) string {
+	return fmt.Sprintf("https://www.aviasales.com/search/%s%s?passengers=%d", flight.Origin, flight.Destination, passengers)
+}
+
+func (f *fakeSearcher) FormatFlightMessage(originCity, destCity string, flights []app.Flight, passengers int, currency string) string {
+	return fmt.Sprintf("%s → %s: %d", originCity, destCity, len(flights))
+}
+
+func newTestWorker(mockRedis *mockRedisClient, searcher Searcher) (*SearchWorker, *ConsumerHealthMonitor) {
+	monitor := NewConsumerHealthMonitor()
+	worker := NewSearchWorker(
+		NewSearchRequestConsumer(mockRedis, "test-group"),
+		NewSearchResultProducer(mockRedis),
+		NewIdempotencyTracker(mockRedis),
+		monitor,
+		searcher,
+		WithPollInterval(10*time.Millisecond),
+	)
+	return worker, monitor
+}
+
+func testRequestEvent(messageID, requestID string) map[string]interface{} {
+	return map[string]interface{}{
+		MessageIDField:   messageID,
+		"request_id":     requestID,
+		"correlation_id": "test-correlation-456",
+		"chat_id":        "12345",
+		"params": map[string]interface{}{
+			"origin":      "MOW",
+			"destination": "PAR",
+			"depart_date": "2024-12-15",
+			"return_date": "2024-12-22",
+			"passengers":  2,
+			"limit":       5,
+		},
+	}
+}
+
+func TestSearchWorker_Handle_PublishesResultsAndAcks(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		streams:   make(map[string][]map[string]interface{}),
+		processed: make(map[string]bool),
+	}
+	searcher := &fakeSearcher{flights: []app.Flight{
+		{
+			Origin:      "MOW",
+			Destination: "PAR",
+			DepartDate:  time.Date(2024, 12, 15, 10, 30, 0, 0, time.UTC),
+			ReturnDate:  time.Date(2024, 12, 22, 15, 45, 0, 0, time.UTC),
+			Price:       15000,
+		},
+	}}
+	worker, monitor := newTestWorker(mockRedis, searcher)
+
+	mockRedis.AddToStream("search.requests", testRequestEvent("1-0", "req-1"))
+	request, err := worker.consumer.Consume(context.Background())
+	if err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+
+	if err := worker.Handle(context.Background(), request); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+
+	if searcher.calledWith.Origin != "MOW" || searcher.calledWith.Limit != 5 || searcher.calledWith.Currency != "rub" {
+		t.Errorf("unexpected search params: %+v", searcher.calledWith)
+	}
+
+	published := mockRedis.GetStreams()["search.results"]
+	if len(published) != 1 {
+		t.Fatalf("expected 1 published result, got %d", len(published))
+	}
+
+	var results []FlightResult
+	if err := json.Unmarshal([]byte(published[0]["results"].(string)), &results); err != nil {
+		t.Fatalf("results json: %v", err)
+	}
+	if len(results) != 1 || results[0].DepartDate != "2024-12-15" || results[0].Link == "" || results[0].Currency != "rub" {
+		t.Errorf("unexpected results: %+v", results)
+	}
+
+	if len(mockRedis.acked) != 1 || mockRedis.acked[0] != "1-0" {
+		t.Errorf("expected message 1-0 to be acked, got %v", mockRedis.acked)
+	}
+
+	if monitor.GetMetrics().ProcessedCount != 1 {
+		t.Errorf("expected processed count 1, got %d", monitor.GetMetrics().ProcessedCount)
+	}
+}
+
+func TestSearchWorker_Handle_SearchErrorPublishesError(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		streams:   make(map[string][]map[string]interface{}),
+		processed: make(map[string]bool),
+	}
+	worker, monitor := newTestWorker(mockRedis, &fakeSearcher{err: errors.New("API timeout")})
+
+	mockRedis.AddToStream("search.requests", testRequestEvent("1-0", "req-1"))
+	request, err := worker.consumer.Consume(context.Background())
+	if err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+
+	if err := worker.Handle(context.Background(), request); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+
+	published := mockRedis.GetStreams()["search.results"]
+	if len(published) != 1 || published[0]["error"] != "API timeout" {
+		t.Fatalf("expected published error result, got %v", published)
+	}
+
+	if len(mockRedis.acked) != 1 {
+		t.Errorf("expected message to be acked, got %v", mockRedis.acked)
+	}
+
+	if monitor.GetMetrics().ErrorCount != 1 {
+		t.Errorf("expected error count 1, got %d", monitor.GetMetrics().ErrorCount)
+	}
+}
+
+func TestSearchWorker_Handle_DuplicateSkipsSearch(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		streams:   make(map[string][]map[string]interface{}),
+		processed: make(map[string]bool),
+	}
+	searcher := &fakeSearcher{}
+	worker, _ := newTestWorker(mockRedis, searcher)
+
+	if err := worker.tracker.MarkProcessed(context.Background(), "req-1"); err != nil {
+		t.Fatalf("mark: %v", err)
+	}
+
+	mockRedis.AddToStream("search.requests", testRequestEvent("1-0", "req-1"))
+	request, err := worker.consumer.Consume(context.Background())
+	if err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+
+	if err := worker.Handle(context.Background(), request); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+
+	if searcher.calledWith.Origin != "" {
+		t.Error("expected search to be skipped for duplicate request")
+	}
+	if len(mockRedis.GetStreams()["search.results"]) != 0 {
+		t.Error("expected no results for duplicate request")
+	}
+	if len(mockRedis.acked) != 1 {
+		t.Errorf("expected duplicate to be acked, got %v", mockRedis.acked)
+	}
+}
+
+func TestSearchWorker_Run_StopsOnCancel(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		streams:   make(map[string][]map[string]interface{}),
+		processed: make(map[string]bool),
+	}
+	worker, _ := newTestWorker(mockRedis, &fakeSearcher{})
+
+	mockRedis.AddToStream("search.requests", testRequestEvent("1-0", "req-1"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if err := worker.Run(ctx); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	if len(mockRedis.acked) != 1 {
+		t.Errorf("expected queued request to be processed before shutdown, got %v", mockRedis.acked)
+	}
+}
+
+func TestSearchWorker_Handle_DuplicateReplaysStoredResult(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		streams:   make(map[string][]map[string]interface{}),
+		processed: make(map[string]bool),
+	}
+	searcher := &fakeSearcher{}
+	worker, _ := newTestWorker(mockRedis, searcher)
+
+	// первый воркер уже обработал req-1 и сохранил опубликованный результат
+	original := &SearchResult{
+		RequestID:     "req-1",
+		CorrelationID: "test-correlation-456",
+		ChatID:        "12345",
+		Count:         1,
+		Results:       []FlightResult{{Origin: "MOW", Destination: "PAR", Price: 15000, Currency: "rub"}},
+		Timestamp:     time.Date(2024, 11, 1, 12, 0, 0, 0, time.UTC),
+	}
+	_, _, err := worker.tracker.ProcessWithResult(context.Background(), "req-1", func() (*StoredResult, error) {
+		return &StoredResult{Result: original, MessageID: "100-0", SourceMessageID: "1-0"}, nil
+	})
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+
+	duplicate := testRequestEvent("2-0", "req-1")
+	duplicate["correlation_id"] = "another-correlation"
+	mockRedis.AddToStream("search.requests", duplicate)
+
+	request, err := worker.consumer.Consume(context.Background())
+	if err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+	if err := worker.Handle(context.Background(), request); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+
+	if searcher.calledWith.Origin != "" {
+		t.Error("expected duplicate not to trigger a new search")
+	}
+
+	published := mockRedis.GetStreams()["search.results"]
+	if len(published) != 1 {
+		t.Fatalf("expected replayed result, got %d", len(published))
+	}
+	if published[0]["correlation_id"] != "test-correlation-456" {
+		t.Errorf("expected original correlation id, got %v", published[0]["correlation_id"])
+	}
+	if published[0]["timestamp"] != original.Timestamp.Unix() {
+		t.Errorf("expected original timestamp %d, got %v", original.Timestamp.Unix(), published[0]["timestamp"])
+	}
+
+	var results []FlightResult
+	if err := json.Unmarshal([]byte(published[0]["results"].(string)), &results); err != nil {
+		t.Fatalf("results json: %v", err)
+	}
+	if len(results) != 1 || results[0] != original.Results[0] {
+		t.Errorf("expected original results, got %+v", results)
+	}
+
+	if len(mockRedis.acked) != 1 || mockRedis.acked[0] != "2-0" {
+		t.Errorf("expected duplicate message to be acked, got %v", mockRedis.acked)
+	}
+}
+
+func TestSearchWorker_Handle_RedeliveryDoesNotRepublish(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		streams:   make(map[string][]map[string]interface{}),
+		processed: make(map[string]bool),
+	}
+	worker, _ := newTestWorker(mockRedis, &fakeSearcher{})
+
+	mockRedis.AddToStream("search.requests", testRequestEvent("1-0", "req-1"))
+	request, err := worker.consumer.Consume(context.Background())
+	if err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+
+	// воркер упал после публикации, сообщение перехвачено и доставлено повторно
+	for i := 0; i < 2; i++ {
+		if err := worker.Handle(context.Background(), request); err != nil {
+			t.Fatalf("handle: %v", err)
+		}
+	}
+
+	if published := mockRedis.GetStreams()["search.results"]; len(published) != 1 {
+		t.Errorf("expected single published result for redelivered message, got %d", len(published))
+	}
+}
+
+func TestSearchWorker_Handle_ExpiredRequestSkipsSearch(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		streams:   make(map[string][]map[string]interface{}),
+		processed: make(map[string]bool),
+	}
+	searcher := &fakeSearcher{}
+	worker, monitor := newTestWorker(mockRedis, searcher)
+
+	event := testRequestEvent("1-0", "req-1")
+	event["created_at"] = time.Now().Add(-10 * time.Minute).Unix()
+	event["deadline"] = time.Now().Add(-5 * time.Minute).UTC().Format(time.RFC3339)
+	mockRedis.AddToStream("search.requests", event)
+
+	request, err := worker.consumer.Consume(context.Background())
+	if err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+	if err := worker.Handle(context.Background(), request); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+
+	if searcher.calledWith.Origin != "" {
+		t.Error("expected expired request not to be searched")
+	}
+
+	published := mockRedis.GetStreams()["search.results"]
+	if len(published) != 1 || published[0]["status"] != ResultStatusExpired {
+		t.Fatalf("expected expired result, got %v", published)
+	}
+	if len(mockRedis.acked) != 1 {
+		t.Errorf("expected expired request to be acked, got %v", mockRedis.acked)
+	}
+	if monitor.GetMetrics().ErrorCount != 0 {
+		t.Errorf("expected expired request not to count as error, got %d", monitor.GetMetrics().ErrorCount)
+	}
+}
+
+func TestSearchWorker_Handle_DeadlineLimitsSearch(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		streams:   make(map[string][]map[string]interface{}),
+		processed: make(map[string]bool),
+	}
+	worker, _ := newTestWorker(mockRedis, &fakeSearcher{block: true})
+
+	event := testRequestEvent("1-0", "req-1")
+	event["deadline"] = time.Now().Add(50 * time.Millisecond).Format(time.RFC3339Nano)
+	mockRedis.AddToStream("search.requests", event)
+
+	request, err := worker.consumer.Consume(context.Background())
+	if err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+
+	start := time.Now()
+	if err := worker.Handle(context.Background(), request); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected search to be cut at the deadline, took %v", elapsed)
+	}
+
+	published := mockRedis.GetStreams()["search.results"]
+	if len(published) != 1 || published[0]["status"] != ResultStatusExpired {
+		t.Fatalf("expected expired result, got %v", published)
+	}
+}
+
+// Тест: зависший поиск прерывается по searchTimeout и публикуется как ошибка
+func TestSearchWorker_Handle_SearchTimeout(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		streams:   make(map[string][]map[string]interface{}),
+		processed: make(map[string]bool),
+	}
+	monitor := NewConsumerHealthMonitor()
+	worker := NewSearchWorker(
+		NewSearchRequestConsumer(mockRedis, "test-group"),
+		NewSearchResultProducer(mockRedis),
+		NewIdempotencyTracker(mockRedis),
+		monitor,
+		&fakeSearcher{block: true},
+		WithSearchTimeout(50*time.Millisecond),
+	)
+
+	mockRedis.AddToStream("search.requests", testRequestEvent("1-0", "req-1"))
+	request, err := worker.consumer.Consume(context.Background())
+	if err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+
+	start := time.Now()
+	if err := worker.Handle(context.Background(), request); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected search to be cut by the timeout, took %v", elapsed)
+	}
+
+	published := mockRedis.GetStreams()["search.results"]
+	if len(published) != 1 || published[0]["status"] != ResultStatusError {
+		t.Fatalf("expected error result, got %v", published)
+	}
+	if len(mockRedis.acked) != 1 {
+		t.Errorf("expected timed out request to be acked, got %v", mockRedis.acked)
+	}
+}
+
+func TestSearchWorker_Handle_PublishesFlightDetailsAndMessage(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		streams:   make(map[string][]map[string]interface{}),
+		processed: make(map[string]bool),
+	}
+	searcher := &fakeSearcher{flights: []app.Flight{
+		{
+			Origin:       "MOW",
+			Destination:  "PAR",
+			DepartDate:   time.Date(2024, 12, 15, 10, 30, 0, 0, time.UTC),
+			Price:        15000,
+			Airline:      "SU",
+			FlightNumber: 2454,
+			Duration:     260,
+			Transfers:    1,
+			Gate:         "Aeroflot",
+			ExpiresAt:    time.Date(2024, 12, 2, 0, 0, 0, 0, time.UTC),
+		},
+	}}
+	worker, _ := newTestWorker(mockRedis, searcher)
+
+	mockRedis.AddToStream("search.requests", testRequestEvent("1-0", "req-1"))
+	request, err := worker.consumer.Consume(context.Background())
+	if err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+	if err := worker.Handle(context.Background(), request); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+
+	published := mockRedis.GetStreams()["search.results"]
+	if len(published) != 1 {
+		t.Fatalf("expected 1 published result, got %d", len(published))
+	}
+	result, err := DecodeSearchResult(published[0])
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if result.Message != "MOW → PAR: 1" {
+		t.Errorf("expected rendered message, got %q", result.Message)
+	}
+
+	flight := result.Results[0]
+	if flight.Airline != "SU" || flight.FlightNumber != 2454 || flight.Duration != 260 || flight.Transfers != 1 || flight.Gate != "Aeroflot" {
+		t.Errorf("expected flight details, got %+v", flight)
+	}
+	if flight.DepartAt != "2024-12-15T10:30:00Z" || flight.ExpiresAt != "2024-12-02T00:00:00Z" || flight.ReturnAt != "" {
+		t.Errorf("unexpected flight times: %+v", flight)
+	}
+	if flight.Link != "https://www.aviasales.com/search/MOWPAR?passengers=2" {
+		t.Errorf("expected passengers-aware link, got %s", flight.Link)
+	}
+}
+
+func TestSearchWorker_Handle_DirectParamUsesDirectSearch(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		streams:   make(map[string][]map[string]interface{}),
+		processed: make(map[string]bool),
+	}
+	searcher := &fakeSearcher{}
+	worker, _ := newTestWorker(mockRedis, searcher)
+
+	event := testRequestEvent("1-0", "req-1")
+	event["params"].(map[string]interface{})["direct"] = true
+	mockRedis.AddToStream("search.requests", event)
+
+	request, err := worker.consumer.Consume(context.Background())
+	if err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+	if err := worker.Handle(context.Background(), request); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+
+	if !searcher.direct {
+		t.Error("expected direct=true to use SearchDirect")
+	}
+}
+
+func TestSearchWorker_Handle_PricesAPIV3(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		streams:   make(map[string][]map[string]interface{}),
+		processed: make(map[string]bool),
+	}
+	searcher := &fakeSearcher{}
+	worker, _ := newTestWorker(mockRedis, searcher)
+
+	event := testRequestEvent("1-0", "req-1")
+	event["params"].(map[string]interface{})["api"] = "v3"
+	event["params"].(map[string]interface{})["one_way"] = true
+	mockRedis.AddToStream("search.requests", event)
+
+	request, err := worker.consumer.Consume(context.Background())
+	if err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+	if err := worker.Handle(context.Background(), request); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+
+	if searcher.calledWith.API != app.PricesAPIV3 || !searcher.calledWith.OneWay {
+		t.Errorf("expected v3 one-way search, got %+v", searcher.calledWith)
+	}
+}
+
+func TestSearchWorker_Handle_MatrixKinds(t *testing.T) {
+	for _, kind := range []string{SearchKindWeekMatrix, SearchKindMonthMatrix} {
+		t.Run(kind, func(t *testing.T) {
+			mockRedis := &mockRedisClient{
+				streams:   make(map[string][]map[string]interface{}),
+				processed: make(map[string]bool),
+			}
+			searcher := &fakeSearcher{flights: []app.Flight{
+				{Origin: "MOW", Destination: "PAR", DepartDate: time.Date(2024, 12, 14, 0, 0, 0, 0, time.UTC), Price: 21000},
+				{Origin: "MOW", Destination: "PAR", DepartDate: time.Date(2024, 12, 15, 0, 0, 0, 0, time.UTC), Price: 15000},
+			}}
+			worker, _ := newTestWorker(mockRedis, searcher)
+
+			event := testRequestEvent("1-0", "req-1")
+			event["params"].(map[string]interface{})["kind"] = kind
+			mockRedis.AddToStream("search.requests", event)
+
+			request, err := worker.consumer.Consume(context.Background())
+			if err != nil {
+				t.Fatalf("consume: %v", err)
+			}
+			if err := worker.Handle(context.Background(), request); err != nil {
+				t.Fatalf("handle: %v", err)
+			}
+
+			if searcher.matrix != kind || searcher.matrixWith.DepartDate != "2024-12-15" || searcher.matrixWith.Currency != "rub" {
+				t.Errorf("expected %s search, got %s %+v", kind, searcher.matrix, searcher.matrixWith)
+			}
+
+			result, err := DecodeSearchResult(mockRedis.GetStreams()["search.results"][0])
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if result.Count != 2 || result.Results[0].DepartDate != "2024-12-14" {
+				t.Errorf("expected matrix cells in original order, got %+v", result.Results)
+			}
+		})
+	}
+}
+
+func TestSearchWorker_Handle_AnywhereDestination(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		streams:   make(map[string][]map[string]interface{}),
+		processed: make(map[string]bool),
+	}
+	searcher := &fakeSearcher{flights: []app.Flight{
+		{Origin: "MOW", OriginName: "Москва", Destination: "IST", DestinationName: "Стамбул", Price: 8500},
+		{Origin: "MOW", OriginName: "Москва", Destination: "AER", DestinationName: "Сочи", Price: 9900},
+	}}
+	worker, _ := newTestWorker(mockRedis, searcher)
+
+	event := testRequestEvent("1-0", "req-1")
+	delete(event["params"].(map[string]interface{}), "destination")
+	mockRedis.AddToStream("search.requests", event)
+
+	request, err := worker.consumer.Consume(context.Background())
+	if err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+	if err := worker.Handle(context.Background(), request); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+
+	if searcher.calledWith.Destination != app.AnyDestination {
+		t.Errorf("expected destination %q, got %q", app.AnyDestination, searcher.calledWith.Destination)
+	}
+
+	result, err := DecodeSearchResult(mockRedis.GetStreams()["search.results"][0])
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if result.Count != 2 || result.Message != "Москва → : 2" {
+		t.Errorf("unexpected result: count=%d message=%q", result.Count, result.Message)
+	}
+}
+
+// datesSearcher потокобезопасно запоминает даты вылета параллельных поисков
+type datesSearcher struct {
+	fakeSearcher
+	mu    sync.Mutex
+	dates []string
+}
+
+func (s *datesSearcher) SearchCheap(_ context.Context, p app.SearchParams) ([]app.Flight, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dates = append(s.dates, p.DepartDate)
+	if p.DepartDate == "2031-12-16" {
+		return nil, errors.New("upstream timeout")
+	}
+	depart, _ := time.Parse("2006-01-02", p.DepartDate)
+	return []app.Flight{{Origin: "MOW", Destination: "PAR", DepartDate: depart, Price: 10000 + depart.Day()}}, nil
+}
+
+func TestSearchWorker_Handle_FlexDays(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		streams:   make(map[string][]map[string]interface{}),
+		processed: make(map[string]bool),
+	}
+	searcher := &datesSearcher{}
+	worker, _ := newTestWorker(mockRedis, searcher)
+
+	event := testRequestEvent("1-0", "req-1")
+	event["params"].(map[string]interface{})["depart_date"] = "2031-12-15"
+	event["params"].(map[string]interface{})["return_date"] = "2031-12-22"
+	event["params"].(map[string]interface{})["flex_days"] = 1
+	mockRedis.AddToStream("search.requests", event)
+
+	request, err := worker.consumer.Consume(context.Background())
+	if err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+	if err := worker.Handle(context.Background(), request); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+
+	sort.Strings(searcher.dates)
+	if fmt.Sprint(searcher.dates) != "[2031-12-14 2031-12-15 2031-12-16]" {
+		t.Errorf("unexpected searched dates: %v", searcher.dates)
+	}
+
+	// Ошибка одной даты не проваливает поиск
+	result, err := DecodeSearchResult(mockRedis.GetStreams()["search.results"][0])
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if result.Status != ResultStatusOK || result.Count != 2 || result.Results[0].Price != 10014 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	// Сводка по дням, лучший билет и сбойные даты доходят до потребителя
+	if result.Flex == nil {
+		t.Fatal("expected flex summary in result")
+	}
+	if len(result.Flex.Days) != 2 || result.Flex.Days["2031-12-15"].Price != 10015 {
+		t.Errorf("unexpected flex days: %+v", result.Flex.Days)
+	}
+	if result.Flex.Best == nil || result.Flex.Best.Price != 10014 || result.Flex.Best.DepartDate != "2031-12-14" {
+		t.Errorf("unexpected flex best: %+v", result.Flex.Best)
+	}
+	if result.Flex.Errors["2031-12-16"] == "" {
+		t.Errorf("expected failed date in flex errors, got %v", result.Flex.Errors)
+	}
+}
+
+// routesSearcher потокобезопасно отдает билет по маршруту каждого параллельного поиска
+type routesSearcher struct {
+	fakeSearcher
+	mu     sync.Mutex
+	routes []string
+}
+
+func (s *routesSearcher) SearchCheap(_ context.Context, p app.SearchParams) ([]app.Flight, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes = append(s.routes, p.Origin+"-"+p.Destination)
+	price := map[string]int{"DME-IST": 9000, "DME-AYT": 12000, "SVO-IST": 8000, "SVO-AYT": 15000}[p.Origin+"-"+p.Destination]
+	return []app.Flight{{Origin: p.Origin, Destination: p.Destination, Price: price}}, nil
+}
+
+func TestSearchWorker_Handle_MultiRoute(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		streams:   make(map[string][]map[string]interface{}),
+		processed: make(map[string]bool),
+	}
+	searcher := &routesSearcher{}
+	worker, _ := newTestWorker(mockRedis, searcher)
+
+	event := testRequestEvent("1-0", "req-1")
+	event["params"].(map[string]interface{})["origin"] = "MOW"
+	event["params"].(map[string]interface{})["destination"] = "IST,AYT"
+	event["params"].(map[string]interface{})["expand_airports"] = true
+	event["params"].(map[string]interface{})["limit"] = 3
+	mockRedis.AddToStream("search.requests", event)
+
+	request, err := worker.consumer.Consume(context.Background())
+	if err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+	if err := worker.Handle(context.Background(), request); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+
+	sort.Strings(searcher.routes)
+	if fmt.Sprint(searcher.routes) != "[DME-AYT DME-IST SVO-AYT SVO-IST]" {
+		t.Errorf("unexpected searched routes: %v", searcher.routes)
+	}
+
+	result, err := DecodeSearchResult(mockRedis.GetStreams()["search.results"][0])
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if result.Count != 3 {
+		t.Fatalf("expected 3 results, got %d", result.Count)
+	}
+	for i, want := range []string{"SVO-IST", "DME-IST", "DME-AYT"} {
+		if got := result.Results[i].Origin + "-" + result.Results[i].Destination; got != want {
+			t.Errorf("result %d: expected route %s, got %s", i, want, got)
+		}
+	}
+}
+
+func TestSearchWorker_Handle_GroupedPrices(t *testing.T) {
+	mockRedis := &mockRedisClient{
+		streams:   make(map[string][]map[string]interface{}),
+		processed: make(map[string]bool),
+	}
+	searcher := &fakeSearcher{flights: []app.Flight{
+		{Origin: "MOW", Destination: "PAR", DepartDate: time.Date(2025, 3, 11, 0, 0, 0, 0, time.UTC), Price: 12300},
+		{Origin: "MOW", Destination: "PAR", DepartDate: time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC), Price: 15400},
+	}}
+	worker, _ := newTestWorker(mockRedis, searcher)
+
+	event := testRequestEvent("1-0", "req-1")
+	event["params"].(map[string]interface{})["kind"] = SearchKindGrouped
+	event["params"].(map[string]interface{})["direct"] = true
+	mockRedis.AddToStream("search.requests", event)
+
+	request, err := worker.consumer.Consume(context.Background())
+	if err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+	if err := worker.Handle(context.Background(), request); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+
+	if searcher.grouped.Destination != "PAR" || !searcher.grouped.Direct || searcher.grouped.Currency != "rub" {
+		t.Errorf("unexpected grouped params: %+v", searcher.grouped)
+	}
+
+	result, err := DecodeSearchResult(mockRedis.GetStreams()["search.results"][0])
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if result.Count != 2 || result.Results[0].DepartDate != "2025-01-20" || result.Message != "MOW → PAR по периодам: 2" {
+		t.Errorf("expected periods in order with grouped message, got %+v", result)
+	}
+}